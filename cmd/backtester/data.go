@@ -0,0 +1,158 @@
+package main
+
+import (
+	"backtester/types"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// candleColumns is the header of the candle CSV files of the import command.
+var candleColumns = []string{"timestamp", "open", "high", "low", "close", "volume"}
+
+func importCommand(args []string) error {
+	fs := newFlagSet("import", "")
+	db := dbFlag(fs)
+	logs := addLogFlags(fs)
+	ticker := fs.String("ticker", "", "`ticker` of the asset, created when missing")
+	name := fs.String("name", "", "`name` of the asset, the ticker when empty")
+	assetType := fs.String("type", string(types.AssetTypeStock), "asset `type`: STOCK, ETF or CRYPTO")
+	file := fs.String("file", "", "CSV `file` with the columns "+strings.Join(candleColumns, ",")+" of one minute candles")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *ticker == "" || *file == "" {
+		fs.Usage()
+		return fmt.Errorf("%w: import needs -ticker and -file", usageErr)
+	}
+
+	f, err := os.Open(*file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	candles, err := readCandlesCSV(f)
+	if err != nil {
+		return fmt.Errorf("read %s: %w", *file, err)
+	}
+
+	database, err := openDatabase(*db)
+	if err != nil {
+		return err
+	}
+	if *name == "" {
+		*name = strings.ToUpper(*ticker)
+	}
+	ctx := context.Background()
+	asset, err := database.SaveAsset(strings.ToUpper(*ticker), *name, types.AssetType(strings.ToUpper(*assetType)), ctx)
+	if err != nil {
+		return err
+	}
+	count, err := database.ImportCandles(asset.Id, candles, ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(logs.printer(), "Imported %d candles of %s\n", count, asset.Ticker)
+	return nil
+}
+
+// readCandlesCSV reads candles with a header row. Timestamps are RFC 3339, YYYY-MM-DD or unix seconds.
+func readCandlesCSV(r io.Reader) ([]types.Candle, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = len(candleColumns)
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+	for i, column := range candleColumns {
+		if strings.ToLower(header[i]) != column {
+			return nil, fmt.Errorf("column %d is %q, want %q", i+1, header[i], column)
+		}
+	}
+
+	var candles []types.Candle
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return candles, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+		candle, err := parseCandle(record)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		candles = append(candles, candle)
+	}
+}
+
+func parseCandle(record []string) (types.Candle, error) {
+	timestamp, err := parseTimestamp(record[0])
+	if err != nil {
+		return types.Candle{}, err
+	}
+	values := make([]decimal.Decimal, len(record)-1)
+	for i, field := range record[1:] {
+		if values[i], err = decimal.NewFromString(field); err != nil {
+			return types.Candle{}, fmt.Errorf("invalid %s %q", candleColumns[i+1], field)
+		}
+	}
+	return types.Candle{
+		Timestamp: timestamp,
+		Open:      values[0],
+		High:      values[1],
+		Low:       values[2],
+		Close:     values[3],
+		Volume:    values[4],
+		Interval:  types.OneMinute,
+	}, nil
+}
+
+func parseTimestamp(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t.UTC(), nil
+	}
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t, nil
+	}
+	if seconds, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(seconds, 0).UTC(), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid timestamp %q", s)
+}
+
+func assetsCommand(args []string) error {
+	fs := newFlagSet("assets", "")
+	db := dbFlag(fs)
+	assetType := fs.String("type", "", "only list assets of this `type`: STOCK, ETF or CRYPTO")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	database, err := openDatabase(*db)
+	if err != nil {
+		return err
+	}
+	assets, err := database.GetAssets(types.AssetType(strings.ToUpper(*assetType)), context.Background())
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TICKER\tNAME\tTYPE")
+	for _, asset := range assets {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", asset.Ticker, asset.Name, asset.Type)
+	}
+	return w.Flush()
+}
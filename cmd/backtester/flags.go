@@ -0,0 +1,343 @@
+package main
+
+import (
+	"backtester/internal/engine"
+	"backtester/internal/repository"
+	"backtester/types"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// usageErr fails a command that was called with invalid flags or arguments.
+var usageErr = errors.New("invalid usage")
+
+// newFlagSet returns the flag set of a command, args describes its positional arguments in the usage.
+func newFlagSet(name, args string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: backtester %s [flags] %s\n\nFlags:\n", name, args)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parseFlags parses the flags of a command. The flag package already reported invalid flags along with
+// the usage.
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	err := fs.Parse(args)
+	if err != nil && !errors.Is(err, flag.ErrHelp) {
+		return usageErr
+	}
+	return err
+}
+
+// dbFlag adds the database URL flag, which defaults to $BACKTESTER_DB.
+func dbFlag(fs *flag.FlagSet) *string {
+	url := os.Getenv("BACKTESTER_DB")
+	if url == "" {
+		url = dburl
+	}
+	return fs.String("db", url, "PostgreSQL `url` of the candle and run database")
+}
+
+func openDatabase(url string) (*repository.Database, error) {
+	db, err := repository.NewDatabase(url)
+	if err != nil {
+		return nil, fmt.Errorf("open database: %w", err)
+	}
+	return &db, nil
+}
+
+// logFlags controls how much an engine prints.
+type logFlags struct {
+	quiet   bool
+	verbose bool
+}
+
+func addLogFlags(fs *flag.FlagSet) *logFlags {
+	f := &logFlags{}
+	fs.BoolVar(&f.quiet, "quiet", false, "print nothing but errors")
+	fs.BoolVar(&f.verbose, "verbose", false, "log debug messages")
+	return f
+}
+
+func (f *logFlags) apply(eng *engine.Engine) *engine.Engine {
+	switch {
+	case f.quiet:
+		return eng.Quiet()
+	case f.verbose:
+		return eng.WithLogger(slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	}
+	return eng
+}
+
+// printer prints command output unless quiet.
+func (f *logFlags) printer() io.Writer {
+	if f.quiet {
+		return io.Discard
+	}
+	return os.Stdout
+}
+
+// runOptions are the settings of a Donchian run, from flags or from a manifest.
+type runOptions struct {
+	name        string
+	out         string
+	start       time.Time
+	end         time.Time
+	instruments []instrumentOption
+	cash        decimal.Decimal
+	short       bool
+	riskFree    decimal.Decimal
+	conflict    engine.ConflictResolution
+	benchmark   string
+	simulations int
+	seed        uint64
+	method      engine.MonteCarloMethod
+}
+
+type instrumentOption struct {
+	ticker   string
+	interval types.Interval
+	context  []types.Interval
+}
+
+// runFlags are the flags of the commands that build Donchian engines.
+type runFlags struct {
+	runOptions
+	tickers  []string
+	interval types.Interval
+	context  []types.Interval
+}
+
+func addRunFlags(fs *flag.FlagSet) *runFlags {
+	f := &runFlags{
+		runOptions: runOptions{
+			start:    time.Date(2015, 1, 1, 0, 0, 0, 0, time.UTC),
+			end:      time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+			cash:     decimal.NewFromFloat(2000),
+			riskFree: decimal.NewFromFloat(0.03),
+			conflict: engine.ResolveStrongest,
+			method:   engine.MonteCarloResample,
+		},
+		tickers:  []string{"AMD"},
+		interval: types.Hour,
+		context:  []types.Interval{types.Week},
+	}
+	fs.StringVar(&f.name, "name", "donchian", "`name` of the run and its report files")
+	fs.StringVar(&f.out, "out", "reports", "output `directory` of the report files")
+	fs.Var(dateFlag{&f.start}, "start", "first `date` of the run, as YYYY-MM-DD")
+	fs.Var(dateFlag{&f.end}, "end", "last `date` of the run, as YYYY-MM-DD")
+	fs.Var(listFlag{&f.tickers}, "instruments", "comma separated `tickers`, or the preset "+presetNames())
+	fs.Var(intervalFlag{&f.interval}, "interval", "primary `interval` of the instruments: 1, 5, 15, 30, 60, 120, 240, D, W or M")
+	fs.Var(intervalsFlag{&f.context}, "context", "comma separated context `intervals` of the instruments")
+	fs.Var(decimalFlag{&f.cash}, "cash", "initial `cash` of the portfolio")
+	fs.BoolVar(&f.short, "short", true, "allow short selling")
+	fs.Var(decimalFlag{&f.riskFree}, "risk-free", "annual risk free `rate` of the Sharpe ratio")
+	fs.Var(conflictFlag{&f.conflict}, "conflict", "`mode` that reduces the signals of a ticker on a bar: PASS_THROUGH, NET, STRONGEST, FIRST or IGNORE")
+	fs.StringVar(&f.benchmark, "benchmark", "", "benchmark `ticker` such as SPY, whose candles must be in the database, or "+buyAndHold+" to hold the instruments")
+	fs.IntVar(&f.simulations, "montecarlo", 10000, "Monte Carlo `simulations`, 0 to skip them")
+	fs.Uint64Var(&f.seed, "seed", 42, "Monte Carlo `seed`")
+	return f
+}
+
+// options returns the run options with the instruments of the flags.
+func (f *runFlags) options() runOptions {
+	opts := f.runOptions
+	opts.instruments = nil
+	tickers := f.tickers
+	if len(tickers) == 1 {
+		if preset, ok := instrumentPresets[tickers[0]]; ok {
+			tickers = preset
+		}
+	}
+	for _, ticker := range tickers {
+		opts.instruments = append(opts.instruments, instrumentOption{
+			ticker:   strings.ToUpper(ticker),
+			interval: f.interval,
+			context:  f.context,
+		})
+	}
+	return opts
+}
+
+// dateFlag is a date flag in the YYYY-MM-DD format.
+type dateFlag struct{ t *time.Time }
+
+func (f dateFlag) String() string {
+	if f.t == nil || f.t.IsZero() {
+		return ""
+	}
+	return f.t.Format(time.DateOnly)
+}
+
+func (f dateFlag) Set(s string) error {
+	t, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		return fmt.Errorf("invalid date %q, want YYYY-MM-DD", s)
+	}
+	*f.t = t
+	return nil
+}
+
+// intervalFlag is an interval flag in the notation of types.ConvertInterval.
+type intervalFlag struct{ i *types.Interval }
+
+func (f intervalFlag) String() string {
+	if f.i == nil {
+		return ""
+	}
+	return string(*f.i)
+}
+
+func (f intervalFlag) Set(s string) error {
+	interval, err := parseInterval(s)
+	if err != nil {
+		return err
+	}
+	*f.i = interval
+	return nil
+}
+
+// intervalsFlag is a comma separated list of intervals, empty for none.
+type intervalsFlag struct{ i *[]types.Interval }
+
+func (f intervalsFlag) String() string {
+	if f.i == nil {
+		return ""
+	}
+	names := make([]string, len(*f.i))
+	for i, interval := range *f.i {
+		names[i] = string(interval)
+	}
+	return strings.Join(names, ",")
+}
+
+func (f intervalsFlag) Set(s string) error {
+	var intervals []types.Interval
+	for _, name := range splitList(s) {
+		interval, err := parseInterval(name)
+		if err != nil {
+			return err
+		}
+		intervals = append(intervals, interval)
+	}
+	*f.i = intervals
+	return nil
+}
+
+func parseInterval(s string) (types.Interval, error) {
+	interval, ok := types.ConvertInterval[strings.ToUpper(s)]
+	if !ok {
+		return "", fmt.Errorf("unknown interval %q", s)
+	}
+	return interval, nil
+}
+
+// conflictFlag is a signal conflict resolution flag.
+type conflictFlag struct{ c *engine.ConflictResolution }
+
+func (f conflictFlag) String() string {
+	if f.c == nil {
+		return ""
+	}
+	return string(*f.c)
+}
+
+func (f conflictFlag) Set(s string) error {
+	mode := engine.ConflictResolution(strings.ToUpper(s))
+	switch mode {
+	case engine.ResolvePassThrough, engine.ResolveNet, engine.ResolveStrongest, engine.ResolveFirst, engine.ResolveIgnore:
+		*f.c = mode
+		return nil
+	}
+	return fmt.Errorf("unknown conflict resolution %q", s)
+}
+
+// valueRange is an inclusive range of parameter values.
+type valueRange struct {
+	from, to, step decimal.Decimal
+}
+
+// rangeFlag is a range flag in the from:to:step format, a single value is a range of one.
+type rangeFlag struct{ r *valueRange }
+
+func (f rangeFlag) String() string {
+	if f.r == nil {
+		return ""
+	}
+	return f.r.from.String() + ":" + f.r.to.String() + ":" + f.r.step.String()
+}
+
+func (f rangeFlag) Set(s string) error {
+	parts := strings.Split(s, ":")
+	if len(parts) != 1 && len(parts) != 3 {
+		return fmt.Errorf("invalid range %q, want from:to:step", s)
+	}
+	values := make([]decimal.Decimal, len(parts))
+	for i, part := range parts {
+		d, err := decimal.NewFromString(strings.TrimSpace(part))
+		if err != nil {
+			return fmt.Errorf("invalid number %q in range %q", part, s)
+		}
+		values[i] = d
+	}
+	if len(values) == 1 {
+		*f.r = valueRange{from: values[0], to: values[0], step: decimal.NewFromInt(1)}
+		return nil
+	}
+	*f.r = valueRange{from: values[0], to: values[1], step: values[2]}
+	return nil
+}
+
+// decimalFlag is a decimal number flag.
+type decimalFlag struct{ d *decimal.Decimal }
+
+func (f decimalFlag) String() string {
+	if f.d == nil {
+		return ""
+	}
+	return f.d.String()
+}
+
+func (f decimalFlag) Set(s string) error {
+	d, err := decimal.NewFromString(s)
+	if err != nil {
+		return fmt.Errorf("invalid number %q", s)
+	}
+	*f.d = d
+	return nil
+}
+
+// listFlag is a comma separated list of strings.
+type listFlag struct{ s *[]string }
+
+func (f listFlag) String() string {
+	if f.s == nil {
+		return ""
+	}
+	return strings.Join(*f.s, ",")
+}
+
+func (f listFlag) Set(s string) error {
+	*f.s = splitList(s)
+	return nil
+}
+
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"backtester/internal/engine"
+	"backtester/types"
+	"flag"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// sweepFlags are the flags of the commands that sweep the Donchian grid. The optimizer quiets its
+// engines, so the log flags only apply to the printed results.
+type sweepFlags struct {
+	run       *runFlags
+	logs      *logFlags
+	workers   int
+	rankBy    string
+	lookback  valueRange
+	position  valueRange
+	execution []types.Interval
+}
+
+func addSweepFlags(fs *flag.FlagSet) *sweepFlags {
+	f := &sweepFlags{
+		run:       addRunFlags(fs),
+		logs:      addLogFlags(fs),
+		lookback:  valueRange{decimal.NewFromInt(2), decimal.NewFromInt(8), decimal.NewFromInt(1)},
+		position:  valueRange{decimal.NewFromFloat(0.05), decimal.NewFromFloat(0.25), decimal.NewFromFloat(0.05)},
+		execution: []types.Interval{types.Hour, types.Day},
+	}
+	fs.IntVar(&f.workers, "workers", runtime.NumCPU(), "`number` of runs side by side")
+	fs.StringVar(&f.rankBy, "rank", string(engine.MetricSharpe), "`metric` to rank the combinations by")
+	fs.Var(rangeFlag{&f.lookback}, "lookback", "Donchian channel lookbacks in weeks, as a `range` from:to:step or a single value")
+	fs.Var(rangeFlag{&f.position}, "position", "fractions of the cash per position, as a `range` from:to:step or a single value")
+	fs.Var(intervalsFlag{&f.execution}, "execution", "comma separated execution `intervals` of the orders")
+	return f
+}
+
+func (f *sweepFlags) optimizer(db string) (*engine.Optimizer, []engine.Parameter, error) {
+	params, factory, err := f.donchianSweep()
+	if err != nil {
+		return nil, nil, err
+	}
+	database, err := openDatabase(db)
+	if err != nil {
+		return nil, nil, err
+	}
+	return engine.NewOptimizer(database, factory, f.workers).RankBy(engine.Metric(f.rankBy)), params, nil
+}
+
+func optimizeCommand(args []string) error {
+	fs := newFlagSet("optimize", "")
+	db := dbFlag(fs)
+	sweep := addSweepFlags(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	optimizer, params, err := sweep.optimizer(*db)
+	if err != nil {
+		return err
+	}
+	results, err := optimizer.Run(params...)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(sweep.run.out, sweep.run.name+"_optimization")
+	if err := engine.WriteOptimizationCSVFile(path+".csv", results); err != nil {
+		return err
+	}
+	return engine.WriteOptimizationJSONFile(path+".json", results)
+}
+
+// walkForwardCommand optimizes on -in-sample years and trades the winner on the following
+// -out-of-sample years, rolling by the out-of-sample window.
+func walkForwardCommand(args []string) error {
+	fs := newFlagSet("walkforward", "")
+	db := dbFlag(fs)
+	sweep := addSweepFlags(fs)
+	inSample := fs.Int("in-sample", 3, "in-sample window in `years`")
+	outOfSample := fs.Int("out-of-sample", 1, "out-of-sample window in `years`")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	optimizer, params, err := sweep.optimizer(*db)
+	if err != nil {
+		return err
+	}
+	year := 365 * 24 * time.Hour
+	result, err := engine.NewWalkForward(optimizer, time.Duration(*inSample)*year, time.Duration(*outOfSample)*year).Run(params...)
+	if err != nil {
+		return err
+	}
+
+	out := sweep.logs.printer()
+	for _, w := range result.Windows {
+		fmt.Fprintf(out, "%s - %s: %s (IS CAGR %.2f%%, OOS CAGR %.2f%%)\n",
+			w.OutOfSampleStart.Format(time.DateOnly),
+			w.OutOfSampleEnd.Format(time.DateOnly),
+			w.Params,
+			w.InSample.CAGR.Mul(decimal.NewFromInt(100)).InexactFloat64(),
+			w.OutOfSample.CAGR.Mul(decimal.NewFromInt(100)).InexactFloat64(),
+		)
+	}
+	fmt.Fprintf(out, "Walk-forward efficiency: %.2f\n", result.Efficiency.InexactFloat64())
+	fmt.Fprintf(out, "Out-of-sample CAGR:      %.2f%%\n", result.Report.CAGR.Mul(decimal.NewFromInt(100)).InexactFloat64())
+	fmt.Fprintf(out, "Out-of-sample Sharpe:    %.2f\n", result.Report.SharpeRatio.InexactFloat64())
+	return nil
+}
+
+// donchianSweep returns the Donchian parameter grid of the flags (lookback, position size, execution
+// interval) and the engine factory for it. The combinations write no report files and skip Monte Carlo.
+func (f *sweepFlags) donchianSweep() ([]engine.Parameter, engine.EngineFactory, error) {
+	if !f.lookback.from.IsInteger() || !f.lookback.to.IsInteger() || !f.lookback.step.IsInteger() {
+		return nil, nil, fmt.Errorf("%w: -lookback must be whole weeks", usageErr)
+	}
+	if len(f.execution) == 0 {
+		return nil, nil, fmt.Errorf("%w: -execution needs an interval", usageErr)
+	}
+	lookback, err := engine.IntRange("lookback_weeks",
+		int(f.lookback.from.IntPart()), int(f.lookback.to.IntPart()), int(f.lookback.step.IntPart()))
+	if err != nil {
+		return nil, nil, err
+	}
+	positionPercent, err := engine.DecimalRange("position_percent", f.position.from, f.position.to, f.position.step)
+	if err != nil {
+		return nil, nil, err
+	}
+	intervals := make([]any, len(f.execution))
+	for i, interval := range f.execution {
+		intervals[i] = interval
+	}
+	executionInterval := engine.Values("execution_interval", intervals...)
+
+	opts := f.run.options()
+	opts.simulations = 0
+	factory := func(params engine.Params) (*engine.Engine, error) {
+		return donchianEngine(nil, opts, engine.NewReportingConfig(opts.riskFree, false, opts.name, opts.out), params)
+	}
+	return []engine.Parameter{lookback, positionPercent, executionInterval}, factory, nil
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"slices"
+	"strings"
+)
+
+// instrumentPresets are named portfolios for the -instruments flag.
+var instrumentPresets = map[string][]string{
+	"diversified": {
+		// ETF
+		"SPY", "QQQ",
+		// Consumer discretionary
+		"WMT", "NKE",
+		// Energy
+		"XOM", "CVX",
+		// Financials
+		"SOFI", "BAC",
+		// Health care
+		"JNJ", "UNH",
+		// Industrials
+		"RTX", "CAT",
+		// Tech
+		"NVDA", "INTC",
+		// Materials
+		"CDE", "HL",
+		// Metals/mining
+		// Telecommunication
+		"T", "VZ",
+		// Utilities
+		"PCG", "OKLO",
+		// Real estate
+		"VICI", "AGNC",
+	},
+}
+
+func presetNames() string {
+	names := make([]string, 0, len(instrumentPresets))
+	for name := range instrumentPresets {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"backtester/internal/engine"
+	"backtester/internal/repository"
+	"backtester/types"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+func reportCommand(args []string) error {
+	fs := newFlagSet("report", "<journal>")
+	logs := addLogFlags(fs)
+	name := fs.String("name", "donchian", "`name` of the report files")
+	out := fs.String("out", "reports", "output `directory` of the report files")
+	runID := fs.String("run", "", "`ID` of the run to replay when the journal holds several")
+	riskFree := decimal.NewFromFloat(0.03)
+	fs.Var(decimalFlag{&riskFree}, "risk-free", "annual risk free `rate` of the Sharpe ratio")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("%w: report needs one journal, got %d", usageErr, fs.NArg())
+	}
+
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	reporting := engine.NewReportingConfig(riskFree, true, *name, *out).HTMLReport().JSONReport()
+	_, err = logs.apply(engine.NewReplayEngine(f, reporting).WithRunID(*runID)).Replay()
+	return err
+}
+
+// compareCommand compares saved runs by ID, or JSON reports by path, against the first one and writes
+// the overlaid equity curves to compare.html in the output directory.
+func compareCommand(args []string) error {
+	fs := newFlagSet("compare", "<run ID or report.json>...")
+	db := dbFlag(fs)
+	logs := addLogFlags(fs)
+	out := fs.String("out", "reports", "output `directory` of compare.html")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	var (
+		runs     []types.Run
+		database *repository.Database
+	)
+	for _, ref := range fs.Args() {
+		if strings.HasSuffix(ref, ".json") {
+			run, err := engine.LoadReportJSONFile(ref)
+			if err != nil {
+				return err
+			}
+			runs = append(runs, run)
+			continue
+		}
+		if database == nil {
+			var err error
+			if database, err = openDatabase(*db); err != nil {
+				return err
+			}
+		}
+		run, err := database.GetRun(ref, context.Background())
+		if err != nil {
+			return err
+		}
+		runs = append(runs, *run)
+	}
+
+	comparison, err := engine.CompareRuns(runs...)
+	if err != nil {
+		return err
+	}
+	if !logs.quiet {
+		engine.PrintComparison(comparison)
+	}
+	return engine.WriteComparisonHTMLFile(filepath.Join(*out, "compare.html"), comparison)
+}
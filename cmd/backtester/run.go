@@ -0,0 +1,184 @@
+package main
+
+import (
+	"backtester/internal/engine"
+	"backtester/internal/repository"
+	"backtester/strategies/donchian"
+	"backtester/types"
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// buyAndHold is the -benchmark value that benchmarks against holding the instruments.
+const buyAndHold = "buy-and-hold"
+
+func runCommand(args []string) error {
+	fs := newFlagSet("run", "")
+	db := dbFlag(fs)
+	run := addRunFlags(fs)
+	logs := addLogFlags(fs)
+	lookback := fs.Int("lookback", 4, "Donchian channel lookback in `weeks`")
+	position := decimal.NewFromFloat(0.1)
+	fs.Var(decimalFlag{&position}, "position", "`fraction` of the cash per position")
+	execution := types.Hour
+	fs.Var(intervalFlag{&execution}, "execution", "execution `interval` of the orders")
+	save := fs.Bool("save", false, "save the run in the database, which needs its runs tables")
+	tags := fs.String("tags", "", "comma separated `tags` of the saved run")
+	journal := fs.String("journal", "", "write the event journal of the run to `file`")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	database, err := openDatabase(*db)
+	if err != nil {
+		return err
+	}
+	params := engine.Params{
+		"lookback_weeks":     *lookback,
+		"position_percent":   position,
+		"execution_interval": execution,
+	}
+	opts := run.options()
+	eng, err := donchianEngine(database, opts, opts.reporting(), params)
+	if err != nil {
+		return err
+	}
+	if *save {
+		eng.WithRunStore(database, splitList(*tags)...)
+	}
+	if *journal != "" {
+		sink, err := engine.NewJSONLJournalFile(*journal)
+		if err != nil {
+			return err
+		}
+		eng.WithJournal(sink)
+	}
+	_, err = logs.apply(eng).Run()
+	return err
+}
+
+func verifyCommand(args []string) error {
+	fs := newFlagSet("verify", "<manifest>")
+	db := dbFlag(fs)
+	logs := addLogFlags(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("%w: verify needs one manifest, got %d", usageErr, fs.NArg())
+	}
+
+	path := fs.Arg(0)
+	manifest, err := engine.ReadManifestFile(path)
+	if err != nil {
+		return err
+	}
+	database, err := openDatabase(*db)
+	if err != nil {
+		return err
+	}
+	opts := manifestOptions(manifest)
+	factory := func(params engine.Params) (*engine.Engine, error) {
+		eng, err := donchianEngine(database, opts, opts.reporting(), params)
+		if err != nil {
+			return nil, err
+		}
+		return logs.apply(eng), nil
+	}
+	if _, err := engine.VerifyManifest(manifest, factory, nil); err != nil {
+		return err
+	}
+	fmt.Fprintf(logs.printer(), "Run %s matches %s\n", manifest.RunID, path)
+	return nil
+}
+
+// manifestOptions returns the run options a manifest was written with. VerifyManifest applies the
+// engine settings of the manifest, such as cash flows and risk limits, on top of them.
+func manifestOptions(manifest *engine.Manifest) runOptions {
+	config := manifest.Config
+	opts := runOptions{
+		name:  manifest.Name,
+		out:   manifest.Reporting.Path,
+		start: config.Start,
+		// The simulation stops one minute past the end of the run
+		end:       config.End.Add(-time.Minute),
+		cash:      config.InitialCash,
+		short:     config.AllowShortSelling,
+		riskFree:  config.RiskFreeRate,
+		conflict:  config.ConflictResolution,
+		benchmark: config.Benchmark,
+	}
+	if manifest.Reporting.BuyAndHold {
+		opts.benchmark = buyAndHold
+	}
+	for _, instrument := range config.Instruments {
+		opts.instruments = append(opts.instruments, instrumentOption{
+			ticker:   instrument.Ticker,
+			interval: instrument.Interval,
+			context:  instrument.Context,
+		})
+	}
+	if mc := config.MonteCarlo; mc != nil {
+		opts.method, opts.simulations, opts.seed = mc.Method, mc.Simulations, mc.Seed
+	}
+	return opts
+}
+
+// reporting returns the reporting of a single run, which writes every report and the manifest.
+func (o runOptions) reporting() *engine.ReportingConfig {
+	reporting := engine.NewReportingConfig(o.riskFree, true, o.name, o.out).HTMLReport().JSONReport().Manifest()
+	switch o.benchmark {
+	case "":
+	case buyAndHold:
+		reporting.BenchmarkBuyAndHold()
+	default:
+		reporting.BenchmarkTicker(o.benchmark)
+	}
+	return reporting
+}
+
+func (o runOptions) instrumentConfigs() []*engine.InstrumentConfig {
+	instruments := make([]*engine.InstrumentConfig, 0, len(o.instruments))
+	for _, option := range o.instruments {
+		instrument := engine.Instrument(option.ticker, o.start, o.end, option.interval)
+		for _, interval := range option.context {
+			instrument.AddContext(interval)
+		}
+		instruments = append(instruments, instrument)
+	}
+	return engine.Instruments(instruments...)
+}
+
+// donchianEngine builds a Donchian run from the options and the lookback_weeks, position_percent and
+// execution_interval parameters.
+func donchianEngine(db *repository.Database, opts runOptions, reporting *engine.ReportingConfig, params engine.Params) (*engine.Engine, error) {
+	weeks, err := params.Int("lookback_weeks")
+	if err != nil {
+		return nil, err
+	}
+	percent, err := params.Decimal("position_percent")
+	if err != nil {
+		return nil, err
+	}
+	interval, err := params.Interval("execution_interval")
+	if err != nil {
+		return nil, err
+	}
+	eng := engine.NewEngine(
+		opts.instrumentConfigs(),
+		engine.NewExecutionConfig(interval, 24, 24),
+		reporting,
+		donchian.NewStrategy(weeks),
+		donchian.NewLongOnlyAllocator(percent),
+		&donchian.Broker{},
+		engine.NewPortfolioConfig(opts.cash, opts.short),
+		db,
+	).WithParameters(params).WithConflictResolution(opts.conflict)
+	if opts.simulations > 0 {
+		eng.WithMonteCarlo(engine.NewMonteCarloConfig(opts.method, opts.simulations, opts.seed))
+	}
+	return eng, nil
+}
@@ -14,8 +14,14 @@ type backtester struct {
 	portfolioConfig *PortfolioConfig
 	strategy        strategy
 	allocator       allocator
+	signalResolver  *signalResolver
+	riskManager     riskManager
 	broker          broker
 	portfolio       *portfolio
+	quiet           bool
+	// snapshotFrequency is zero for daily snapshots
+	snapshotFrequency SnapshotFrequency
+	journal           *journal
 
 	start               time.Time
 	curTime             time.Time
@@ -23,6 +29,8 @@ type backtester struct {
 	instrumentFeedIndex map[string]int
 	contextFeedIndex    map[string]map[types.Interval]int
 	executionIndex      map[string]int
+	cashFlows           []scheduledCashFlow
+	cashFlowIndex       int
 }
 
 func newBacktester(feeds []*InstrumentConfig, executionConfig *ExecutionConfig, portfolioConfig *PortfolioConfig, strat strategy, sizing allocator, broker broker, portfolio *portfolio) *backtester {
@@ -48,6 +56,7 @@ func newBacktester(feeds []*InstrumentConfig, executionConfig *ExecutionConfig,
 		portfolioConfig:     portfolioConfig,
 		strategy:            strat,
 		allocator:           sizing,
+		signalResolver:      newSignalResolver(ResolveIgnore),
 		broker:              broker,
 		portfolio:           portfolio,
 		instrumentFeedIndex: feedIndex,
@@ -57,9 +66,17 @@ func newBacktester(feeds []*InstrumentConfig, executionConfig *ExecutionConfig,
 }
 
 func (b *backtester) run() error {
-	bar := initProgressBar(int(b.end.Sub(b.start).Minutes()))
+	var bar *progressbar.ProgressBar
+	if !b.quiet {
+		bar = initProgressBar(int(b.end.Sub(b.start).Minutes()))
+	}
 	for !b.curTime.After(b.end) {
+		if err := b.applyDueCashFlows(); err != nil {
+			return err
+		}
+
 		signals := make(map[string][]types.Signal)
+		newBar := false
 		for _, instrument := range b.instruments {
 			i := b.instrumentFeedIndex[instrument.ticker]
 			if i >= len(instrument.primary.candles) {
@@ -69,11 +86,15 @@ func (b *backtester) run() error {
 			// Only send candles when they are fully closed.
 			candleCloseTime := curCandle.Timestamp.Add(types.IntervalToTime[instrument.interval])
 			if candleCloseTime.Equal(b.curTime) {
-				curSignals := signals[instrument.ticker]
+				b.journal.record(b.curTime, EventCandle, instrument.ticker, curCandle)
 				curContexts := b.buildInstrumentContext(instrument, b.curTime)
-				curSignals = append(curSignals, b.strategy.OnCandle(curCandle, curContexts)...)
-				signals[instrument.ticker] = curSignals
+				emitted := b.strategy.OnCandle(curCandle, curContexts)
+				for _, signal := range emitted {
+					b.journal.record(b.curTime, EventSignal, signal.Ticker, signal)
+				}
+				signals[instrument.ticker] = append(signals[instrument.ticker], emitted...)
 				b.instrumentFeedIndex[instrument.ticker]++
+				newBar = true
 			}
 			b.executionIndex[instrument.ticker] = advanceFeedIndex(
 				b.executionConfig.candles[instrument.ticker],
@@ -83,23 +104,62 @@ func (b *backtester) run() error {
 			)
 		}
 
-		orders := b.allocator.Allocate(signals, b.portfolio.GetPortfolioSnapshot())
+		dropped := len(b.signalResolver.dropped)
+		signals = b.signalResolver.resolve(signals, b.curTime)
+		for _, d := range b.signalResolver.dropped[dropped:] {
+			b.journal.record(b.curTime, EventSignalDropped, d.Signal.Ticker, d)
+		}
+		view := b.portfolio.GetPortfolioSnapshot()
+		orders := b.allocator.Allocate(signals, view)
+		for _, order := range orders {
+			b.journal.record(b.curTime, EventOrder, order.Ticker, order)
+		}
+		var rejections []types.ExecutionReport
+		if b.riskManager != nil {
+			orders, rejections = b.riskManager.Check(orders, view)
+			b.journalRiskDecisions(rejections)
+		}
 		executions := b.broker.Execute(orders, b.buildExecutionContext())
-		err := b.portfolio.processExecutions(executions)
+		attachStops(orders, executions)
+		for _, execution := range executions {
+			b.journal.record(b.curTime, EventExecution, execution.Ticker, execution)
+		}
+		err := b.portfolio.processExecutions(append(rejections, executions...))
 		if err != nil {
 			return err
 		}
 
-		// Create a snapshot of the portfolio every day
-		if b.curTime.Hour() == 0 && b.curTime.Minute() == 0 {
+		if b.snapshotFrequency.due(b.curTime, b.start, b.end, newBar, hasFills(executions)) {
 			curSnapshot := b.portfolio.GetPortfolioSnapshot()
 			curSnapshot.Time = b.curTime
 			b.portfolio.snapshots = append(b.portfolio.snapshots, curSnapshot)
+			b.journal.record(b.curTime, EventSnapshot, "", curSnapshot)
+		}
+		if b.journal != nil && b.journal.err != nil {
+			return b.journal.err
 		}
 
 		// We use time.Minute here because the lowest timeframe we have is minute
 		b.curTime = b.curTime.Add(time.Minute)
-		bar.Add(1)
+		if bar != nil {
+			bar.Add(1)
+		}
+	}
+	b.journal.record(b.curTime, EventRunEnd, "", nil)
+	return nil
+}
+
+// applyDueCashFlows applies every scheduled cash flow up to the current time before the strategy sees the bar.
+func (b *backtester) applyDueCashFlows() error {
+	for b.cashFlowIndex < len(b.cashFlows) && !b.cashFlows[b.cashFlowIndex].time.After(b.curTime) {
+		applied := len(b.portfolio.cashFlows)
+		if err := b.portfolio.applyCashFlow(b.cashFlows[b.cashFlowIndex]); err != nil {
+			return err
+		}
+		for _, flow := range b.portfolio.cashFlows[applied:] {
+			b.journal.record(b.curTime, EventCashFlow, "", flow)
+		}
+		b.cashFlowIndex++
 	}
 	return nil
 }
@@ -194,6 +254,28 @@ func getGlobalTimeRange(feeds []*InstrumentConfig) (time.Time, time.Time) {
 	return minStart, maxEnd
 }
 
+// journalRiskDecisions records the rejections of the last risk check and, when the risk manager reports
+// them, the orders it resized and the halt of trading.
+func (b *backtester) journalRiskDecisions(rejections []types.ExecutionReport) {
+	if b.journal == nil {
+		return
+	}
+	var resized []RiskResize
+	var halt *RiskHalt
+	if auditor, ok := b.riskManager.(riskAuditor); ok {
+		resized, halt = auditor.lastDecisions()
+	}
+	if halt != nil {
+		b.journal.record(b.curTime, EventRiskHalt, "", halt)
+	}
+	for _, rejection := range rejections {
+		b.journal.record(b.curTime, EventRiskRejection, rejection.Ticker, rejection)
+	}
+	for _, resize := range resized {
+		b.journal.record(b.curTime, EventRiskResize, resize.Order.Ticker, resize)
+	}
+}
+
 func (b *backtester) getCurrentTime() time.Time {
 	return b.curTime
 }
@@ -214,6 +296,29 @@ func (b *backtester) getLastPriceForTicker(ticker string) decimal.Decimal {
 	return decimal.Zero
 }
 
+func (b *backtester) getCandlesForTicker(ticker string, n int) []types.Candle {
+	for _, feed := range b.instruments {
+		if feed.ticker != ticker {
+			continue
+		}
+		end := min(b.instrumentFeedIndex[ticker], len(feed.primary.candles))
+		start := 0
+		if n > 0 && end > n {
+			start = end - n
+		}
+		return feed.primary.candles[start:end:end]
+	}
+	return nil
+}
+
+func (b *backtester) getTickers() []string {
+	tickers := make([]string, 0, len(b.instruments))
+	for _, feed := range b.instruments {
+		tickers = append(tickers, feed.ticker)
+	}
+	return tickers
+}
+
 // Index only goes one way
 func advanceFeedIndex(candles []types.Candle, prevIndex int, curTime time.Time, candleInterval types.Interval) int {
 	if prevIndex < -1 {
@@ -255,3 +360,19 @@ func initProgressBar(maxTicks int) *progressbar.ProgressBar {
 			BarEnd:        "]",
 		}))
 }
+
+// attachStops copies the stop of every order onto the reports of the same ticker and side, so brokers
+// do not have to carry it over themselves.
+func attachStops(orders []types.Order, reports []types.ExecutionReport) {
+	for i := range reports {
+		if !reports[i].StopPrice.IsZero() {
+			continue
+		}
+		for _, order := range orders {
+			if order.Ticker == reports[i].Ticker && order.Side == reports[i].Side {
+				reports[i].StopPrice = order.StopPrice
+				break
+			}
+		}
+	}
+}
@@ -34,7 +34,7 @@ func TestBacktester_PassesSignalsMapKeyedByTicker(t *testing.T) {
 
 	engine := mockEngine(strat, instruments, recAlloc, testBroker)
 
-	if err := engine.Run(); err != nil {
+	if _, err := engine.Run(); err != nil {
 		t.Fatalf("Error running engine: %v", err)
 	}
 
@@ -82,7 +82,7 @@ func TestBacktest_BacktesterBrokerUpdatePortfolio(t *testing.T) {
 
 	engine := mockEngine(&testStrat, mockInstrument(), testAllocator, testBroker)
 
-	err := engine.Run()
+	_, err := engine.Run()
 	if err != nil {
 		t.Fatalf("Error running backtester: %v", err)
 	}
@@ -104,7 +104,7 @@ func TestBacktest_BacktesterBrokerExecutionContext(t *testing.T) {
 
 	engine := mockEngine(&testStrat, mockInstrument(), testAllocator, testBroker)
 
-	err := engine.Run()
+	_, err := engine.Run()
 	if err != nil {
 		t.Errorf("Error running backtester: %v", err)
 	}
@@ -320,7 +320,7 @@ func TestBacktest_BacktesterCallsAllocatorAndBroker(t *testing.T) {
 	testBroker := &mockBroker{}
 	engine := mockEngine(&testStrat, mockInstrument(), testAllocator, testBroker)
 
-	err := engine.Run()
+	_, err := engine.Run()
 	if err != nil {
 		t.Errorf("Error running engine: %v", err)
 	}
@@ -1174,7 +1174,7 @@ func TestBacktest_ShouldSendCandlesInOrder(t *testing.T) {
 	testBroker := &mockBroker{}
 	engine := mockEngine(testStrat, mockInstrument(), testAllocator, testBroker)
 
-	err := engine.Run()
+	_, err := engine.Run()
 	if err != nil {
 		t.Errorf("Error running engine: %v", err)
 	}
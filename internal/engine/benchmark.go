@@ -0,0 +1,250 @@
+package engine
+
+import (
+	"backtester/types"
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/shopspring/decimal"
+)
+
+const buyAndHoldBenchmark = "Buy & Hold"
+
+// BenchmarkTicker compares the run against holding the given ticker, loaded from the same data store as daily candles.
+func (c *ReportingConfig) BenchmarkTicker(ticker string) *ReportingConfig {
+	c.benchmarkTicker = ticker
+	c.benchmarkBuyAndHold = false
+	return c
+}
+
+// BenchmarkBuyAndHold compares the run against an equal weight buy-and-hold of every traded instrument.
+func (c *ReportingConfig) BenchmarkBuyAndHold() *ReportingConfig {
+	c.benchmarkBuyAndHold = true
+	c.benchmarkTicker = ""
+	return c
+}
+
+// priceSeries is a candle feed the benchmark is valued from.
+type priceSeries struct {
+	candles  []types.Candle
+	interval types.Interval
+}
+
+func (e *Engine) loadBenchmarkData() error {
+	e.benchmarkSeries = nil
+
+	if e.reportingConfig.benchmarkBuyAndHold {
+		for _, instrument := range e.backtester.instruments {
+			e.benchmarkSeries = append(e.benchmarkSeries, priceSeries{
+				candles:  instrument.primary.candles,
+				interval: instrument.interval,
+			})
+		}
+		return nil
+	}
+
+	if e.reportingConfig.benchmarkTicker == "" {
+		return nil
+	}
+
+	ctx := context.Background()
+	asset, err := e.db.GetAssetByTicker(e.reportingConfig.benchmarkTicker, ctx)
+	if err != nil {
+		return err
+	}
+	cs, err := e.db.GetAggregates(asset.Id, asset.Ticker, types.Day, e.backtester.start, e.backtester.end, ctx)
+	if err != nil {
+		return err
+	}
+	e.benchmarkSeries = []priceSeries{{candles: cs, interval: types.Day}}
+	return nil
+}
+
+func (e *Engine) benchmarkName() string {
+	if e.reportingConfig.benchmarkBuyAndHold {
+		return buyAndHoldBenchmark
+	}
+	return e.reportingConfig.benchmarkTicker
+}
+
+// addBenchmark values the benchmark on every snapshot and fills the relative metrics of the report.
+func (e *Engine) addBenchmark(report *Report, snapshots []types.PortfolioView) {
+	if len(e.benchmarkSeries) == 0 || len(snapshots) == 0 {
+		return
+	}
+
+	report.BenchmarkName = e.benchmarkName()
+	report.benchmark = benchmarkEquity(snapshots, e.benchmarkSeries, portfolioValue(snapshots[0]))
+	periods, weekdaysOnly := e.returnPeriods(snapshots)
+
+	benchSnapshots := make([]types.PortfolioView, len(snapshots))
+	for i, value := range report.benchmark {
+		benchSnapshots[i] = types.PortfolioView{Time: snapshots[i].Time, Cash: value}
+	}
+	report.BenchmarkCAGR = calcCAGR(benchSnapshots, noopWaitGroup())
+	report.ExcessCAGR = report.CAGR.Sub(report.BenchmarkCAGR)
+
+	// Compare on the same days the daily ratios of the report use
+	strategy := timeWeightedEquity(snapshots)
+	var strategyDays, benchmarkDays []decimal.Decimal
+	for i, snap := range snapshots {
+		if weekdaysOnly && isWeekend(snap.Time) {
+			continue
+		}
+		strategyDays = append(strategyDays, strategy[i])
+		benchmarkDays = append(benchmarkDays, report.benchmark[i])
+	}
+	calcRelativeMetrics(report, strategyDays, benchmarkDays, e.reportingConfig.sharpeRiskFreeRate, periods)
+}
+
+// benchmarkEquity splits startEquity equally over the series and buys each at its first known price.
+// A series keeps its share in cash until its first candle has closed.
+func benchmarkEquity(snapshots []types.PortfolioView, series []priceSeries, startEquity decimal.Decimal) []decimal.Decimal {
+	allocation := startEquity.Div(decimal.NewFromInt(int64(len(series))))
+	units := make([]decimal.Decimal, len(series))
+	bought := make([]bool, len(series))
+	indices := make([]int, len(series))
+	for i := range indices {
+		indices[i] = -1
+	}
+
+	values := make([]decimal.Decimal, len(snapshots))
+	for s, snap := range snapshots {
+		total := decimal.Zero
+		for i, ps := range series {
+			indices[i] = advanceFeedIndex(ps.candles, indices[i], snap.Time, ps.interval)
+			if indices[i] < 0 {
+				total = total.Add(allocation)
+				continue
+			}
+			price := ps.candles[indices[i]].Close
+			if !bought[i] && price.IsPositive() {
+				units[i] = allocation.Div(price)
+				bought[i] = true
+			}
+			if !bought[i] {
+				total = total.Add(allocation)
+				continue
+			}
+			total = total.Add(units[i].Mul(price))
+		}
+		values[s] = total
+	}
+	return values
+}
+
+// calcRelativeMetrics compares the day over day returns of the strategy and benchmark curves.
+func calcRelativeMetrics(report *Report, strategy, benchmark []decimal.Decimal, annualRiskFree decimal.Decimal, periodsPerYear int) {
+	var rp, rb []float64
+	for i := 1; i < len(strategy) && i < len(benchmark); i++ {
+		if !strategy[i-1].IsPositive() || !benchmark[i-1].IsPositive() {
+			continue
+		}
+		rp = append(rp, strategy[i].Div(strategy[i-1]).InexactFloat64()-1)
+		rb = append(rb, benchmark[i].Div(benchmark[i-1]).InexactFloat64()-1)
+	}
+	if len(rp) < 2 {
+		return
+	}
+
+	n := float64(len(rp))
+	meanP, meanB := mean(rp), mean(rb)
+	var covPB, varP, varB float64
+	active := make([]float64, len(rp))
+	for i := range rp {
+		covPB += (rp[i] - meanP) * (rb[i] - meanB)
+		varP += (rp[i] - meanP) * (rp[i] - meanP)
+		varB += (rb[i] - meanB) * (rb[i] - meanB)
+		active[i] = rp[i] - rb[i]
+	}
+	covPB /= n - 1
+	varP /= n - 1
+	varB /= n - 1
+
+	beta := 0.0
+	if varB > 0 {
+		beta = covPB / varB
+	}
+	if varP > 0 && varB > 0 {
+		report.Correlation = decimal.NewFromFloat(covPB / math.Sqrt(varP*varB))
+	}
+
+	periods := float64(periodsPerYear)
+	rfDaily := math.Pow(1+annualRiskFree.InexactFloat64(), 1/periods) - 1
+	report.Beta = decimal.NewFromFloat(beta)
+	report.Alpha = decimal.NewFromFloat(((meanP - rfDaily) - beta*(meanB-rfDaily)) * periods)
+
+	trackingError := stdDev(active) * math.Sqrt(periods)
+	report.TrackingError = decimal.NewFromFloat(trackingError)
+	if trackingError > 0 {
+		report.InformationRatio = decimal.NewFromFloat(mean(active) * periods / trackingError)
+	}
+
+	report.UpCapture, report.DownCapture = captureRatios(rp, rb)
+}
+
+// captureRatios divides the average strategy return by the average benchmark return over the
+// periods the benchmark was up, and over the periods it was down.
+func captureRatios(rp, rb []float64) (decimal.Decimal, decimal.Decimal) {
+	var upP, upB, downP, downB float64
+	var ups, downs int
+	for i := range rb {
+		switch {
+		case rb[i] > 0:
+			upP += rp[i]
+			upB += rb[i]
+			ups++
+		case rb[i] < 0:
+			downP += rp[i]
+			downB += rb[i]
+			downs++
+		}
+	}
+
+	up, down := decimal.Zero, decimal.Zero
+	if ups > 0 && upB != 0 {
+		up = decimal.NewFromFloat(upP / upB)
+	}
+	if downs > 0 && downB != 0 {
+		down = decimal.NewFromFloat(downP / downB)
+	}
+	return up, down
+}
+
+func mean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+// stdDev is the sample standard deviation.
+func stdDev(values []float64) float64 {
+	if len(values) < 2 {
+		return 0
+	}
+	m := mean(values)
+	sum := 0.0
+	for _, v := range values {
+		sum += (v - m) * (v - m)
+	}
+	return math.Sqrt(sum / float64(len(values)-1))
+}
+
+func printBenchmark(report *Report) {
+	fmt.Printf("\n-- Benchmark (%s) --\n", report.BenchmarkName)
+	fmt.Printf("Benchmark CAGR:        %.2f%%\n", report.BenchmarkCAGR.Mul(decimal.NewFromInt(100)).InexactFloat64())
+	fmt.Printf("Excess CAGR:           %.2f%%\n", report.ExcessCAGR.Mul(decimal.NewFromInt(100)).InexactFloat64())
+	fmt.Printf("Alpha:                 %.2f%%\n", report.Alpha.Mul(decimal.NewFromInt(100)).InexactFloat64())
+	fmt.Printf("Beta:                  %.2f\n", report.Beta.InexactFloat64())
+	fmt.Printf("Correlation:           %.2f\n", report.Correlation.InexactFloat64())
+	fmt.Printf("Tracking Error:        %.2f%%\n", report.TrackingError.Mul(decimal.NewFromInt(100)).InexactFloat64())
+	fmt.Printf("Information Ratio:     %.2f\n", report.InformationRatio.InexactFloat64())
+	fmt.Printf("Up Capture:            %.2f\n", report.UpCapture.InexactFloat64())
+	fmt.Printf("Down Capture:          %.2f\n", report.DownCapture.InexactFloat64())
+}
@@ -0,0 +1,110 @@
+package engine
+
+import (
+	"backtester/types"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestBenchmarkEquity(t *testing.T) {
+	base := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	daily := func(offset int, closes ...string) priceSeries {
+		ps := priceSeries{interval: types.Day}
+		for i, c := range closes {
+			ps.candles = append(ps.candles, types.Candle{
+				Close:     decimal.RequireFromString(c),
+				Timestamp: base.AddDate(0, 0, offset+i-1),
+			})
+		}
+		return ps
+	}
+
+	snapshots := []types.PortfolioView{
+		newPv(base, "1000"),
+		newPv(base.AddDate(0, 0, 1), "1000"),
+		newPv(base.AddDate(0, 0, 2), "1000"),
+	}
+	series := []priceSeries{
+		daily(0, "10", "20", "10"),
+		// Starts a day later, holds its share in cash until then
+		daily(1, "50", "100"),
+	}
+
+	got := benchmarkEquity(snapshots, series, decimal.NewFromInt(1000))
+	want := []string{"1000", "1500", "1500"}
+	for i, w := range want {
+		if !got[i].Equal(decimal.RequireFromString(w)) {
+			t.Errorf("index %d: got=%s, want=%s", i, got[i], w)
+		}
+	}
+}
+
+func TestCalcRelativeMetrics(t *testing.T) {
+	benchReturns := []float64{0.01, -0.02, 0.03, -0.01, 0.02}
+	curve := func(leverage float64) []decimal.Decimal {
+		values := []decimal.Decimal{decimal.NewFromInt(1000)}
+		for _, r := range benchReturns {
+			last := values[len(values)-1]
+			values = append(values, last.Mul(decimal.NewFromFloat(1+leverage*r)))
+		}
+		return values
+	}
+
+	t.Run("twice the benchmark", func(t *testing.T) {
+		report := &Report{}
+		calcRelativeMetrics(report, curve(2), curve(1), decimal.Zero, tradingDaysPerYear)
+
+		for name, tt := range map[string]struct {
+			got  decimal.Decimal
+			want float64
+		}{
+			"beta":         {report.Beta, 2},
+			"correlation":  {report.Correlation, 1},
+			"up capture":   {report.UpCapture, 2},
+			"down capture": {report.DownCapture, 2},
+		} {
+			if math.Abs(tt.got.InexactFloat64()-tt.want) > 1e-6 {
+				t.Errorf("%s = %s, want %v", name, tt.got, tt.want)
+			}
+		}
+		if !report.TrackingError.IsPositive() {
+			t.Errorf("tracking error should be positive, got %s", report.TrackingError)
+		}
+	})
+
+	t.Run("same as benchmark", func(t *testing.T) {
+		report := &Report{}
+		calcRelativeMetrics(report, curve(1), curve(1), decimal.Zero, tradingDaysPerYear)
+		if !report.TrackingError.IsZero() || !report.InformationRatio.IsZero() {
+			t.Errorf("tracking error=%s information ratio=%s, want 0", report.TrackingError, report.InformationRatio)
+		}
+		if math.Abs(report.Alpha.InexactFloat64()) > 1e-9 {
+			t.Errorf("alpha = %s, want 0", report.Alpha)
+		}
+	})
+}
+
+func TestBacktest_BuyAndHoldBenchmark(t *testing.T) {
+	start := time.UnixMilli(0).UTC()
+	feeds := Instruments(Instrument("AAPL", start, start.Add(3*24*time.Hour), types.Hour))
+	engine := mockEngine(&allocatorStrategy{}, feeds, &mockAllocator{}, &mockBroker{})
+	engine.reportingConfig.BenchmarkBuyAndHold()
+
+	report, err := engine.Run()
+	if err != nil {
+		t.Fatalf("Error running engine: %v", err)
+	}
+	if report.BenchmarkName != buyAndHoldBenchmark {
+		t.Errorf("benchmark name = %q, want %q", report.BenchmarkName, buyAndHoldBenchmark)
+	}
+	if len(report.benchmark) != len(engine.portfolio.snapshots) {
+		t.Fatalf("benchmark has %d values, want one per snapshot (%d)", len(report.benchmark), len(engine.portfolio.snapshots))
+	}
+	// Prices rise every hour in the mock data, so holding beats the idle portfolio
+	if !report.ExcessCAGR.IsNegative() {
+		t.Errorf("excess CAGR = %s, want negative", report.ExcessCAGR)
+	}
+}
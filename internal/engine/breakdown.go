@@ -0,0 +1,172 @@
+package engine
+
+import (
+	"backtester/types"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	sideLong  = "LONG"
+	sideShort = "SHORT"
+	// unknownGroup collects trades whose ticker has no asset or whose entry has no reason
+	unknownGroup = "UNKNOWN"
+)
+
+// PerformanceStats summarises the closed trades of one group, for example one ticker.
+type PerformanceStats struct {
+	Key          string          `json:"key"`
+	Trades       int             `json:"trades"`
+	Wins         int             `json:"wins"`
+	WinRate      decimal.Decimal `json:"win_rate"`
+	NetProfit    decimal.Decimal `json:"net_profit"`
+	AvgWin       decimal.Decimal `json:"avg_win"`
+	AvgLoss      decimal.Decimal `json:"avg_loss"`
+	ProfitFactor decimal.Decimal `json:"profit_factor"`
+	Fees         decimal.Decimal `json:"fees"`
+	// Exposure is the fraction of the run the group had at least one trade open, open trades included
+	Exposure decimal.Decimal `json:"exposure"`
+}
+
+// addBreakdowns splits the round trips of the report by ticker, asset type, side and entry reason.
+func (e *Engine) addBreakdowns(report *Report, start, end time.Time) {
+	report.ByTicker = breakdown(report.Trades, start, end, func(r TradeRecord) string {
+		return r.Ticker
+	})
+	report.ByAssetType = breakdown(report.Trades, start, end, func(r TradeRecord) string {
+		if asset := e.assets[r.Ticker]; asset != nil {
+			return string(asset.Type)
+		}
+		return unknownGroup
+	})
+	report.BySide = breakdown(report.Trades, start, end, func(r TradeRecord) string {
+		if r.Side == types.SideTypeSell {
+			return sideShort
+		}
+		return sideLong
+	})
+	report.ByReason = breakdown(report.Trades, start, end, func(r TradeRecord) string {
+		if r.Reason == "" {
+			return unknownGroup
+		}
+		return r.Reason
+	})
+}
+
+// breakdown groups the trades by key and returns the groups sorted by key. Only closed trades count
+// towards the trade statistics, open trades only towards exposure.
+func breakdown(records []TradeRecord, start, end time.Time, key func(TradeRecord) string) []PerformanceStats {
+	type totals struct {
+		stats        PerformanceStats
+		grossProfit  decimal.Decimal
+		grossLoss    decimal.Decimal
+		losingTrades int
+		open         []timeSpan
+	}
+
+	groups := make(map[string]*totals)
+	for _, record := range records {
+		k := key(record)
+		group, ok := groups[k]
+		if !ok {
+			group = &totals{stats: PerformanceStats{Key: k}}
+			groups[k] = group
+		}
+
+		exit := record.ExitTime
+		if record.Open {
+			exit = end
+		}
+		group.open = append(group.open, timeSpan{from: record.EntryTime, to: exit})
+		if record.Open {
+			continue
+		}
+
+		group.stats.Trades++
+		group.stats.NetProfit = group.stats.NetProfit.Add(record.NetPnL)
+		group.stats.Fees = group.stats.Fees.Add(record.Fees)
+		if record.NetPnL.IsPositive() {
+			group.stats.Wins++
+			group.grossProfit = group.grossProfit.Add(record.NetPnL)
+		} else {
+			group.losingTrades++
+			group.grossLoss = group.grossLoss.Add(record.NetPnL.Abs())
+		}
+	}
+
+	period := end.Sub(start)
+	out := make([]PerformanceStats, 0, len(groups))
+	for _, group := range groups {
+		stats := group.stats
+		if stats.Trades > 0 {
+			stats.WinRate = decimal.NewFromInt(int64(stats.Wins)).Div(decimal.NewFromInt(int64(stats.Trades)))
+		}
+		if stats.Wins > 0 {
+			stats.AvgWin = group.grossProfit.Div(decimal.NewFromInt(int64(stats.Wins)))
+		}
+		if group.losingTrades > 0 {
+			stats.AvgLoss = group.grossLoss.Div(decimal.NewFromInt(int64(group.losingTrades)))
+		}
+		if group.grossLoss.IsPositive() {
+			stats.ProfitFactor = group.grossProfit.Div(group.grossLoss)
+		}
+		if period > 0 {
+			covered := coveredDuration(group.open, start, end)
+			stats.Exposure = decimal.NewFromFloat(covered.Seconds() / period.Seconds())
+		}
+		out = append(out, stats)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+	return out
+}
+
+type timeSpan struct {
+	from, to time.Time
+}
+
+// coveredDuration returns how much of [start, end] is covered by at least one span.
+func coveredDuration(spans []timeSpan, start, end time.Time) time.Duration {
+	sort.Slice(spans, func(i, j int) bool { return spans[i].from.Before(spans[j].from) })
+
+	var covered time.Duration
+	cursor := start
+	for _, span := range spans {
+		from, to := span.from, span.to
+		if from.Before(cursor) {
+			from = cursor
+		}
+		if to.After(end) {
+			to = end
+		}
+		if !to.After(from) {
+			continue
+		}
+		covered += to.Sub(from)
+		cursor = to
+	}
+	return covered
+}
+
+func printBreakdown(title string, groups []PerformanceStats) {
+	if len(groups) == 0 {
+		return
+	}
+	fmt.Printf("\n-- By %s --\n", title)
+	fmt.Printf("%-28s %7s %9s %12s %10s %10s %8s %9s\n", "", "Trades", "Win Rate", "Net Profit", "Avg Win", "Avg Loss", "PF", "Exposure")
+	for _, g := range groups {
+		key := g.Key
+		if len(key) > 28 {
+			key = key[:25] + "..."
+		}
+		fmt.Printf("%-28s %7d %8.2f%% %12.2f %10.2f %10.2f %8.2f %8.2f%%\n", key, g.Trades,
+			g.WinRate.Mul(decimal.NewFromInt(100)).InexactFloat64(),
+			g.NetProfit.InexactFloat64(),
+			g.AvgWin.InexactFloat64(),
+			g.AvgLoss.InexactFloat64(),
+			g.ProfitFactor.InexactFloat64(),
+			g.Exposure.Mul(decimal.NewFromInt(100)).InexactFloat64())
+	}
+}
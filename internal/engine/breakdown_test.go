@@ -0,0 +1,111 @@
+package engine
+
+import (
+	"backtester/types"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestBreakdown(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 0, 10)
+	closed := func(ticker, pnl string, fromDay, toDay int) TradeRecord {
+		return TradeRecord{
+			Ticker:    ticker,
+			NetPnL:    decimal.RequireFromString(pnl),
+			Fees:      decimal.NewFromInt(1),
+			EntryTime: start.AddDate(0, 0, fromDay),
+			ExitTime:  start.AddDate(0, 0, toDay),
+		}
+	}
+	records := []TradeRecord{
+		closed("BBB", "-20", 0, 1),
+		closed("AAA", "30", 0, 2),
+		// Overlaps the first AAA trade, the overlap only counts once
+		closed("AAA", "-10", 1, 3),
+		closed("AAA", "10", 5, 6),
+		{Ticker: "CCC", Open: true, EntryTime: start.AddDate(0, 0, 8)},
+	}
+
+	got := breakdown(records, start, end, func(r TradeRecord) string { return r.Ticker })
+	if len(got) != 3 {
+		t.Fatalf("got %d groups, want 3", len(got))
+	}
+
+	aaa := got[0]
+	if aaa.Key != "AAA" || aaa.Trades != 3 || aaa.Wins != 2 {
+		t.Errorf("AAA: got key=%s trades=%d wins=%d", aaa.Key, aaa.Trades, aaa.Wins)
+	}
+	for name, tt := range map[string]struct {
+		got, want decimal.Decimal
+	}{
+		"net profit":    {aaa.NetProfit, decimal.NewFromInt(30)},
+		"avg win":       {aaa.AvgWin, decimal.NewFromInt(20)},
+		"avg loss":      {aaa.AvgLoss, decimal.NewFromInt(10)},
+		"profit factor": {aaa.ProfitFactor, decimal.NewFromInt(4)},
+		"fees":          {aaa.Fees, decimal.NewFromInt(3)},
+		"win rate":      {aaa.WinRate.Round(4), decimal.RequireFromString("0.6667")},
+		"exposure":      {aaa.Exposure, decimal.RequireFromString("0.4")},
+	} {
+		if !tt.got.Equal(tt.want) {
+			t.Errorf("AAA %s: got=%s, want=%s", name, tt.got, tt.want)
+		}
+	}
+
+	if bbb := got[1]; bbb.Key != "BBB" || !bbb.WinRate.IsZero() || !bbb.ProfitFactor.IsZero() {
+		t.Errorf("BBB: got key=%s win rate=%s profit factor=%s", bbb.Key, bbb.WinRate, bbb.ProfitFactor)
+	}
+	// Open trades only count towards exposure, up to the end of the run
+	if ccc := got[2]; ccc.Trades != 0 || !ccc.Exposure.Equal(decimal.RequireFromString("0.2")) {
+		t.Errorf("CCC: got trades=%d exposure=%s, want 0 and 0.2", ccc.Trades, ccc.Exposure)
+	}
+}
+
+func TestAddBreakdowns(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	trade := func(ticker string, side types.Side, reason string) TradeRecord {
+		return TradeRecord{Ticker: ticker, Side: side, Reason: reason, EntryTime: start, ExitTime: start.Add(time.Hour), NetPnL: decimal.NewFromInt(1)}
+	}
+	e := &Engine{assets: map[string]*types.Asset{
+		"AAPL": {Ticker: "AAPL", Type: types.AssetTypeStock},
+		"BTC":  {Ticker: "BTC", Type: types.AssetTypeCrypto},
+	}}
+	report := &Report{Trades: []TradeRecord{
+		trade("AAPL", types.SideTypeBuy, "breakout"),
+		trade("BTC", types.SideTypeSell, "breakdown"),
+		trade("BTC", types.SideTypeBuy, ""),
+		trade("XYZ", types.SideTypeBuy, "breakout"),
+	}}
+
+	e.addBreakdowns(report, start, start.AddDate(0, 0, 1))
+
+	keys := func(groups []PerformanceStats) map[string]int {
+		out := make(map[string]int)
+		for _, g := range groups {
+			out[g.Key] = g.Trades
+		}
+		return out
+	}
+	for name, tt := range map[string]struct {
+		got  map[string]int
+		want map[string]int
+	}{
+		"ticker":     {keys(report.ByTicker), map[string]int{"AAPL": 1, "BTC": 2, "XYZ": 1}},
+		"asset type": {keys(report.ByAssetType), map[string]int{string(types.AssetTypeStock): 1, string(types.AssetTypeCrypto): 2, unknownGroup: 1}},
+		"side":       {keys(report.BySide), map[string]int{sideLong: 3, sideShort: 1}},
+		"reason":     {keys(report.ByReason), map[string]int{"breakout": 2, "breakdown": 1, unknownGroup: 1}},
+	} {
+		if len(tt.got) != len(tt.want) {
+			t.Errorf("%s: got=%v, want=%v", name, tt.got, tt.want)
+			continue
+		}
+		for k, n := range tt.want {
+			if tt.got[k] != n {
+				t.Errorf("%s: got=%v, want=%v", name, tt.got, tt.want)
+				break
+			}
+		}
+	}
+}
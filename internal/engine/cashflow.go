@@ -0,0 +1,115 @@
+package engine
+
+import (
+	"backtester/types"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+var NegativeCashFlowErr = errors.New("cash flow amount must not be negative")
+
+type CashFlowType string
+
+const (
+	CashFlowDeposit           CashFlowType = "DEPOSIT"
+	CashFlowWithdrawal        CashFlowType = "WITHDRAWAL"
+	CashFlowPercentWithdrawal CashFlowType = "PERCENT_WITHDRAWAL"
+)
+
+// CashFlowConfig describes an external cash flow into or out of the portfolio.
+// A flow is one-off by default; use EveryMonths to turn it into a recurring schedule (e.g. DCA deposits).
+type CashFlowConfig struct {
+	flowType    CashFlowType
+	amount      decimal.Decimal // absolute amount, or a fraction of portfolio value for CashFlowPercentWithdrawal
+	start       time.Time
+	everyMonths int
+	until       time.Time
+}
+
+// CashFlow is an external cash flow that has been applied to the portfolio.
+// Amount is signed: positive for deposits, negative for withdrawals.
+type CashFlow struct {
+	Type   CashFlowType
+	Amount decimal.Decimal
+	Time   time.Time
+}
+
+type scheduledCashFlow struct {
+	flowType CashFlowType
+	amount   decimal.Decimal
+	time     time.Time
+}
+
+func Deposit(amount decimal.Decimal, at time.Time) *CashFlowConfig {
+	return &CashFlowConfig{flowType: CashFlowDeposit, amount: amount, start: at}
+}
+
+func Withdrawal(amount decimal.Decimal, at time.Time) *CashFlowConfig {
+	return &CashFlowConfig{flowType: CashFlowWithdrawal, amount: amount, start: at}
+}
+
+// PercentWithdrawal withdraws a fraction (0.04 = 4%) of the total portfolio value at the time it is applied.
+func PercentWithdrawal(fraction decimal.Decimal, at time.Time) *CashFlowConfig {
+	return &CashFlowConfig{flowType: CashFlowPercentWithdrawal, amount: fraction, start: at}
+}
+
+// EveryMonths repeats the flow every n months from its start time up to and including until.
+func (c *CashFlowConfig) EveryMonths(n int, until time.Time) *CashFlowConfig {
+	c.everyMonths = n
+	c.until = until
+	return c
+}
+
+// expandCashFlows turns the configured flows into a chronological list of single occurrences.
+// Occurrences outside [start, end] are dropped.
+func expandCashFlows(configs []*CashFlowConfig, start, end time.Time) []scheduledCashFlow {
+	var out []scheduledCashFlow
+	for _, cfg := range configs {
+		if cfg.everyMonths <= 0 {
+			if !cfg.start.Before(start) && !cfg.start.After(end) {
+				out = append(out, scheduledCashFlow{flowType: cfg.flowType, amount: cfg.amount, time: cfg.start})
+			}
+			continue
+		}
+
+		for i := 0; ; i++ {
+			t := cfg.start.AddDate(0, i*cfg.everyMonths, 0)
+			if t.After(cfg.until) || t.After(end) {
+				break
+			}
+			if t.Before(start) {
+				continue
+			}
+			out = append(out, scheduledCashFlow{flowType: cfg.flowType, amount: cfg.amount, time: t})
+		}
+	}
+
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i].time.Before(out[j].time)
+	})
+	return out
+}
+
+// timeWeightedEquity returns the equity curve of the snapshots with external cash flows removed.
+// Each period return is (V_i - F_i) / V_i-1 where F_i is the net flow since the previous snapshot.
+// The curve is expressed in the currency of the first snapshot, so it equals the raw
+// portfolio value when no cash flows occurred. Snapshots must be in chronological order.
+func timeWeightedEquity(snapshots []types.PortfolioView) []decimal.Decimal {
+	out := make([]decimal.Decimal, len(snapshots))
+	scale := decimal.NewFromInt(1)
+
+	for i, snap := range snapshots {
+		value := portfolioValue(snap)
+		if i > 0 {
+			flow := snap.NetContributions.Sub(snapshots[i-1].NetContributions)
+			if !flow.IsZero() && !value.IsZero() {
+				scale = scale.Mul(value.Sub(flow)).Div(value)
+			}
+		}
+		out[i] = value.Mul(scale)
+	}
+	return out
+}
@@ -0,0 +1,168 @@
+package engine
+
+import (
+	"backtester/types"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestExpandCashFlows(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2020, 12, 31, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		configs   []*CashFlowConfig
+		wantTimes []time.Time
+	}{
+		{
+			name:      "one-off inside range",
+			configs:   []*CashFlowConfig{Deposit(decimal.NewFromInt(100), start.AddDate(0, 2, 0))},
+			wantTimes: []time.Time{start.AddDate(0, 2, 0)},
+		},
+		{
+			name: "one-off outside range is dropped",
+			configs: []*CashFlowConfig{
+				Deposit(decimal.NewFromInt(100), start.AddDate(-1, 0, 0)),
+				Withdrawal(decimal.NewFromInt(100), end.AddDate(0, 0, 1)),
+			},
+			wantTimes: nil,
+		},
+		{
+			name:    "quarterly deposit stops at until",
+			configs: []*CashFlowConfig{Deposit(decimal.NewFromInt(100), start).EveryMonths(3, start.AddDate(0, 7, 0))},
+			wantTimes: []time.Time{
+				start,
+				start.AddDate(0, 3, 0),
+				start.AddDate(0, 6, 0),
+			},
+		},
+		{
+			name: "recurring flow starting before the run only keeps occurrences in range",
+			configs: []*CashFlowConfig{
+				Deposit(decimal.NewFromInt(100), start.AddDate(0, -2, 0)).EveryMonths(1, start.AddDate(0, 1, 0)),
+			},
+			wantTimes: []time.Time{start, start.AddDate(0, 1, 0)},
+		},
+		{
+			name: "flows are sorted chronologically",
+			configs: []*CashFlowConfig{
+				Withdrawal(decimal.NewFromInt(50), start.AddDate(0, 5, 0)),
+				Deposit(decimal.NewFromInt(100), start.AddDate(0, 1, 0)),
+			},
+			wantTimes: []time.Time{start.AddDate(0, 1, 0), start.AddDate(0, 5, 0)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := expandCashFlows(tt.configs, start, end)
+			if len(got) != len(tt.wantTimes) {
+				t.Fatalf("len(got)=%d, want %d (%v)", len(got), len(tt.wantTimes), got)
+			}
+			for i, want := range tt.wantTimes {
+				if !got[i].time.Equal(want) {
+					t.Errorf("flow %d time = %s, want %s", i, got[i].time, want)
+				}
+			}
+		})
+	}
+}
+
+func TestTimeWeightedEquity(t *testing.T) {
+	base := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	withFlow := func(pv types.PortfolioView, contributions string) types.PortfolioView {
+		pv.NetContributions = decimal.RequireFromString(contributions)
+		return pv
+	}
+
+	tests := []struct {
+		name      string
+		snapshots []types.PortfolioView
+		want      []decimal.Decimal
+	}{
+		{
+			name: "no flows equals raw portfolio value",
+			snapshots: []types.PortfolioView{
+				newPv(base, "1000"),
+				newPv(base.AddDate(0, 0, 1), "1100"),
+				newPv(base.AddDate(0, 0, 2), "990"),
+			},
+			want: []decimal.Decimal{
+				decimal.RequireFromString("1000"),
+				decimal.RequireFromString("1100"),
+				decimal.RequireFromString("990"),
+			},
+		},
+		{
+			name: "deposit does not count as growth",
+			// 1000 -> deposit 1000 -> 2000 (0% return) -> 2200 (+10%)
+			snapshots: []types.PortfolioView{
+				newPv(base, "1000"),
+				withFlow(newPv(base.AddDate(0, 0, 1), "2000"), "1000"),
+				withFlow(newPv(base.AddDate(0, 0, 2), "2200"), "1000"),
+			},
+			want: []decimal.Decimal{
+				decimal.RequireFromString("1000"),
+				decimal.RequireFromString("1000"),
+				decimal.RequireFromString("1100"),
+			},
+		},
+		{
+			name: "withdrawal does not count as loss",
+			// 1000 -> +10% to 1100 -> withdraw 550 -> 550 (0% return)
+			snapshots: []types.PortfolioView{
+				newPv(base, "1000"),
+				newPv(base.AddDate(0, 0, 1), "1100"),
+				withFlow(newPv(base.AddDate(0, 0, 2), "550"), "-550"),
+			},
+			want: []decimal.Decimal{
+				decimal.RequireFromString("1000"),
+				decimal.RequireFromString("1100"),
+				decimal.RequireFromString("1100"),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := timeWeightedEquity(tt.snapshots)
+			if len(got) != len(tt.want) {
+				t.Fatalf("len(got)=%d, want %d", len(got), len(tt.want))
+			}
+			for i := range got {
+				if !got[i].Equal(tt.want[i]) {
+					t.Errorf("index %d: got=%s, want=%s", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestBacktest_AppliesScheduledCashFlows(t *testing.T) {
+	start := time.UnixMilli(0).UTC()
+	feeds := Instruments(Instrument("AAPL", start, start.Add(5*time.Minute), testInterval))
+
+	engine := mockEngine(&allocatorStrategy{}, feeds, &mockAllocator{}, &mockBroker{})
+	engine.portfolioConfig.AddCashFlow(
+		Deposit(decimal.NewFromInt(500), start.Add(time.Minute)).EveryMonths(1, start.Add(time.Hour)),
+		Withdrawal(decimal.NewFromInt(200), start.Add(3*time.Minute)),
+	)
+
+	if _, err := engine.Run(); err != nil {
+		t.Fatalf("Error running engine: %v", err)
+	}
+
+	if want := decimal.NewFromInt(100300); !engine.portfolio.cash.Equal(want) {
+		t.Errorf("cash = %s, want %s", engine.portfolio.cash, want)
+	}
+	if want := decimal.NewFromInt(300); !engine.portfolio.netContributions.Equal(want) {
+		t.Errorf("net contributions = %s, want %s", engine.portfolio.netContributions, want)
+	}
+	if len(engine.portfolio.cashFlows) != 2 {
+		t.Fatalf("expected 2 applied cash flows, got %d", len(engine.portfolio.cashFlows))
+	}
+}
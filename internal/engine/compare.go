@@ -0,0 +1,442 @@
+package engine
+
+import (
+	"backtester/types"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+var TooFewRunsErr = errors.New("comparison needs at least two runs")
+
+// comparisonColors are the equity curve colors of the compared runs, the baseline first.
+var comparisonColors = []string{colorStrategy, "#ff7f0e", colorGain, colorLoss, "#9467bd", "#8c564b", "#e377c2", colorBenchmark}
+
+// Comparison lines up the metrics, equity curves and trades of two or more runs against the first
+// run, the baseline.
+type Comparison struct {
+	Runs    []string
+	Metrics []MetricComparison
+	// Trades holds the differences of every run after the baseline
+	Trades []TradeDiff
+	runs   []types.Run
+}
+
+// MetricComparison is one metric across the runs. A value is invalid when the run does not have the
+// metric. Deltas are against the baseline.
+type MetricComparison struct {
+	Name   string
+	Values []decimal.NullDecimal
+	Deltas []decimal.NullDecimal
+}
+
+// TradeDiff lists the trades of a run that differ from the baseline. Trades match on ticker, side and
+// entry time. Added trades are only in the run, removed trades only in the baseline.
+type TradeDiff struct {
+	Run     string
+	Added   []types.RunTrade
+	Removed []types.RunTrade
+	Changed []TradeChange
+}
+
+// TradeChange is a matched trade with a different quantity, entry price or exit price.
+type TradeChange struct {
+	Baseline types.RunTrade
+	Run      types.RunTrade
+}
+
+// CompareRuns compares saved runs or runs loaded from JSON reports, see LoadReportJSONFile.
+func CompareRuns(runs ...types.Run) (*Comparison, error) {
+	if len(runs) < 2 {
+		return nil, TooFewRunsErr
+	}
+
+	c := &Comparison{runs: runs}
+	for _, run := range runs {
+		c.Runs = append(c.Runs, runLabel(run))
+	}
+
+	names := make(map[string]bool)
+	for _, run := range runs {
+		for name := range run.Metrics {
+			names[name] = true
+		}
+	}
+	for name := range names {
+		metric := MetricComparison{Name: name}
+		base, hasBase := runs[0].Metrics[name]
+		for _, run := range runs {
+			value, ok := run.Metrics[name]
+			metric.Values = append(metric.Values, decimal.NullDecimal{Decimal: value, Valid: ok})
+			metric.Deltas = append(metric.Deltas, decimal.NullDecimal{Decimal: value.Sub(base), Valid: ok && hasBase})
+		}
+		c.Metrics = append(c.Metrics, metric)
+	}
+	sort.Slice(c.Metrics, func(i, j int) bool { return c.Metrics[i].Name < c.Metrics[j].Name })
+
+	for i, run := range runs[1:] {
+		diff := diffTrades(runs[0].Trades, run.Trades)
+		diff.Run = c.Runs[i+1]
+		c.Trades = append(c.Trades, diff)
+	}
+	return c, nil
+}
+
+func runLabel(run types.Run) string {
+	switch {
+	case run.Name == "":
+		return run.RunID
+	case run.RunID == "":
+		return run.Name
+	default:
+		return fmt.Sprintf("%s (%s)", run.Name, run.RunID)
+	}
+}
+
+type tradeKey struct {
+	ticker string
+	side   types.Side
+	entry  int64
+}
+
+func keyOf(trade types.RunTrade) tradeKey {
+	return tradeKey{ticker: trade.Ticker, side: trade.Side, entry: trade.EntryTime.UnixNano()}
+}
+
+// diffTrades matches trades in order, so with several entries on the same key the first baseline
+// trade matches the first trade of the run.
+func diffTrades(baseline, trades []types.RunTrade) TradeDiff {
+	unmatched := make(map[tradeKey][]int)
+	for i, trade := range baseline {
+		unmatched[keyOf(trade)] = append(unmatched[keyOf(trade)], i)
+	}
+
+	var diff TradeDiff
+	matched := make([]bool, len(baseline))
+	for _, trade := range trades {
+		key := keyOf(trade)
+		candidates := unmatched[key]
+		if len(candidates) == 0 {
+			diff.Added = append(diff.Added, trade)
+			continue
+		}
+		base := baseline[candidates[0]]
+		matched[candidates[0]] = true
+		unmatched[key] = candidates[1:]
+		if !base.Quantity.Equal(trade.Quantity) || !base.EntryPrice.Equal(trade.EntryPrice) || !base.ExitPrice.Equal(trade.ExitPrice) {
+			diff.Changed = append(diff.Changed, TradeChange{Baseline: base, Run: trade})
+		}
+	}
+	for i, trade := range baseline {
+		if !matched[i] {
+			diff.Removed = append(diff.Removed, trade)
+		}
+	}
+	return diff
+}
+
+// LoadReportJSONFile reads a report written by ReportingConfig.JSONReport as a run, for CompareRuns.
+func LoadReportJSONFile(path string) (types.Run, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return types.Run{}, fmt.Errorf("open json report: %w", err)
+	}
+	defer f.Close()
+
+	run, err := readReportJSON(f)
+	if err != nil {
+		return types.Run{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return run, nil
+}
+
+func readReportJSON(r io.Reader) (types.Run, error) {
+	var doc struct {
+		Name   string `json:"name"`
+		Config struct {
+			Start time.Time `json:"start"`
+			End   time.Time `json:"end"`
+		} `json:"config"`
+		Metrics   map[string]json.RawMessage `json:"metrics"`
+		Trades    []TradeRecord              `json:"trades"`
+		Snapshots []jsonSnapshot             `json:"snapshots"`
+	}
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return types.Run{}, fmt.Errorf("decode json report: %w", err)
+	}
+
+	run := types.Run{
+		Name:    doc.Name,
+		Start:   doc.Config.Start,
+		End:     doc.Config.End,
+		Metrics: metricValues(doc.Metrics),
+	}
+	if id, ok := doc.Metrics["run_id"]; ok {
+		_ = json.Unmarshal(id, &run.RunID)
+	}
+	for _, record := range doc.Trades {
+		run.Trades = append(run.Trades, runTrade(record, run.End))
+	}
+	for _, snap := range doc.Snapshots {
+		run.Equity = append(run.Equity, types.EquityPoint{
+			Time:             snap.Time,
+			Equity:           snap.TotalValue,
+			Cash:             snap.Cash,
+			NetContributions: snap.NetContributions,
+		})
+	}
+	return run, nil
+}
+
+// PrintComparison prints the metrics of the runs side by side with the deltas to the baseline,
+// followed by the trades that differ.
+func PrintComparison(c *Comparison) {
+	writeComparison(os.Stdout, c)
+}
+
+func writeComparison(w io.Writer, c *Comparison) {
+	fmt.Fprintln(w, "===== Run Comparison =====")
+	for i, label := range c.Runs {
+		role := "run"
+		if i == 0 {
+			role = "baseline"
+		}
+		fmt.Fprintf(w, "[%d] %s (%s)\n", i, label, role)
+	}
+
+	fmt.Fprintln(w, "\n-- Metrics --")
+	fmt.Fprintf(w, "%-32s", "")
+	for i := range c.Runs {
+		fmt.Fprintf(w, " %14s", fmt.Sprintf("[%d]", i))
+		if i > 0 {
+			fmt.Fprintf(w, " %14s", fmt.Sprintf("delta [%d]", i))
+		}
+	}
+	fmt.Fprintln(w)
+	for _, metric := range c.Metrics {
+		fmt.Fprintf(w, "%-32s", metric.Name)
+		for i, value := range metric.Values {
+			fmt.Fprintf(w, " %14s", formatNullDecimal(value, false))
+			if i > 0 {
+				fmt.Fprintf(w, " %14s", formatNullDecimal(metric.Deltas[i], true))
+			}
+		}
+		fmt.Fprintln(w)
+	}
+
+	for i, diff := range c.Trades {
+		fmt.Fprintf(w, "\n-- Trades [%d] vs [0]: %d added, %d removed, %d changed --\n", i+1, len(diff.Added), len(diff.Removed), len(diff.Changed))
+		for _, trade := range diff.Added {
+			fmt.Fprintf(w, "+ %s\n", formatRunTrade(trade))
+		}
+		for _, trade := range diff.Removed {
+			fmt.Fprintf(w, "- %s\n", formatRunTrade(trade))
+		}
+		for _, change := range diff.Changed {
+			fmt.Fprintf(w, "~ %s\n  %s\n", formatRunTrade(change.Baseline), formatRunTrade(change.Run))
+		}
+	}
+}
+
+func formatNullDecimal(value decimal.NullDecimal, signed bool) string {
+	if !value.Valid {
+		return "-"
+	}
+	s := value.Decimal.StringFixed(4)
+	if signed && value.Decimal.IsPositive() {
+		s = "+" + s
+	}
+	return s
+}
+
+func formatRunTrade(trade types.RunTrade) string {
+	return fmt.Sprintf("%s %s %s qty=%s entry=%s exit=%s pnl=%s", trade.EntryTime.Format(time.DateTime), trade.Ticker, trade.Side,
+		trade.Quantity.String(), trade.EntryPrice.StringFixed(2), trade.ExitPrice.StringFixed(2), trade.NetPnL.StringFixed(2))
+}
+
+type comparisonSheet struct {
+	Title   string
+	Runs    []string
+	Equity  template.HTML
+	Metrics []comparisonSheetMetric
+	Trades  []comparisonSheetDiff
+}
+
+type comparisonSheetMetric struct {
+	Name   string
+	Values []string
+}
+
+type comparisonSheetDiff struct {
+	Run  string
+	Rows []comparisonSheetTrade
+}
+
+type comparisonSheetTrade struct {
+	Change string
+	Trade  string
+}
+
+// WriteComparisonHTMLFile writes a self-contained HTML page with the equity curves of the runs
+// overlaid, the metrics table and the differing trades.
+func WriteComparisonHTMLFile(path string, c *Comparison) error {
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create comparison file: %w", err)
+	}
+	defer f.Close()
+
+	return writeComparisonHTML(f, c)
+}
+
+func writeComparisonHTML(w io.Writer, c *Comparison) error {
+	times, curves := alignEquityCurves(c.runs)
+	series := make([]chartSeries, len(curves))
+	for i, curve := range curves {
+		series[i] = chartSeries{name: c.Runs[i], color: comparisonColors[i%len(comparisonColors)], values: curve}
+	}
+
+	sheet := comparisonSheet{
+		Title:  "Run Comparison",
+		Runs:   c.Runs,
+		Equity: lineChartSVG(times, series),
+	}
+	for _, metric := range c.Metrics {
+		row := comparisonSheetMetric{Name: metric.Name}
+		for i, value := range metric.Values {
+			cell := formatNullDecimal(value, false)
+			if i > 0 && metric.Deltas[i].Valid {
+				cell += " (" + formatNullDecimal(metric.Deltas[i], true) + ")"
+			}
+			row.Values = append(row.Values, cell)
+		}
+		sheet.Metrics = append(sheet.Metrics, row)
+	}
+	for _, diff := range c.Trades {
+		sheetDiff := comparisonSheetDiff{Run: diff.Run}
+		for _, trade := range diff.Added {
+			sheetDiff.Rows = append(sheetDiff.Rows, comparisonSheetTrade{"Added", formatRunTrade(trade)})
+		}
+		for _, trade := range diff.Removed {
+			sheetDiff.Rows = append(sheetDiff.Rows, comparisonSheetTrade{"Removed", formatRunTrade(trade)})
+		}
+		for _, change := range diff.Changed {
+			sheetDiff.Rows = append(sheetDiff.Rows, comparisonSheetTrade{"Changed", formatRunTrade(change.Baseline) + " → " + formatRunTrade(change.Run)})
+		}
+		sheet.Trades = append(sheet.Trades, sheetDiff)
+	}
+
+	if err := comparisonTemplate.Execute(w, sheet); err != nil {
+		return fmt.Errorf("render comparison: %w", err)
+	}
+	return nil
+}
+
+// alignEquityCurves puts the time-weighted equity of every run on the union of their snapshot times.
+// A run keeps its last value between its own snapshots and its first value before them.
+func alignEquityCurves(runs []types.Run) ([]time.Time, [][]float64) {
+	seen := make(map[int64]bool)
+	var times []time.Time
+	for _, run := range runs {
+		for _, point := range run.Equity {
+			if !seen[point.Time.UnixNano()] {
+				seen[point.Time.UnixNano()] = true
+				times = append(times, point.Time)
+			}
+		}
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+
+	curves := make([][]float64, len(runs))
+	for i, run := range runs {
+		views := make([]types.PortfolioView, len(run.Equity))
+		for j, point := range run.Equity {
+			views[j] = types.PortfolioView{Time: point.Time, Cash: point.Equity, NetContributions: point.NetContributions}
+		}
+		equity := toFloats(timeWeightedEquity(views))
+
+		curve := make([]float64, len(times))
+		next := 0
+		for j, t := range times {
+			for next < len(views) && !views[next].Time.After(t) {
+				next++
+			}
+			switch {
+			case len(equity) == 0:
+			case next == 0:
+				curve[j] = equity[0]
+			default:
+				curve[j] = equity[next-1]
+			}
+		}
+		curves[i] = curve
+	}
+	return times, curves
+}
+
+var comparisonTemplate = template.Must(template.New("comparison").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em auto; max-width: 960px; color: #222; }
+h2 { margin-top: 2em; border-bottom: 1px solid #ddd; }
+svg { width: 100%; height: auto; }
+svg .grid { stroke: #eee; }
+svg .axis { font-size: 11px; fill: #555; }
+svg .legend { font-size: 12px; }
+table { border-collapse: collapse; width: 100%; font-size: 0.85em; }
+th, td { padding: 0.25em 0.5em; text-align: right; border-bottom: 1px solid #eee; }
+th:first-child, td:first-child { text-align: left; }
+td.trade { text-align: left; font-family: monospace; }
+.empty { color: #999; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<ol start="0">
+{{- range .Runs}}
+<li>{{.}}</li>
+{{- end}}
+</ol>
+
+<h2>Equity</h2>
+{{.Equity}}
+
+<h2>Metrics</h2>
+<table>
+<tr><th>Metric</th>{{range $i, $run := .Runs}}<th>[{{$i}}]</th>{{end}}</tr>
+{{- range .Metrics}}
+<tr><td>{{.Name}}</td>{{range .Values}}<td>{{.}}</td>{{end}}</tr>
+{{- end}}
+</table>
+
+{{- range .Trades}}
+<h2>Trades of {{.Run}}</h2>
+{{- if .Rows}}
+<table>
+<tr><th>Change</th><th>Trade</th></tr>
+{{- range .Rows}}
+<tr><td>{{.Change}}</td><td class="trade">{{.Trade}}</td></tr>
+{{- end}}
+</table>
+{{- else}}
+<p class="empty">Same trades as the baseline.</p>
+{{- end}}
+{{- end}}
+</body>
+</html>
+`))
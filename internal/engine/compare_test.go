@@ -0,0 +1,141 @@
+package engine
+
+import (
+	"backtester/types"
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func compareTrade(ticker string, side types.Side, day int, qty, entry, exit float64) types.RunTrade {
+	return types.RunTrade{
+		Ticker:     ticker,
+		Side:       side,
+		EntryTime:  time.Date(2024, 1, day, 0, 0, 0, 0, time.UTC),
+		ExitTime:   time.Date(2024, 1, day+1, 0, 0, 0, 0, time.UTC),
+		Quantity:   decimal.NewFromFloat(qty),
+		EntryPrice: decimal.NewFromFloat(entry),
+		ExitPrice:  decimal.NewFromFloat(exit),
+	}
+}
+
+func TestDiffTrades(t *testing.T) {
+	baseline := []types.RunTrade{
+		compareTrade("AAPL", types.SideTypeBuy, 1, 1, 100, 110),
+		compareTrade("AAPL", types.SideTypeBuy, 1, 2, 100, 110),
+		compareTrade("MSFT", types.SideTypeBuy, 2, 1, 50, 55),
+		compareTrade("TSLA", types.SideTypeSell, 3, 1, 20, 18),
+	}
+	tests := []struct {
+		name        string
+		trades      []types.RunTrade
+		wantAdded   int
+		wantRemoved int
+		wantChanged int
+	}{
+		{"should find no differences", baseline, 0, 0, 0},
+		{"should find removed trades", baseline[:2], 0, 2, 0},
+		{"should find added trade", append(append([]types.RunTrade{}, baseline...), compareTrade("NVDA", types.SideTypeBuy, 4, 1, 10, 12)), 1, 0, 0},
+		{"should match on side", []types.RunTrade{baseline[0], baseline[1], baseline[2], compareTrade("TSLA", types.SideTypeBuy, 3, 1, 20, 18)}, 1, 1, 0},
+		{"should find changed size and price", []types.RunTrade{
+			baseline[0],
+			compareTrade("AAPL", types.SideTypeBuy, 1, 3, 100, 110),
+			compareTrade("MSFT", types.SideTypeBuy, 2, 1, 50, 60),
+			baseline[3],
+		}, 0, 0, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff := diffTrades(baseline, tt.trades)
+			if len(diff.Added) != tt.wantAdded || len(diff.Removed) != tt.wantRemoved || len(diff.Changed) != tt.wantChanged {
+				t.Errorf("diffTrades() added=%d removed=%d changed=%d, want %d %d %d",
+					len(diff.Added), len(diff.Removed), len(diff.Changed), tt.wantAdded, tt.wantRemoved, tt.wantChanged)
+			}
+		})
+	}
+}
+
+func TestCompareRuns(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	base := types.Run{
+		RunID:   "a",
+		Name:    "donchian",
+		Metrics: map[string]decimal.Decimal{"cagr": decimal.NewFromFloat(0.1), "sharpe_ratio": decimal.NewFromFloat(1)},
+		Trades:  []types.RunTrade{compareTrade("AAPL", types.SideTypeBuy, 1, 1, 100, 110)},
+		Equity: []types.EquityPoint{
+			{Time: start, Equity: decimal.NewFromInt(1000)},
+			{Time: start.AddDate(0, 0, 2), Equity: decimal.NewFromInt(1100)},
+		},
+	}
+	run := types.Run{
+		RunID:   "b",
+		Name:    "donchian",
+		Metrics: map[string]decimal.Decimal{"cagr": decimal.NewFromFloat(0.15)},
+		Equity: []types.EquityPoint{
+			{Time: start, Equity: decimal.NewFromInt(1000)},
+			{Time: start.AddDate(0, 0, 1), Equity: decimal.NewFromInt(1050)},
+		},
+	}
+
+	if _, err := CompareRuns(base); !errors.Is(err, TooFewRunsErr) {
+		t.Fatalf("CompareRuns() error = %v, want %v", err, TooFewRunsErr)
+	}
+
+	c, err := CompareRuns(base, run)
+	if err != nil {
+		t.Fatalf("CompareRuns() error = %v", err)
+	}
+	if len(c.Metrics) != 2 || c.Metrics[0].Name != "cagr" || c.Metrics[1].Name != "sharpe_ratio" {
+		t.Fatalf("metrics = %+v, want cagr and sharpe_ratio", c.Metrics)
+	}
+	if delta := c.Metrics[0].Deltas[1]; !delta.Valid || !delta.Decimal.Equal(decimal.NewFromFloat(0.05)) {
+		t.Errorf("cagr delta = %+v, want 0.05", delta)
+	}
+	if c.Metrics[1].Values[1].Valid || c.Metrics[1].Deltas[1].Valid {
+		t.Errorf("missing sharpe ratio = %+v, want invalid", c.Metrics[1])
+	}
+	if len(c.Trades) != 1 || len(c.Trades[0].Removed) != 1 || c.Trades[0].Run != "donchian (b)" {
+		t.Errorf("trades = %+v, want one removed trade of donchian (b)", c.Trades)
+	}
+
+	times, curves := alignEquityCurves(c.runs)
+	if len(times) != 3 || curves[0][1] != 1000 || curves[1][2] != 1050 {
+		t.Errorf("aligned %v to %v, want three points with carried values", times, curves)
+	}
+
+	var buf bytes.Buffer
+	if err := writeComparisonHTML(&buf, c); err != nil {
+		t.Fatalf("writeComparisonHTML() error = %v", err)
+	}
+	if n := strings.Count(buf.String(), "<polyline"); n != 2 {
+		t.Errorf("got %d equity curves, want 2", n)
+	}
+}
+
+func TestReadReportJSON(t *testing.T) {
+	engine := mockEngine(&alternatingStrategy{}, mockInstrument(), &signalAllocator{}, &fillingBroker{}).WithRunID("run-1").Quiet()
+	report, err := engine.Run()
+	if err != nil {
+		t.Fatalf("Error running engine: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := writeReportJSON(&buf, engine.reportDocument(report)); err != nil {
+		t.Fatalf("writeReportJSON() error = %v", err)
+	}
+	run, err := readReportJSON(&buf)
+	if err != nil {
+		t.Fatalf("readReportJSON() error = %v", err)
+	}
+	if run.RunID != "run-1" || len(run.Trades) != len(report.Trades) || len(run.Equity) != len(engine.portfolio.snapshots) {
+		t.Errorf("run %s has %d trades and %d equity points, want run-1 with %d and %d",
+			run.RunID, len(run.Trades), len(run.Equity), len(report.Trades), len(engine.portfolio.snapshots))
+	}
+	if want := reportMetricValues(report); len(run.Metrics) != len(want) || !run.Metrics["net_profit"].Equal(want["net_profit"]) {
+		t.Errorf("read %d metrics with net profit %s, want %d with %s", len(run.Metrics), run.Metrics["net_profit"], len(want), want["net_profit"])
+	}
+}
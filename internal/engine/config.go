@@ -46,6 +46,7 @@ func (c *InstrumentConfig) AddContext(interval types.Interval) *InstrumentConfig
 type PortfolioConfig struct {
 	initialCash       decimal.Decimal
 	allowShortSelling bool
+	cashFlows         []*CashFlowConfig
 }
 
 func NewPortfolioConfig(initialCash decimal.Decimal, allowShortSelling bool) *PortfolioConfig {
@@ -55,6 +56,13 @@ func NewPortfolioConfig(initialCash decimal.Decimal, allowShortSelling bool) *Po
 	}
 }
 
+// AddCashFlow schedules deposits and withdrawals. Flows added before the engine runs are applied, also
+// after NewEngine.
+func (c *PortfolioConfig) AddCashFlow(flows ...*CashFlowConfig) *PortfolioConfig {
+	c.cashFlows = append(c.cashFlows, flows...)
+	return c
+}
+
 type ExecutionConfig struct {
 	interval   types.Interval
 	barsBefore int
@@ -76,6 +84,22 @@ type ReportingConfig struct {
 	printTrades        bool
 	reportName         string
 	filePath           string
+	htmlReport         bool
+	jsonReport         bool
+	manifest           bool
+
+	benchmarkTicker     string
+	benchmarkBuyAndHold bool
+	annualization       map[types.AssetType]int
+	drawdownEpisodes    int
+}
+
+// withoutFiles returns a copy of the config that writes no report files, for engines that run side by
+// side or only re-run a result.
+func (c *ReportingConfig) withoutFiles() *ReportingConfig {
+	files := *c
+	files.printTrades, files.htmlReport, files.jsonReport, files.manifest = false, false, false, false
+	return &files
 }
 
 func NewReportingConfig(sharpeRiskFreeRate decimal.Decimal, reportFile bool, reportName string, filePath string) *ReportingConfig {
@@ -13,7 +13,7 @@ import (
 )
 
 // writeTradesCSVFile writes trades to a CSV file at the given path.
-func (e *Engine) writePortfolioCSVFile(path string, views []types.PortfolioView) error {
+func (e *Engine) writePortfolioCSVFile(path string, views []types.PortfolioView, benchmark []decimal.Decimal) error {
 	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
 		return fmt.Errorf("failed to create directory: %w", err)
 	}
@@ -24,10 +24,11 @@ func (e *Engine) writePortfolioCSVFile(path string, views []types.PortfolioView)
 	}
 	defer f.Close()
 
-	return writePortfolioCSV(f, views)
+	return writePortfolioCSV(f, views, benchmark)
 }
 
-func writePortfolioCSV(w io.Writer, views []types.PortfolioView) error {
+// writePortfolioCSV writes one row per snapshot. benchmark holds the benchmark value per snapshot and may be nil.
+func writePortfolioCSV(w io.Writer, views []types.PortfolioView, benchmark []decimal.Decimal) error {
 	cw := csv.NewWriter(w)
 	defer cw.Flush()
 
@@ -38,14 +39,21 @@ func writePortfolioCSV(w io.Writer, views []types.PortfolioView) error {
 		"positions_value",       // decimal: sum(qty * last_market_price)
 		"total_portfolio_value", // decimal: cash + positions_value
 		"num_positions",         // int: count of positions
+		"net_contributions",     // decimal: cumulative deposits - withdrawals
+		"long_value",            // decimal: market value of long positions
+		"short_value",           // decimal: absolute market value of short positions
+		"gross_exposure",        // decimal: (long + short) / total_portfolio_value
+		"net_exposure",          // decimal: (long - short) / total_portfolio_value
+		"cash_utilisation",      // decimal: 1 - cash / total_portfolio_value, at least 0
+		"benchmark_value",       // decimal: benchmark equity, empty without a benchmark
 	}
 	if err := cw.Write(header); err != nil {
 		return fmt.Errorf("write header: %w", err)
 	}
 
-	for _, pv := range views {
+	for i, pv := range views {
 		positionsValue := decimal.Zero
-		numPositions := len(pv.Positions)
+		exposure := exposureOf(pv)
 
 		for _, pos := range pv.Positions {
 			// value = quantity * last market price
@@ -60,7 +68,17 @@ func writePortfolioCSV(w io.Writer, views []types.PortfolioView) error {
 			pv.Cash.StringFixed(2),
 			positionsValue.StringFixed(2),
 			totalValue.StringFixed(2),
-			fmt.Sprintf("%d", numPositions),
+			fmt.Sprintf("%d", exposure.openPositions),
+			pv.NetContributions.StringFixed(2),
+			exposure.longValue.StringFixed(2),
+			exposure.shortValue.StringFixed(2),
+			exposure.grossPct.StringFixed(4),
+			exposure.netPct.StringFixed(4),
+			exposure.cashUtilisation.StringFixed(4),
+			"",
+		}
+		if i < len(benchmark) {
+			record[len(record)-1] = benchmark[i].StringFixed(2)
 		}
 
 		if err := cw.Write(record); err != nil {
@@ -77,7 +95,7 @@ func writePortfolioCSV(w io.Writer, views []types.PortfolioView) error {
 }
 
 // writeTradesCSVFile writes trades to a CSV file at the given path.
-func (e *Engine) writeTradesCSVFile(path string, trades []trade) error {
+func (e *Engine) writeTradesCSVFile(path string, trades []TradeRecord) error {
 	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
 		return fmt.Errorf("failed to create directory: %w", err)
 	}
@@ -91,44 +109,91 @@ func (e *Engine) writeTradesCSVFile(path string, trades []trade) error {
 	return writeTradesCSV(f, trades)
 }
 
-// writeTradesCSV writes trades to any io.Writer as CSV.
+// writeTradesCSV writes one row per round trip to any io.Writer as CSV.
 // You can pass os.Stdout for debugging, or a file.
-func writeTradesCSV(w io.Writer, trades []trade) error {
+func writeTradesCSV(w io.Writer, trades []TradeRecord) error {
 	cw := csv.NewWriter(w)
 	defer cw.Flush()
 
 	// Header row
 	header := []string{
 		"trade_id",
-		"leg", // "buy" or "sell"
 		"ticker",
-		"side",
-		"status",
-		"total_filled_qty",
-		"avg_fill_price",
-		"total_fees",
-		"remaining_qty",
-		"num_fills",
-		"reject_reason",
-		"report_time", // RFC3339
+		"direction", // "long" or "short"
+		"status",    // "closed" or "open"
+		"entry_time",
+		"exit_time",
+		"quantity",
+		"entry_price",
+		"exit_price",
+		"fees",
+		"net_pnl",
+		"return_pct",
+		"bars_held", // execution feed bars
+		"duration_hours",
+		"mae_pct",
+		"mfe_pct",
+		"stop_price",
+		"r_multiple",
+		"entry_reason",
+		"exit_reason",
 	}
 	if err := cw.Write(header); err != nil {
 		return fmt.Errorf("write header: %w", err)
 	}
 
+	pct := func(d decimal.Decimal) string { return d.Mul(decimal.NewFromInt(100)).StringFixed(2) }
 	for i, t := range trades {
-		tradeID := fmt.Sprintf("%d", i)
+		direction := "long"
+		if t.Side == types.SideTypeSell {
+			direction = "short"
+		}
 
-		if t.buy != nil {
-			if err := writeExecutionRow(cw, tradeID, "buy", t.buy); err != nil {
-				return err
+		// Exit columns stay empty while the trade is open
+		status, exitTime, exitPrice, netPnL, returnPct := "open", "", "", "", ""
+		bars, duration, mae, mfe, rMultiple := "", "", "", "", ""
+		if !t.Open {
+			status = "closed"
+			exitTime = t.ExitTime.Format(time.RFC3339)
+			exitPrice = t.ExitPrice.String()
+			netPnL = t.NetPnL.StringFixed(2)
+			returnPct = pct(t.Return)
+			bars = fmt.Sprintf("%d", t.Bars)
+			duration = fmt.Sprintf("%.2f", t.Duration.Hours())
+			mae, mfe = pct(t.MAE), pct(t.MFE)
+		}
+		stop := ""
+		if !t.Stop.IsZero() {
+			stop = t.Stop.String()
+			if !t.Open {
+				rMultiple = t.RMultiple.StringFixed(2)
 			}
 		}
 
-		if t.sell != nil {
-			if err := writeExecutionRow(cw, tradeID, "sell", t.sell); err != nil {
-				return err
-			}
+		record := []string{
+			fmt.Sprintf("%d", i),
+			t.Ticker,
+			direction,
+			status,
+			t.EntryTime.Format(time.RFC3339),
+			exitTime,
+			t.Quantity.String(),
+			t.EntryPrice.String(),
+			exitPrice,
+			t.Fees.StringFixed(2),
+			netPnL,
+			returnPct,
+			bars,
+			duration,
+			mae,
+			mfe,
+			stop,
+			rMultiple,
+			t.Reason,
+			t.ExitReason,
+		}
+		if err := cw.Write(record); err != nil {
+			return fmt.Errorf("write trade record: %w", err)
 		}
 	}
 
@@ -139,26 +204,3 @@ func writeTradesCSV(w io.Writer, trades []trade) error {
 
 	return nil
 }
-
-// Helper to convert a single ExecutionReport into one CSV row.
-func writeExecutionRow(cw *csv.Writer, tradeID, leg string, er *types.ExecutionReport) error {
-	record := []string{
-		tradeID,
-		leg,
-		er.Ticker,
-		string(er.Side),
-		string(er.Status),
-		er.TotalFilledQty.String(),
-		er.AvgFillPrice.String(),
-		er.TotalFees.String(),
-		er.RemainingQty.String(),
-		fmt.Sprintf("%d", len(er.Fills)),
-		er.RejectReason,
-		er.ReportTime.Format(time.RFC3339),
-	}
-
-	if err := cw.Write(record); err != nil {
-		return fmt.Errorf("write record: %w", err)
-	}
-	return nil
-}
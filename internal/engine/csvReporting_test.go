@@ -0,0 +1,78 @@
+package engine
+
+import (
+	"backtester/types"
+	"bytes"
+	"encoding/csv"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestWriteTradesCSV(t *testing.T) {
+	at := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	trades := []TradeRecord{
+		{
+			Ticker:     "AAA",
+			Side:       types.SideTypeSell,
+			EntryTime:  at,
+			ExitTime:   at.Add(36 * time.Hour),
+			Quantity:   decimal.NewFromInt(2),
+			EntryPrice: decimal.NewFromInt(100),
+			ExitPrice:  decimal.NewFromInt(90),
+			Fees:       decimal.NewFromInt(1),
+			NetPnL:     decimal.NewFromInt(19),
+			Return:     decimal.RequireFromString("0.095"),
+			Bars:       36,
+			Duration:   36 * time.Hour,
+			MAE:        decimal.RequireFromString("0.02"),
+			MFE:        decimal.RequireFromString("0.12"),
+			Stop:       decimal.NewFromInt(105),
+			RMultiple:  decimal.RequireFromString("1.9"),
+			Reason:     "breakdown",
+			ExitReason: "cover",
+		},
+		{Ticker: "BBB", Side: types.SideTypeBuy, Open: true, EntryTime: at, Quantity: decimal.NewFromInt(1), EntryPrice: decimal.NewFromInt(5)},
+	}
+
+	var buf bytes.Buffer
+	if err := writeTradesCSV(&buf, trades); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("invalid csv: %v", err)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("got %d rows, want header and 2 trades", len(rows))
+	}
+
+	column := make(map[string]int)
+	for i, name := range rows[0] {
+		column[name] = i
+	}
+	closed, open := rows[1], rows[2]
+	for name, want := range map[string]string{
+		"direction":      "short",
+		"status":         "closed",
+		"net_pnl":        "19.00",
+		"return_pct":     "9.50",
+		"bars_held":      "36",
+		"duration_hours": "36.00",
+		"mae_pct":        "2.00",
+		"mfe_pct":        "12.00",
+		"r_multiple":     "1.90",
+		"entry_reason":   "breakdown",
+		"exit_reason":    "cover",
+	} {
+		if got := closed[column[name]]; got != want {
+			t.Errorf("closed %s: got=%q, want=%q", name, got, want)
+		}
+	}
+	for _, name := range []string{"exit_time", "net_pnl", "r_multiple"} {
+		if got := open[column[name]]; got != "" {
+			t.Errorf("open %s: got=%q, want empty", name, got)
+		}
+	}
+}
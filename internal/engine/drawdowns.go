@@ -0,0 +1,208 @@
+package engine
+
+import (
+	"backtester/types"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+const defaultDrawdownEpisodes = 5
+
+// DrawdownEpisodes sets how many of the deepest drawdowns the report lists, 5 by default.
+func (c *ReportingConfig) DrawdownEpisodes(n int) *ReportingConfig {
+	c.drawdownEpisodes = n
+	return c
+}
+
+func (c *ReportingConfig) drawdownEpisodeCount() int {
+	if c.drawdownEpisodes > 0 {
+		return c.drawdownEpisodes
+	}
+	return defaultDrawdownEpisodes
+}
+
+// DrawdownEpisode is one stretch below a previous equity peak, from the peak until equity is back at
+// or above it. Length runs from peak to recovery, or to the end of the run when not recovered.
+type DrawdownEpisode struct {
+	Peak          time.Time       `json:"peak"`
+	Trough        time.Time       `json:"trough"`
+	Recovery      time.Time       `json:"recovery,omitzero"`
+	Recovered     bool            `json:"recovered"`
+	PeakEquity    decimal.Decimal `json:"peak_equity"`
+	TroughEquity  decimal.Decimal `json:"trough_equity"`
+	Depth         decimal.Decimal `json:"depth"`
+	Length        time.Duration   `json:"length_ns"`
+	TimeToRecover time.Duration   `json:"time_to_recover_ns"`
+}
+
+type drawdownSummary struct {
+	episodes       []DrawdownEpisode
+	avgDepth       decimal.Decimal
+	timeUnderWater time.Duration
+	underWaterPct  decimal.Decimal
+}
+
+// calcDrawdownEpisodes returns the top deepest drawdowns, deepest first, with the average depth and
+// time under water over all of them.
+func calcDrawdownEpisodes(snapshots []types.PortfolioView, top int, wg *sync.WaitGroup) drawdownSummary {
+	defer wg.Done()
+
+	var summary drawdownSummary
+	episodes := drawdownEpisodes(snapshots)
+	if len(episodes) == 0 {
+		return summary
+	}
+
+	total := decimal.Zero
+	for _, episode := range episodes {
+		total = total.Add(episode.Depth)
+		summary.timeUnderWater += episode.Length
+	}
+	summary.avgDepth = total.Div(decimal.NewFromInt(int64(len(episodes))))
+	if period := snapshots[len(snapshots)-1].Time.Sub(snapshots[0].Time); period > 0 {
+		summary.underWaterPct = decimal.NewFromFloat(summary.timeUnderWater.Seconds() / period.Seconds())
+	}
+
+	sort.SliceStable(episodes, func(i, j int) bool { return episodes[i].Depth.GreaterThan(episodes[j].Depth) })
+	if len(episodes) > top {
+		episodes = episodes[:top]
+	}
+	summary.episodes = episodes
+	return summary
+}
+
+// drawdownEpisodes returns every drawdown of the time-weighted equity curve in chronological order.
+func drawdownEpisodes(snapshots []types.PortfolioView) []DrawdownEpisode {
+	if len(snapshots) == 0 {
+		return nil
+	}
+
+	curve := timeWeightedEquity(snapshots)
+	var episodes []DrawdownEpisode
+	var current *DrawdownEpisode
+	peak, peakTime := curve[0], snapshots[0].Time
+
+	for i, snap := range snapshots {
+		equity := curve[i]
+		if equity.GreaterThanOrEqual(peak) {
+			if current != nil {
+				current.Recovery = snap.Time
+				current.Recovered = true
+				current.Length = snap.Time.Sub(current.Peak)
+				current.TimeToRecover = snap.Time.Sub(current.Trough)
+				episodes = append(episodes, *current)
+				current = nil
+			}
+			peak, peakTime = equity, snap.Time
+			continue
+		}
+		if !peak.IsPositive() {
+			continue
+		}
+
+		if current == nil {
+			current = &DrawdownEpisode{Peak: peakTime, PeakEquity: peak, TroughEquity: equity, Trough: snap.Time}
+		}
+		if equity.LessThan(current.TroughEquity) {
+			current.TroughEquity = equity
+			current.Trough = snap.Time
+		}
+		current.Depth = peak.Sub(current.TroughEquity).Div(peak)
+	}
+
+	if current != nil {
+		current.Length = snapshots[len(snapshots)-1].Time.Sub(current.Peak)
+		episodes = append(episodes, *current)
+	}
+	return episodes
+}
+
+func (e *Engine) writeDrawdownsCSVFile(path string, episodes []DrawdownEpisode) error {
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create drawdowns file: %w", err)
+	}
+	defer f.Close()
+
+	return writeDrawdownsCSV(f, episodes)
+}
+
+// writeDrawdownsCSV writes one row per drawdown episode. The recovery columns stay empty for a
+// drawdown that has not recovered by the end of the run.
+func writeDrawdownsCSV(w io.Writer, episodes []DrawdownEpisode) error {
+	cw := csv.NewWriter(w)
+	defer cw.Flush()
+
+	header := []string{
+		"rank", "peak", "trough", "recovery", "peak_equity", "trough_equity",
+		"depth_percent", "length_days", "recovery_days",
+	}
+	if err := cw.Write(header); err != nil {
+		return fmt.Errorf("write header: %w", err)
+	}
+
+	days := func(d time.Duration) string { return fmt.Sprintf("%.2f", d.Hours()/24) }
+	for i, episode := range episodes {
+		recovery, recoveryDays := "", ""
+		if episode.Recovered {
+			recovery = episode.Recovery.Format(time.RFC3339)
+			recoveryDays = days(episode.TimeToRecover)
+		}
+		record := []string{
+			fmt.Sprintf("%d", i+1),
+			episode.Peak.Format(time.RFC3339),
+			episode.Trough.Format(time.RFC3339),
+			recovery,
+			episode.PeakEquity.StringFixed(4),
+			episode.TroughEquity.StringFixed(4),
+			episode.Depth.Mul(decimal.NewFromInt(100)).StringFixed(2),
+			days(episode.Length),
+			recoveryDays,
+		}
+		if err := cw.Write(record); err != nil {
+			return fmt.Errorf("write drawdown record: %w", err)
+		}
+	}
+
+	if err := cw.Error(); err != nil {
+		return fmt.Errorf("flush csv: %w", err)
+	}
+	return nil
+}
+
+func printDrawdownEpisodes(report *Report) {
+	fmt.Printf("Avg Drawdown %%:        %.2f%%\n", report.AvgDrawdownPercent.Mul(decimal.NewFromInt(100)).InexactFloat64())
+	fmt.Printf("Time Under Water:      %d days (%.2f%%)\n", int(report.TimeUnderWater.Hours()/24),
+		report.TimeUnderWaterPercent.Mul(decimal.NewFromInt(100)).InexactFloat64())
+	if len(report.Drawdowns) == 0 {
+		return
+	}
+
+	fmt.Printf("\n%-4s %-10s %-10s %-13s %8s %8s %9s\n", "#", "Peak", "Trough", "Recovery", "Depth", "Days", "Recover")
+	for i, episode := range report.Drawdowns {
+		recovery, recoveryDays := "not recovered", "-"
+		if episode.Recovered {
+			recovery = episode.Recovery.Format("2006-01-02")
+			recoveryDays = fmt.Sprintf("%d", int(episode.TimeToRecover.Hours()/24))
+		}
+		fmt.Printf("%-4d %-10s %-10s %-13s %7.2f%% %8d %9s\n", i+1,
+			episode.Peak.Format("2006-01-02"),
+			episode.Trough.Format("2006-01-02"),
+			recovery,
+			episode.Depth.Mul(decimal.NewFromInt(100)).InexactFloat64(),
+			int(episode.Length.Hours()/24),
+			recoveryDays)
+	}
+}
@@ -0,0 +1,97 @@
+package engine
+
+import (
+	"backtester/types"
+	"bytes"
+	"encoding/csv"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestCalcDrawdownEpisodes(t *testing.T) {
+	base := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	day := func(n int) time.Time { return base.AddDate(0, 0, n) }
+	snapshots := []types.PortfolioView{
+		newPv(day(0), "1000"),
+		newPv(day(1), "900"),
+		newPv(day(2), "950"),
+		newPv(day(4), "1000"), // recovers the first drawdown
+		newPv(day(5), "1200"),
+		newPv(day(6), "1140"),
+		newPv(day(7), "1260"),
+		newPv(day(8), "945"),
+		newPv(day(10), "1000"), // still below the 1260 peak
+	}
+
+	got := calcDrawdownEpisodes(snapshots, 2, noopWaitGroup())
+	if len(got.episodes) != 2 {
+		t.Fatalf("got %d episodes, want 2", len(got.episodes))
+	}
+
+	deepest := got.episodes[0]
+	if !deepest.Peak.Equal(day(7)) || !deepest.Trough.Equal(day(8)) || deepest.Recovered || !deepest.Recovery.IsZero() {
+		t.Errorf("deepest: got peak=%s trough=%s recovered=%v", deepest.Peak, deepest.Trough, deepest.Recovered)
+	}
+	if !deepest.Depth.Equal(decimal.RequireFromString("0.25")) || deepest.Length != 3*24*time.Hour {
+		t.Errorf("deepest: got depth=%s length=%s, want 0.25 and 72h", deepest.Depth, deepest.Length)
+	}
+
+	second := got.episodes[1]
+	if !second.Peak.Equal(day(0)) || !second.Recovery.Equal(day(4)) || !second.Recovered {
+		t.Errorf("second: got peak=%s recovery=%s recovered=%v", second.Peak, second.Recovery, second.Recovered)
+	}
+	if !second.Depth.Equal(decimal.RequireFromString("0.1")) || second.Length != 4*24*time.Hour || second.TimeToRecover != 3*24*time.Hour {
+		t.Errorf("second: got depth=%s length=%s recover=%s", second.Depth, second.Length, second.TimeToRecover)
+	}
+
+	// The 5% drawdown is cut from the table but still counts towards the summary
+	if !got.avgDepth.Equal(decimal.RequireFromString("0.1333333333333333")) {
+		t.Errorf("avg depth: got=%s", got.avgDepth)
+	}
+	if got.timeUnderWater != 9*24*time.Hour || !got.underWaterPct.Equal(decimal.RequireFromString("0.9")) {
+		t.Errorf("under water: got=%s (%s), want 216h (0.9)", got.timeUnderWater, got.underWaterPct)
+	}
+}
+
+func TestCalcDrawdownEpisodes_NoDrawdown(t *testing.T) {
+	base := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	snapshots := []types.PortfolioView{
+		newPv(base, "1000"),
+		newPv(base.AddDate(0, 0, 1), "1000"),
+		newPv(base.AddDate(0, 0, 2), "1100"),
+	}
+
+	got := calcDrawdownEpisodes(snapshots, defaultDrawdownEpisodes, noopWaitGroup())
+	if len(got.episodes) != 0 || !got.avgDepth.IsZero() || got.timeUnderWater != 0 {
+		t.Errorf("got %+v, want no drawdowns", got)
+	}
+}
+
+func TestWriteDrawdownsCSV(t *testing.T) {
+	at := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	episodes := []DrawdownEpisode{
+		{Peak: at, Trough: at.Add(24 * time.Hour), Depth: decimal.RequireFromString("0.25"), Length: 48 * time.Hour},
+		{Peak: at, Trough: at, Recovery: at.Add(36 * time.Hour), Recovered: true, Depth: decimal.RequireFromString("0.1"), Length: 36 * time.Hour, TimeToRecover: 36 * time.Hour},
+	}
+
+	var buf bytes.Buffer
+	if err := writeDrawdownsCSV(&buf, episodes); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("invalid csv: %v", err)
+	}
+
+	if len(records) != 3 {
+		t.Fatalf("got %d rows, want 3", len(records))
+	}
+	if records[1][3] != "" || records[1][6] != "25.00" || records[1][7] != "2.00" || records[1][8] != "" {
+		t.Errorf("unrecovered row: got %v", records[1])
+	}
+	if records[2][3] != "2020-01-02T12:00:00Z" || records[2][8] != "1.50" {
+		t.Errorf("recovered row: got %v", records[2])
+	}
+}
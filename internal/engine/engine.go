@@ -1,8 +1,10 @@
 package engine
 
 import (
+	"backtester/types"
 	"context"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
 	"time"
@@ -16,11 +18,23 @@ type Engine struct {
 	reportingConfig   *ReportingConfig
 	strategy          strategy
 	allocator         allocator
+	riskManager       riskManager
 	broker            broker
 	portfolio         *portfolio
 	backtester        *backtester
+	assets            map[string]*types.Asset
 	allowShortSelling bool
+	quiet             bool
+	monteCarlo        *MonteCarloConfig
+	benchmarkSeries   []priceSeries
 	logger            *slog.Logger
+	journalSink       journalSink
+	runID             string
+	runStore          runStore
+	runTags           []string
+	params            Params
+	// replay is the journal a replay engine reads instead of running the strategy
+	replay io.Reader
 }
 
 func NewEngine(
@@ -49,28 +63,75 @@ func NewEngine(
 		broker:     broker,
 		portfolio:  initPortfolio,
 		backtester: backtester,
+		assets:     make(map[string]*types.Asset),
 		logger:     slog.New(slog.NewTextHandler(os.Stdout, nil)),
 	}
 }
 
-func (e *Engine) Run() error {
+// WithConflictResolution sets how multiple signals for the same ticker on the same bar are reduced before allocation.
+// By default the signals of a ticker are dropped when they disagree on side, see ResolveIgnore.
+func (e *Engine) WithConflictResolution(mode ConflictResolution) *Engine {
+	e.backtester.signalResolver.mode = mode
+	return e
+}
+
+// WithRiskManager places a pre-trade risk layer between the allocator and the broker.
+func (e *Engine) WithRiskManager(rm riskManager) *Engine {
+	e.riskManager = rm
+	e.backtester.riskManager = rm
+	return e
+}
+
+// WithLogger replaces the default logger, which logs at info level to stdout.
+func (e *Engine) WithLogger(logger *slog.Logger) *Engine {
+	e.logger = logger
+	return e
+}
+
+// Quiet turns off logging, the progress bar and the printed report, for running many engines side by side.
+func (e *Engine) Quiet() *Engine {
+	e.quiet = true
+	e.backtester.quiet = true
+	e.logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	return e
+}
+
+// restrictTimeRange clips every feed to the given range before the data is loaded.
+func (e *Engine) restrictTimeRange(start, end time.Time) {
+	for _, feed := range e.feeds {
+		if feed.start.Before(start) {
+			feed.start = start
+		}
+		if feed.end.After(end) {
+			feed.end = end
+		}
+	}
+	e.backtester.start, e.backtester.end = getGlobalTimeRange(e.feeds)
+	e.backtester.curTime = e.backtester.start
+}
+
+// Run backtests the strategy and returns the report, also when it is printed or written to files.
+func (e *Engine) Run() (*Report, error) {
 	start := time.Now()
 	e.logger.Info("Starting backtest engine",
 		slog.Time("start_time", start),
 		slog.String("report_name", e.reportingConfig.reportName),
 	)
 
+	// Flows may be added to the portfolio config until the run starts
+	e.backtester.cashFlows = expandCashFlows(e.portfolioConfig.cashFlows, e.backtester.start, e.backtester.end)
+
 	e.logger.Info("Loading feed data")
 	if err := e.loadFeedData(); err != nil {
 		e.logger.Error("Failed to load feed data", slog.Any("error", err))
-		return err
+		return nil, err
 	}
 	e.logger.Info("Feed data loaded")
 
 	e.logger.Info("Loading context data")
 	if err := e.loadContextData(); err != nil {
 		e.logger.Error("Failed to load context data", slog.Any("error", err))
-		return err
+		return nil, err
 	}
 	e.logger.Info("context data loaded")
 
@@ -78,61 +139,171 @@ func (e *Engine) Run() error {
 	e.logger.Info("Loading execution feed data")
 	if err := e.loadExecutionFeedData(); err != nil {
 		e.logger.Error("Failed to load execution feed", slog.Any("error", err))
-		return err
+		return nil, err
 	}
 	e.logger.Info("Execution feed data loaded")
 
+	if err := e.loadBenchmarkData(); err != nil {
+		e.logger.Error("Failed to load benchmark data", slog.Any("error", err))
+		return nil, err
+	}
+
 	// Initialize strategy and allocator
 	e.logger.Info("Initializing strategy and allocator")
 	if err := e.strategy.Init(e.backtester.portfolio); err != nil {
 		e.logger.Error("Strategy initialization failed", slog.Any("error", err))
-		return err
+		return nil, err
 	}
 	if err := e.allocator.Init(e.backtester.portfolio); err != nil {
 		e.logger.Error("Allocator initialization failed", slog.Any("error", err))
-		return err
+		return nil, err
+	}
+	if e.riskManager != nil {
+		if err := e.riskManager.Init(e.backtester.portfolio, e.assets); err != nil {
+			e.logger.Error("Risk manager initialization failed", slog.Any("error", err))
+			return nil, err
+		}
 	}
 	e.logger.Info("Strategy and allocator initialized successfully")
 
+	if e.runID == "" {
+		e.runID = newRunID()
+	}
+	if e.journalSink != nil {
+		e.backtester.journal = &journal{sink: e.journalSink, runID: e.runID}
+		e.backtester.journal.record(e.backtester.start, EventRun, "", JournalRun{
+			Start:          e.backtester.start,
+			InitialCash:    e.portfolioConfig.initialCash,
+			Assets:         e.assets,
+			DailySnapshots: e.backtester.snapshotFrequency.daily(),
+		})
+	}
+
 	// Run backtest
-	e.logger.Info("Start backtesting")
-	if err := e.backtester.run(); err != nil {
+	e.logger.Info("Start backtesting", slog.String("run_id", e.runID))
+	err := e.backtester.run()
+	if closeErr := e.backtester.journal.close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
 		e.logger.Error("Backtest run failed", slog.Any("error", err))
-		return err
+		return nil, err
 	}
 	e.logger.Info("Backtest run completed",
 		slog.Time("end_sim_time", e.backtester.curTime),
 	)
 
+	report, err := e.buildReport(e.backtester.start, e.backtester.curTime)
+	if err != nil {
+		return nil, err
+	}
+
+	e.logger.Info("Backtest completed successfully",
+		slog.Duration("total_runtime", time.Since(start)),
+		slog.String("report_name", e.reportingConfig.reportName),
+	)
+
+	if !e.quiet {
+		e.printReport(report)
+	}
+
+	return report, nil
+}
+
+// buildReport generates the report of the simulated portfolio and writes the configured report files.
+func (e *Engine) buildReport(start, end time.Time) (*Report, error) {
 	// Generate report
 	e.logger.Info("Generating report")
-	report := e.generateReport(e.backtester.start, e.backtester.curTime, e.backtester.portfolio)
+	results := e.backtester.portfolio
+	report := e.generateReport(start, end, results, executionsToTrades(results), results.snapshots)
+	report.RunID = e.runID
+	e.addBenchmark(report, e.portfolio.snapshots)
+
+	if e.monteCarlo != nil {
+		e.logger.Info("Running Monte Carlo analysis", slog.String("method", string(e.monteCarlo.method)))
+		mc, err := runMonteCarlo(e.monteCarlo, report.trades, e.portfolio.snapshots, report.TotalPeriod)
+		if err != nil {
+			// Too few trades is a property of the result, not a failed run
+			e.logger.Warn("Skipping Monte Carlo analysis", slog.Any("error", err))
+		} else {
+			report.MonteCarlo = mc
+		}
+	}
 
 	// Write trade and portfolio files
 	if e.reportingConfig.printTrades {
 		filenameTrades := fmt.Sprintf("%s/%s_trades.csv", e.reportingConfig.filePath, e.reportingConfig.reportName)
 		e.logger.Info("Writing trades to CSV", slog.String("file", filenameTrades))
-		if err := e.writeTradesCSVFile(filenameTrades, report.trades); err != nil {
+		if err := e.writeTradesCSVFile(filenameTrades, report.Trades); err != nil {
 			e.logger.Error("Failed to write trades CSV", slog.Any("error", err))
-			return err
+			return nil, err
 		}
 
 		filenamePortfolio := fmt.Sprintf("%s/%s_portfolio.csv", e.reportingConfig.filePath, e.reportingConfig.reportName)
 		e.logger.Info("Writing portfolio snapshots to CSV", slog.String("file", filenamePortfolio))
-		if err := e.writePortfolioCSVFile(filenamePortfolio, e.portfolio.snapshots); err != nil {
+		if err := e.writePortfolioCSVFile(filenamePortfolio, e.portfolio.snapshots, report.benchmark); err != nil {
 			e.logger.Error("Failed to write portfolio CSV", slog.Any("error", err))
-			return err
+			return nil, err
+		}
+
+		filenameDrawdowns := fmt.Sprintf("%s/%s_drawdowns.csv", e.reportingConfig.filePath, e.reportingConfig.reportName)
+		e.logger.Info("Writing drawdown episodes to CSV", slog.String("file", filenameDrawdowns))
+		if err := e.writeDrawdownsCSVFile(filenameDrawdowns, report.Drawdowns); err != nil {
+			e.logger.Error("Failed to write drawdowns CSV", slog.Any("error", err))
+			return nil, err
+		}
+
+		filenameReturns := fmt.Sprintf("%s/%s_returns.csv", e.reportingConfig.filePath, e.reportingConfig.reportName)
+		e.logger.Info("Writing periodic returns to CSV", slog.String("file", filenameReturns))
+		if err := e.writeReturnsCSVFile(filenameReturns, report.MonthlyReturns, report.YearlyReturns); err != nil {
+			e.logger.Error("Failed to write returns CSV", slog.Any("error", err))
+			return nil, err
+		}
+
+		filenameRolling := fmt.Sprintf("%s/%s_rolling.csv", e.reportingConfig.filePath, e.reportingConfig.reportName)
+		e.logger.Info("Writing rolling metrics to CSV", slog.String("file", filenameRolling))
+		if err := e.writeRollingCSVFile(filenameRolling, report.Rolling); err != nil {
+			e.logger.Error("Failed to write rolling metrics CSV", slog.Any("error", err))
+			return nil, err
 		}
 	}
 
-	e.logger.Info("Backtest completed successfully",
-		slog.Duration("total_runtime", time.Since(start)),
-		slog.String("report_name", e.reportingConfig.reportName),
-	)
+	if e.reportingConfig.jsonReport {
+		filenameJSON := fmt.Sprintf("%s/%s.json", e.reportingConfig.filePath, e.reportingConfig.reportName)
+		e.logger.Info("Writing JSON report", slog.String("file", filenameJSON))
+		if err := e.writeReportJSONFile(filenameJSON, report); err != nil {
+			e.logger.Error("Failed to write JSON report", slog.Any("error", err))
+			return nil, err
+		}
+	}
 
-	e.printReport(report)
+	if e.reportingConfig.htmlReport {
+		filenameHTML := fmt.Sprintf("%s/%s.html", e.reportingConfig.filePath, e.reportingConfig.reportName)
+		e.logger.Info("Writing HTML report", slog.String("file", filenameHTML))
+		if err := e.writeHTMLReportFile(filenameHTML, report, e.portfolio.snapshots); err != nil {
+			e.logger.Error("Failed to write HTML report", slog.Any("error", err))
+			return nil, err
+		}
+	}
 
-	return nil
+	if e.reportingConfig.manifest {
+		filenameManifest := fmt.Sprintf("%s/%s_manifest.json", e.reportingConfig.filePath, e.reportingConfig.reportName)
+		e.logger.Info("Writing manifest", slog.String("file", filenameManifest))
+		if err := e.writeManifestFile(filenameManifest, e.newManifest(report)); err != nil {
+			e.logger.Error("Failed to write manifest", slog.Any("error", err))
+			return nil, err
+		}
+	}
+
+	if e.runStore != nil {
+		e.logger.Info("Saving run", slog.String("run_id", e.runID))
+		if err := e.saveRun(report, start, end); err != nil {
+			e.logger.Error("Failed to save run", slog.Any("error", err))
+			return nil, err
+		}
+	}
+
+	return report, nil
 }
 
 func (e *Engine) loadFeedData() error {
@@ -143,11 +314,13 @@ func (e *Engine) loadFeedData() error {
 		if err != nil {
 			return err
 		}
+		e.assets[instrument.ticker] = asset
 		cs, err := e.db.GetAggregates(asset.Id, asset.Ticker, instrument.interval, instrument.start, instrument.end, ctx)
 		if err != nil {
 			return err
 		}
 		instrument.primary.candles = cs
+		e.logger.Debug("Loaded candles", slog.String("ticker", instrument.ticker), slog.String("interval", string(instrument.interval)), slog.Int("candles", len(cs)))
 	}
 	return nil
 }
@@ -166,6 +339,7 @@ func (e *Engine) loadContextData() error {
 				return err
 			}
 			instrument.context[i].candles = cs
+			e.logger.Debug("Loaded context candles", slog.String("ticker", instrument.ticker), slog.String("interval", string(config.interval)), slog.Int("candles", len(cs)))
 		}
 	}
 	return nil
@@ -183,6 +357,7 @@ func (e *Engine) loadExecutionFeedData() error {
 			return err
 		}
 		e.backtester.executionConfig.candles[feed.ticker] = cs
+		e.logger.Debug("Loaded execution candles", slog.String("ticker", feed.ticker), slog.String("interval", string(e.executionConfig.interval)), slog.Int("candles", len(cs)))
 	}
 	return nil
 }
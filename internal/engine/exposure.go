@@ -0,0 +1,149 @@
+package engine
+
+import (
+	"backtester/types"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// secondsPerYear is 365.25 days, the year length CAGR uses
+const secondsPerYear = 31557600
+
+// positionExposure is the market exposure of one portfolio snapshot. Short positions carry a negative
+// quantity, so shortValue is the absolute value of their market value. The percentages are of equity.
+type positionExposure struct {
+	longValue       decimal.Decimal
+	shortValue      decimal.Decimal
+	gross           decimal.Decimal
+	net             decimal.Decimal
+	grossPct        decimal.Decimal
+	netPct          decimal.Decimal
+	openPositions   int
+	cashUtilisation decimal.Decimal
+}
+
+func exposureOf(view types.PortfolioView) positionExposure {
+	var exposure positionExposure
+	for _, pos := range view.Positions {
+		if pos.Quantity.IsZero() {
+			continue
+		}
+		exposure.openPositions++
+		value := pos.Quantity.Mul(pos.LastMarketPrice)
+		if value.IsNegative() {
+			exposure.shortValue = exposure.shortValue.Add(value.Abs())
+		} else {
+			exposure.longValue = exposure.longValue.Add(value)
+		}
+	}
+	exposure.gross = exposure.longValue.Add(exposure.shortValue)
+	exposure.net = exposure.longValue.Sub(exposure.shortValue)
+
+	equity := portfolioValue(view)
+	if equity.IsPositive() {
+		exposure.grossPct = exposure.gross.Div(equity)
+		exposure.netPct = exposure.net.Div(equity)
+		// Short sale proceeds sit in cash, so utilisation never drops below zero
+		exposure.cashUtilisation = decimal.Max(decimal.NewFromInt(1).Sub(view.Cash.Div(equity)), decimal.Zero)
+	}
+	return exposure
+}
+
+type exposureSummary struct {
+	timeInMarket   decimal.Decimal
+	avgGross       decimal.Decimal
+	maxGross       decimal.Decimal
+	avgNet         decimal.Decimal
+	annualTurnover decimal.Decimal
+	avgHolding     time.Duration
+}
+
+// calcExposureStats summarises exposure over the snapshots. Every snapshot holds until the next one,
+// so the averages weigh it by that time and snapshots clustered around fills do not bias them. Time in
+// market is the fraction of time with an open position. Annual turnover is half the traded notional
+// per year, divided by the average equity. The average holding period covers closed round trips only.
+func calcExposureStats(snapshots []types.PortfolioView, executions []types.ExecutionReport, records []TradeRecord, wg *sync.WaitGroup) exposureSummary {
+	defer wg.Done()
+
+	var summary exposureSummary
+	if len(snapshots) == 0 {
+		return summary
+	}
+
+	weights := snapshotWeights(snapshots)
+	inMarket, grossSum, netSum, equitySum, total := decimal.Zero, decimal.Zero, decimal.Zero, decimal.Zero, decimal.Zero
+	for i, snap := range snapshots {
+		exposure := exposureOf(snap)
+		weight := weights[i]
+		if exposure.openPositions > 0 {
+			inMarket = inMarket.Add(weight)
+		}
+		grossSum = grossSum.Add(exposure.grossPct.Mul(weight))
+		netSum = netSum.Add(exposure.netPct.Mul(weight))
+		summary.maxGross = decimal.Max(summary.maxGross, exposure.grossPct)
+		equitySum = equitySum.Add(portfolioValue(snap).Mul(weight))
+		total = total.Add(weight)
+	}
+	summary.timeInMarket = inMarket.Div(total)
+	summary.avgGross = grossSum.Div(total)
+	summary.avgNet = netSum.Div(total)
+
+	traded := decimal.Zero
+	for _, exec := range executions {
+		for _, fill := range exec.Fills {
+			traded = traded.Add(fill.Quantity.Abs().Mul(fill.Price))
+		}
+	}
+	avgEquity := equitySum.Div(total)
+	duration := snapshots[len(snapshots)-1].Time.Sub(snapshots[0].Time)
+	if avgEquity.IsPositive() && duration > 0 {
+		years := decimal.NewFromFloat(duration.Seconds()).Div(decimal.NewFromInt(secondsPerYear))
+		summary.annualTurnover = traded.Div(decimal.NewFromInt(2)).Div(avgEquity).Div(years)
+	}
+
+	var holding time.Duration
+	closed := 0
+	for _, record := range records {
+		if record.Open {
+			continue
+		}
+		holding += record.ExitTime.Sub(record.EntryTime)
+		closed++
+	}
+	if closed > 0 {
+		summary.avgHolding = holding / time.Duration(closed)
+	}
+	return summary
+}
+
+// snapshotWeights returns the time every snapshot holds until the next one, in seconds. The last
+// snapshot ends the curve and gets no weight, unless no time passes at all and every snapshot counts once.
+func snapshotWeights(snapshots []types.PortfolioView) []decimal.Decimal {
+	weights := make([]decimal.Decimal, len(snapshots))
+	total := decimal.Zero
+	for i := range snapshots[:len(snapshots)-1] {
+		weights[i] = decimal.NewFromFloat(snapshots[i+1].Time.Sub(snapshots[i].Time).Seconds())
+		total = total.Add(weights[i])
+	}
+	weights[len(weights)-1] = decimal.Zero
+	if total.IsPositive() {
+		return weights
+	}
+	for i := range weights {
+		weights[i] = decimal.NewFromInt(1)
+	}
+	return weights
+}
+
+func printExposure(report *Report) {
+	fmt.Println("\n-- Exposure --")
+	fmt.Printf("Time in Market:        %.2f%%\n", report.TimeInMarket.Mul(decimal.NewFromInt(100)).InexactFloat64())
+	fmt.Printf("Avg Gross Exposure:    %.2f%%\n", report.AvgGrossExposure.Mul(decimal.NewFromInt(100)).InexactFloat64())
+	fmt.Printf("Max Gross Exposure:    %.2f%%\n", report.MaxGrossExposure.Mul(decimal.NewFromInt(100)).InexactFloat64())
+	fmt.Printf("Avg Net Exposure:      %.2f%%\n", report.AvgNetExposure.Mul(decimal.NewFromInt(100)).InexactFloat64())
+	fmt.Printf("Annual Turnover:       %.2fx\n", report.AnnualTurnover.InexactFloat64())
+	fmt.Printf("Avg Holding Period:    %.1f days\n", report.AvgHoldingPeriod.Hours()/24)
+}
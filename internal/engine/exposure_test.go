@@ -0,0 +1,88 @@
+package engine
+
+import (
+	"backtester/types"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestExposureOf(t *testing.T) {
+	view := types.PortfolioView{
+		Cash: decimal.NewFromInt(600),
+		Positions: map[string]types.PositionSnapshot{
+			"AAA": {Ticker: "AAA", Quantity: decimal.NewFromInt(5), LastMarketPrice: decimal.NewFromInt(100)},
+			"BBB": {Ticker: "BBB", Quantity: decimal.NewFromInt(-2), LastMarketPrice: decimal.NewFromInt(50)},
+			"CCC": {Ticker: "CCC", Quantity: decimal.Zero, LastMarketPrice: decimal.NewFromInt(10)},
+		},
+	}
+
+	got := exposureOf(view)
+	if got.openPositions != 2 {
+		t.Errorf("open positions: got=%d, want 2", got.openPositions)
+	}
+	// Equity is 600 + 500 - 100 = 1000
+	for name, tt := range map[string]struct {
+		got  decimal.Decimal
+		want string
+	}{
+		"long":             {got.longValue, "500"},
+		"short":            {got.shortValue, "100"},
+		"gross":            {got.grossPct, "0.6"},
+		"net":              {got.netPct, "0.4"},
+		"cash utilisation": {got.cashUtilisation, "0.4"},
+	} {
+		if !tt.got.Equal(decimal.RequireFromString(tt.want)) {
+			t.Errorf("%s: got=%s, want=%s", name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestCalcExposureStats(t *testing.T) {
+	base := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	invested := func(at time.Time, cash, qty string) types.PortfolioView {
+		view := newPv(at, cash)
+		view.Positions["AAA"] = types.PositionSnapshot{Ticker: "AAA", Quantity: decimal.RequireFromString(qty), LastMarketPrice: decimal.NewFromInt(10)}
+		return view
+	}
+	end := base.Add(secondsPerYear * time.Second)
+	quarter := end.Sub(base) / 4
+	// Flat for a quarter, half invested for a quarter, fully invested for the second half
+	snapshots := []types.PortfolioView{
+		newPv(base, "1000"),
+		invested(base.Add(quarter), "500", "50"),
+		invested(base.Add(2*quarter), "0", "100"),
+		invested(base.Add(2*quarter+time.Minute), "0", "100"),
+		newPv(end, "1000"),
+	}
+	executions := []types.ExecutionReport{
+		newExecutionReport("AAA", types.SideTypeBuy, newFill(base, "10", "100", "0")),
+		newExecutionReport("AAA", types.SideTypeSell, newFill(end, "10", "100", "0")),
+	}
+	records := []TradeRecord{
+		{EntryTime: base, ExitTime: base.Add(48 * time.Hour)},
+		{EntryTime: base, ExitTime: base.Add(24 * time.Hour)},
+		{EntryTime: base, Open: true},
+	}
+
+	got := calcExposureStats(snapshots, executions, records, noopWaitGroup())
+	for name, tt := range map[string]struct {
+		got  decimal.Decimal
+		want string
+	}{
+		"time in market": {got.timeInMarket, "0.75"},
+		"avg gross":      {got.avgGross, "0.625"},
+		"max gross":      {got.maxGross, "1"},
+		"avg net":        {got.avgNet, "0.625"},
+		// 2000 traded over one year on 1000 average equity
+		"annual turnover": {got.annualTurnover, "1"},
+	} {
+		if !tt.got.Round(6).Equal(decimal.RequireFromString(tt.want)) {
+			t.Errorf("%s: got=%s, want=%s", name, tt.got, tt.want)
+		}
+	}
+	if got.avgHolding != 36*time.Hour {
+		t.Errorf("avg holding: got=%s, want 36h", got.avgHolding)
+	}
+}
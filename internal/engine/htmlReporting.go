@@ -0,0 +1,454 @@
+package engine
+
+import (
+	"backtester/types"
+	"fmt"
+	"html/template"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	chartWidth   = 900
+	chartHeight  = 260
+	chartPadding = 48
+	pnlBins      = 20
+
+	colorStrategy  = "#1f77b4"
+	colorBenchmark = "#7f7f7f"
+	colorGain      = "#2ca02c"
+	colorLoss      = "#d62728"
+)
+
+// HTMLReport also writes a single self-contained HTML tear sheet next to the CSV files. It has no
+// external scripts or styles, so it can be mailed around and opened offline.
+func (c *ReportingConfig) HTMLReport() *ReportingConfig {
+	c.htmlReport = true
+	return c
+}
+
+type tearSheet struct {
+	Title    string
+	Period   string
+	Metrics  []tearSheetMetric
+	Equity   template.HTML
+	Drawdown template.HTML
+	Monthly  template.HTML
+	PnL      template.HTML
+	Trades   []tearSheetTrade
+}
+
+type tearSheetMetric struct {
+	Name  string
+	Value string
+}
+
+type tearSheetTrade struct {
+	Ticker     string
+	Direction  string
+	Entry      string
+	Exit       string
+	Quantity   string
+	EntryPrice string
+	ExitPrice  string
+	PnL        string
+	Win        bool
+}
+
+func (e *Engine) writeHTMLReportFile(path string, report *Report, snapshots []types.PortfolioView) error {
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create html report file: %w", err)
+	}
+	defer f.Close()
+
+	return writeHTMLReport(f, e.reportingConfig.reportName, report, snapshots)
+}
+
+// writeHTMLReport renders the tear sheet of a finished run to any io.Writer.
+func writeHTMLReport(w io.Writer, title string, report *Report, snapshots []types.PortfolioView) error {
+	equity := timeWeightedEquity(snapshots)
+	times := make([]time.Time, len(snapshots))
+	for i, snap := range snapshots {
+		times[i] = snap.Time
+	}
+
+	series := []chartSeries{{name: "Strategy", color: colorStrategy, values: toFloats(equity)}}
+	if len(report.benchmark) == len(snapshots) {
+		series = append(series, chartSeries{name: report.BenchmarkName, color: colorBenchmark, values: toFloats(report.benchmark)})
+	}
+
+	sheet := tearSheet{
+		Title:    title,
+		Metrics:  tearSheetMetrics(report),
+		Equity:   lineChartSVG(times, series),
+		Drawdown: underwaterSVG(times, toFloats(equity)),
+		Monthly:  monthlyHeatmapSVG(report.MonthlyReturns),
+		PnL:      pnlHistogramSVG(report.trades),
+		Trades:   tearSheetTrades(report.Trades),
+	}
+	if len(times) > 0 {
+		sheet.Period = fmt.Sprintf("%s to %s", times[0].Format(time.DateOnly), times[len(times)-1].Format(time.DateOnly))
+	}
+
+	if err := tearSheetTemplate.Execute(w, sheet); err != nil {
+		return fmt.Errorf("render html report: %w", err)
+	}
+	return nil
+}
+
+func tearSheetMetrics(report *Report) []tearSheetMetric {
+	pct := func(d decimal.Decimal) string { return d.Mul(decimal.NewFromInt(100)).StringFixed(2) + "%" }
+	ratio := func(d decimal.Decimal) string { return d.StringFixed(2) }
+
+	metrics := []tearSheetMetric{
+		{"Net Profit", report.NetProfit.StringFixed(2)},
+		{"CAGR", pct(report.CAGR)},
+		{"Max Drawdown", pct(report.MaxDrawdownPercent)},
+		{"Max Drawdown Duration", fmt.Sprintf("%d days", int(report.MaxDrawdownDays.Hours()/24))},
+		{"Sharpe Ratio", ratio(report.SharpeRatio)},
+		{"Sortino Ratio", ratio(report.SortinoRatio)},
+		{"Calmar Ratio (36m)", ratio(report.CalmarRatio)},
+		{"Ulcer Index", pct(report.UlcerIndex)},
+		{"Profit Factor", ratio(report.ProfitFactor)},
+		{"Win Rate", pct(report.WinLossRatio)},
+		{"Total Trades", fmt.Sprintf("%d", report.TotalTrades)},
+		{"Expectancy (R)", ratio(report.Expectancy)},
+		{"Total Fees", report.TotalFees.StringFixed(2)},
+	}
+	if report.BenchmarkName != "" {
+		metrics = append(metrics,
+			tearSheetMetric{"Benchmark CAGR", pct(report.BenchmarkCAGR)},
+			tearSheetMetric{"Alpha", pct(report.Alpha)},
+			tearSheetMetric{"Beta", ratio(report.Beta)},
+		)
+	}
+	return metrics
+}
+
+func tearSheetTrades(records []TradeRecord) []tearSheetTrade {
+	var rows []tearSheetTrade
+	for _, record := range records {
+		if record.Open {
+			continue
+		}
+		direction := "Long"
+		if record.Side == types.SideTypeSell {
+			direction = "Short"
+		}
+		rows = append(rows, tearSheetTrade{
+			Ticker:     record.Ticker,
+			Direction:  direction,
+			Entry:      record.EntryTime.Format(time.DateTime),
+			Exit:       record.ExitTime.Format(time.DateTime),
+			Quantity:   record.Quantity.String(),
+			EntryPrice: record.EntryPrice.StringFixed(2),
+			ExitPrice:  record.ExitPrice.StringFixed(2),
+			PnL:        record.NetPnL.StringFixed(2),
+			Win:        record.NetPnL.IsPositive(),
+		})
+	}
+	return rows
+}
+
+func toFloats(values []decimal.Decimal) []float64 {
+	out := make([]float64, len(values))
+	for i, v := range values {
+		out[i] = v.InexactFloat64()
+	}
+	return out
+}
+
+type chartSeries struct {
+	name   string
+	color  string
+	values []float64
+}
+
+// chartFrame maps data onto the drawing area of a chart.
+type chartFrame struct {
+	points   int
+	min, max float64
+}
+
+func (f chartFrame) x(i int) float64 {
+	if f.points < 2 {
+		return chartPadding
+	}
+	return chartPadding + float64(i)*(chartWidth-2*chartPadding)/float64(f.points-1)
+}
+
+func (f chartFrame) y(v float64) float64 {
+	if f.max == f.min {
+		return chartHeight / 2
+	}
+	return chartHeight - chartPadding/2 - (v-f.min)*(chartHeight-chartPadding)/(f.max-f.min)
+}
+
+func emptyChart(message string) template.HTML {
+	return template.HTML(fmt.Sprintf(`<p class="empty">%s</p>`, template.HTMLEscapeString(message)))
+}
+
+// axes draws horizontal grid lines with labels and the first and last date under the chart.
+func (f chartFrame) axes(sb *strings.Builder, times []time.Time, label func(float64) string) {
+	for i := 0; i <= 4; i++ {
+		v := f.min + (f.max-f.min)*float64(i)/4
+		y := f.y(v)
+		fmt.Fprintf(sb, `<line x1="%d" y1="%.1f" x2="%d" y2="%.1f" class="grid"/>`, chartPadding, y, chartWidth-chartPadding, y)
+		fmt.Fprintf(sb, `<text x="%d" y="%.1f" class="axis" text-anchor="end">%s</text>`, chartPadding-4, y+4, template.HTMLEscapeString(label(v)))
+	}
+	if len(times) > 0 {
+		fmt.Fprintf(sb, `<text x="%d" y="%d" class="axis">%s</text>`, chartPadding, chartHeight-2, times[0].Format(time.DateOnly))
+		fmt.Fprintf(sb, `<text x="%d" y="%d" class="axis" text-anchor="end">%s</text>`, chartWidth-chartPadding, chartHeight-2, times[len(times)-1].Format(time.DateOnly))
+	}
+}
+
+func polyline(f chartFrame, values []float64) string {
+	points := make([]string, len(values))
+	for i, v := range values {
+		points[i] = fmt.Sprintf("%.1f,%.1f", f.x(i), f.y(v))
+	}
+	return strings.Join(points, " ")
+}
+
+func lineChartSVG(times []time.Time, series []chartSeries) template.HTML {
+	if len(times) < 2 {
+		return emptyChart("Not enough snapshots to draw the equity curve.")
+	}
+
+	f := chartFrame{points: len(times), min: math.Inf(1), max: math.Inf(-1)}
+	for _, s := range series {
+		for _, v := range s.values {
+			f.min, f.max = math.Min(f.min, v), math.Max(f.max, v)
+		}
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `<svg viewBox="0 0 %d %d" xmlns="http://www.w3.org/2000/svg">`, chartWidth, chartHeight)
+	f.axes(&sb, times, func(v float64) string { return fmt.Sprintf("%.0f", v) })
+	for i, s := range series {
+		fmt.Fprintf(&sb, `<polyline points="%s" fill="none" stroke="%s" stroke-width="1.5"/>`, polyline(f, s.values), s.color)
+		fmt.Fprintf(&sb, `<text x="%d" y="%d" fill="%s" class="legend">%s</text>`, chartPadding+8, 14+14*i, s.color, template.HTMLEscapeString(s.name))
+	}
+	sb.WriteString(`</svg>`)
+	return template.HTML(sb.String())
+}
+
+// underwaterSVG shades the percentage distance of the equity curve below its running peak.
+func underwaterSVG(times []time.Time, equity []float64) template.HTML {
+	if len(equity) < 2 {
+		return emptyChart("Not enough snapshots to draw the drawdown chart.")
+	}
+
+	drawdowns := make([]float64, len(equity))
+	peak := 0.0
+	for i, v := range equity {
+		peak = math.Max(peak, v)
+		if peak > 0 {
+			drawdowns[i] = (v - peak) / peak
+		}
+	}
+	f := chartFrame{points: len(equity), min: 0, max: 0}
+	for _, dd := range drawdowns {
+		f.min = math.Min(f.min, dd)
+	}
+	if f.min == 0 {
+		f.min = -0.01
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `<svg viewBox="0 0 %d %d" xmlns="http://www.w3.org/2000/svg">`, chartWidth, chartHeight)
+	f.axes(&sb, times, func(v float64) string { return fmt.Sprintf("%.1f%%", v*100) })
+	fmt.Fprintf(&sb, `<polygon points="%.1f,%.1f %s %.1f,%.1f" fill="%s" fill-opacity="0.4" stroke="%s"/>`,
+		f.x(0), f.y(0), polyline(f, drawdowns), f.x(len(drawdowns)-1), f.y(0), colorLoss, colorLoss)
+	sb.WriteString(`</svg>`)
+	return template.HTML(sb.String())
+}
+
+// monthlyHeatmapSVG draws one row per year and one column per month, coloured by the sign and size of the return.
+func monthlyHeatmapSVG(returns []PeriodReturn) template.HTML {
+	if len(returns) == 0 {
+		return emptyChart("No monthly returns.")
+	}
+
+	const cellWidth, cellHeight, labelWidth = 64, 24, 48
+	years := map[int]bool{}
+	maxAbs := 0.0
+	for _, r := range returns {
+		years[r.Year] = true
+		maxAbs = math.Max(maxAbs, math.Abs(r.Return.InexactFloat64()))
+	}
+	var ordered []int
+	for y := range years {
+		ordered = append(ordered, y)
+	}
+	sort.Ints(ordered)
+	row := make(map[int]int, len(ordered))
+	for i, y := range ordered {
+		row[y] = i
+	}
+
+	width := labelWidth + 12*cellWidth
+	height := cellHeight * (len(ordered) + 1)
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `<svg viewBox="0 0 %d %d" xmlns="http://www.w3.org/2000/svg">`, width, height)
+	for m := time.January; m <= time.December; m++ {
+		fmt.Fprintf(&sb, `<text x="%d" y="%d" class="axis" text-anchor="middle">%s</text>`,
+			labelWidth+int(m-1)*cellWidth+cellWidth/2, cellHeight-8, m.String()[:3])
+	}
+	for i, y := range ordered {
+		fmt.Fprintf(&sb, `<text x="%d" y="%d" class="axis" text-anchor="end">%d</text>`, labelWidth-6, cellHeight*(i+2)-8, y)
+	}
+	for _, r := range returns {
+		v := r.Return.InexactFloat64()
+		color := colorGain
+		if v < 0 {
+			color = colorLoss
+		}
+		opacity := 0.1
+		if maxAbs > 0 {
+			opacity += 0.8 * math.Abs(v) / maxAbs
+		}
+		x := labelWidth + int(r.Month-1)*cellWidth
+		y := cellHeight * (row[r.Year] + 1)
+		fmt.Fprintf(&sb, `<rect x="%d" y="%d" width="%d" height="%d" fill="%s" fill-opacity="%.2f" stroke="#fff"/>`,
+			x, y, cellWidth, cellHeight, color, opacity)
+		fmt.Fprintf(&sb, `<text x="%d" y="%d" class="cell" text-anchor="middle">%.1f%%</text>`, x+cellWidth/2, y+cellHeight-8, v*100)
+	}
+	sb.WriteString(`</svg>`)
+	return template.HTML(sb.String())
+}
+
+// pnlBuckets splits the net PnL of the closed trades into equal width bins.
+func pnlBuckets(trades []trade, bins int) (counts []int, low, width float64) {
+	var pnls []float64
+	for _, tr := range trades {
+		if pnl, ok := tradeNetPnL(tr); ok {
+			pnls = append(pnls, pnl.InexactFloat64())
+		}
+	}
+	if len(pnls) == 0 {
+		return nil, 0, 0
+	}
+
+	low, high := pnls[0], pnls[0]
+	for _, p := range pnls {
+		low, high = math.Min(low, p), math.Max(high, p)
+	}
+	if low == high {
+		return []int{len(pnls)}, low, 0
+	}
+
+	width = (high - low) / float64(bins)
+	counts = make([]int, bins)
+	for _, p := range pnls {
+		counts[min(int((p-low)/width), bins-1)]++
+	}
+	return counts, low, width
+}
+
+func pnlHistogramSVG(trades []trade) template.HTML {
+	counts, low, width := pnlBuckets(trades, pnlBins)
+	if len(counts) == 0 {
+		return emptyChart("No closed trades.")
+	}
+
+	highest := 0
+	for _, c := range counts {
+		highest = max(highest, c)
+	}
+	f := chartFrame{min: 0, max: float64(highest)}
+	barWidth := float64(chartWidth-2*chartPadding) / float64(len(counts))
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `<svg viewBox="0 0 %d %d" xmlns="http://www.w3.org/2000/svg">`, chartWidth, chartHeight)
+	f.axes(&sb, nil, func(v float64) string { return fmt.Sprintf("%.0f", v) })
+	for i, c := range counts {
+		color := colorGain
+		if low+(float64(i)+0.5)*width < 0 {
+			color = colorLoss
+		}
+		x := chartPadding + float64(i)*barWidth
+		fmt.Fprintf(&sb, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" fill="%s"/>`,
+			x+1, f.y(float64(c)), barWidth-2, f.y(0)-f.y(float64(c)), color)
+	}
+	fmt.Fprintf(&sb, `<text x="%d" y="%d" class="axis">%.2f</text>`, chartPadding, chartHeight-2, low)
+	fmt.Fprintf(&sb, `<text x="%d" y="%d" class="axis" text-anchor="end">%.2f</text>`,
+		chartWidth-chartPadding, chartHeight-2, low+width*float64(len(counts)))
+	sb.WriteString(`</svg>`)
+	return template.HTML(sb.String())
+}
+
+var tearSheetTemplate = template.Must(template.New("tearsheet").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em auto; max-width: 960px; color: #222; }
+h1 { margin-bottom: 0; }
+h2 { margin-top: 2em; border-bottom: 1px solid #ddd; }
+.period { color: #666; }
+.metrics { display: grid; grid-template-columns: repeat(4, 1fr); gap: 0.5em; }
+.metric { background: #f6f6f6; padding: 0.5em; border-radius: 4px; }
+.metric .name { font-size: 0.8em; color: #666; }
+.metric .value { font-size: 1.2em; }
+svg { width: 100%; height: auto; }
+svg .grid { stroke: #eee; }
+svg .axis, svg .cell { font-size: 11px; fill: #555; }
+svg .legend { font-size: 12px; }
+table { border-collapse: collapse; width: 100%; font-size: 0.85em; }
+th, td { padding: 0.25em 0.5em; text-align: right; border-bottom: 1px solid #eee; }
+th:first-child, td:first-child { text-align: left; }
+.win { color: #2ca02c; }
+.loss { color: #d62728; }
+.empty { color: #999; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p class="period">{{.Period}}</p>
+
+<div class="metrics">
+{{- range .Metrics}}
+<div class="metric"><div class="name">{{.Name}}</div><div class="value">{{.Value}}</div></div>
+{{- end}}
+</div>
+
+<h2>Equity</h2>
+{{.Equity}}
+
+<h2>Drawdown</h2>
+{{.Drawdown}}
+
+<h2>Monthly Returns</h2>
+{{.Monthly}}
+
+<h2>Trade P&amp;L Distribution</h2>
+{{.PnL}}
+
+<h2>Trades</h2>
+{{- if .Trades}}
+<table>
+<tr><th>Ticker</th><th>Direction</th><th>Entry</th><th>Exit</th><th>Quantity</th><th>Entry Price</th><th>Exit Price</th><th>Net P&amp;L</th></tr>
+{{- range .Trades}}
+<tr><td>{{.Ticker}}</td><td>{{.Direction}}</td><td>{{.Entry}}</td><td>{{.Exit}}</td><td>{{.Quantity}}</td><td>{{.EntryPrice}}</td><td>{{.ExitPrice}}</td><td class="{{if .Win}}win{{else}}loss{{end}}">{{.PnL}}</td></tr>
+{{- end}}
+</table>
+{{- else}}
+<p class="empty">No closed trades.</p>
+{{- end}}
+</body>
+</html>
+`))
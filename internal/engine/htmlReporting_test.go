@@ -0,0 +1,73 @@
+package engine
+
+import (
+	"backtester/types"
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestPnlBuckets(t *testing.T) {
+	at := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	closedTrade := func(entry, exit string) trade {
+		buy := newExecutionReport("AAA", types.SideTypeBuy, newFill(at, entry, "1", "0"))
+		sell := newExecutionReport("AAA", types.SideTypeSell, newFill(at.Add(time.Hour), exit, "1", "0"))
+		return trade{buy: &buy, sell: &sell, qty: decimal.NewFromInt(1)}
+	}
+
+	counts, low, width := pnlBuckets([]trade{
+		closedTrade("100", "90"),
+		closedTrade("100", "95"),
+		closedTrade("100", "110"),
+	}, 4)
+	if low != -10 || width != 5 {
+		t.Errorf("got low=%f width=%f, want -10 and 5", low, width)
+	}
+	want := []int{1, 1, 0, 1}
+	for i := range want {
+		if counts[i] != want[i] {
+			t.Errorf("bucket %d: got=%d, want=%d", i, counts[i], want[i])
+		}
+	}
+}
+
+func TestWriteHTMLReport(t *testing.T) {
+	base := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	var snapshots []types.PortfolioView
+	for i, cash := range []string{"1000", "1050", "980", "1100"} {
+		snapshots = append(snapshots, newPv(base.AddDate(0, i, 0), cash))
+	}
+	buy := newExecutionReport("AAA", types.SideTypeBuy, newFill(base, "10", "5", "0"))
+	sell := newExecutionReport("AAA", types.SideTypeSell, newFill(base.AddDate(0, 1, 0), "12", "5", "0"))
+	trades := []trade{{buy: &buy, sell: &sell, qty: decimal.NewFromInt(5)}}
+	report := &Report{
+		BenchmarkName: "SPY",
+		benchmark:     []decimal.Decimal{decimal.NewFromInt(1000), decimal.NewFromInt(1010), decimal.NewFromInt(1020), decimal.NewFromInt(1030)},
+		trades:        trades,
+		Trades:        tradeRecords(trades, nil),
+	}
+	report.MonthlyReturns = monthlyReturns(snapshots)
+
+	var buf bytes.Buffer
+	if err := writeHTMLReport(&buf, "<donchian>", report, snapshots); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	html := buf.String()
+
+	if got := strings.Count(html, "<svg"); got != 4 {
+		t.Errorf("got %d charts, want 4", got)
+	}
+	for _, want := range []string{"&lt;donchian&gt;", "SPY", "<td>AAA</td>", "10.00", "Long"} {
+		if !strings.Contains(html, want) {
+			t.Errorf("report is missing %q", want)
+		}
+	}
+	for _, external := range []string{"<script", "<link", "src="} {
+		if strings.Contains(html, external) {
+			t.Errorf("report should be self-contained, found %q", external)
+		}
+	}
+}
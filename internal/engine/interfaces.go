@@ -23,6 +23,17 @@ type allocator interface {
 	Allocate(signals map[string][]types.Signal, view types.PortfolioView) []types.Order
 }
 
+type riskManager interface {
+	Init(api PortfolioApi, assets map[string]*types.Asset) error
+	Check(orders []types.Order, view types.PortfolioView) ([]types.Order, []types.ExecutionReport)
+}
+
+// riskAuditor is a risk manager that also reports the orders it resized and when it halted trading
+// during the last Check, for the journal.
+type riskAuditor interface {
+	lastDecisions() ([]RiskResize, *RiskHalt)
+}
+
 type broker interface {
 	Execute(orders []types.Order, ctx types.ExecutionContext) []types.ExecutionReport
 }
@@ -30,9 +41,14 @@ type broker interface {
 type PortfolioApi interface {
 	GetPortfolioSnapshot() types.PortfolioView
 	GetExecutionReportsForTicker(tradeId string) []types.ExecutionReport
+	GetLastPrice(ticker string) decimal.Decimal
+	GetCandles(ticker string, n int) []types.Candle
+	GetTickers() []string
 }
 
 type backtesterApi interface {
 	getCurrentTime() time.Time
 	getLastPriceForTicker(ticker string) decimal.Decimal
+	getCandlesForTicker(ticker string, n int) []types.Candle
+	getTickers() []string
 }
@@ -0,0 +1,154 @@
+package engine
+
+import (
+	"backtester/types"
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// EventType is the kind of engine decision a journal event records.
+type EventType string
+
+const (
+	EventRun           EventType = "RUN"
+	EventRunEnd        EventType = "RUN_END"
+	EventCashFlow      EventType = "CASH_FLOW"
+	EventCandle        EventType = "CANDLE"
+	EventSignal        EventType = "SIGNAL"
+	EventSignalDropped EventType = "SIGNAL_DROPPED"
+	EventOrder         EventType = "ORDER"
+	EventRiskRejection EventType = "RISK_REJECTION"
+	EventRiskResize    EventType = "RISK_RESIZE"
+	EventRiskHalt      EventType = "RISK_HALT"
+	EventExecution     EventType = "EXECUTION"
+	EventSnapshot      EventType = "SNAPSHOT"
+)
+
+// JournalEvent is one line of the journal. Time is the simulated time of the decision and Seq orders
+// the events of a run. Data holds the candle, signal, order, risk decision, execution report or snapshot.
+type JournalEvent struct {
+	RunID  string    `json:"run_id"`
+	Seq    int64     `json:"seq"`
+	Time   time.Time `json:"time"`
+	Type   EventType `json:"type"`
+	Ticker string    `json:"ticker,omitempty"`
+	Data   any       `json:"data"`
+}
+
+// JournalRun is the data of the RUN event that opens the journal of a run.
+type JournalRun struct {
+	Start       time.Time               `json:"start"`
+	InitialCash decimal.Decimal         `json:"initial_cash"`
+	Assets      map[string]*types.Asset `json:"assets"`
+	// DailySnapshots is set when there is one snapshot per day, see SnapshotFrequency
+	DailySnapshots bool `json:"daily_snapshots"`
+}
+
+// journalSink receives every event of a run in order. The engine closes it when the simulation ends.
+type journalSink interface {
+	Write(event JournalEvent) error
+	Close() error
+}
+
+// journal stamps events with the run ID and a sequence number. A nil journal records nothing. The
+// first write error sticks, so the run loop checks it once per minute instead of after every event.
+type journal struct {
+	sink  journalSink
+	runID string
+	seq   int64
+	err   error
+}
+
+func (j *journal) record(at time.Time, eventType EventType, ticker string, data any) {
+	if j == nil || j.err != nil {
+		return
+	}
+	j.seq++
+	j.err = j.sink.Write(JournalEvent{RunID: j.runID, Seq: j.seq, Time: at, Type: eventType, Ticker: ticker, Data: data})
+}
+
+func (j *journal) close() error {
+	if j == nil {
+		return nil
+	}
+	if err := j.sink.Close(); err != nil && j.err == nil {
+		j.err = err
+	}
+	return j.err
+}
+
+// WithJournal records every candle, signal, order, risk rejection, resize and halt, execution report,
+// cash flow and snapshot of the run to sink. The journal opens with a RUN event and ends with RUN_END
+// at the time the simulation stopped.
+func (e *Engine) WithJournal(sink journalSink) *Engine {
+	e.journalSink = sink
+	return e
+}
+
+// WithRunID sets the ID that tags the journal and the report of the run. By default every run gets a
+// random ID.
+func (e *Engine) WithRunID(id string) *Engine {
+	e.runID = id
+	return e
+}
+
+func newRunID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return time.Now().UTC().Format("20060102T150405.000000000")
+	}
+	return hex.EncodeToString(b)
+}
+
+// JSONLJournal writes one JSON event per line.
+type JSONLJournal struct {
+	w      *bufio.Writer
+	enc    *json.Encoder
+	closer io.Closer
+}
+
+// NewJSONLJournal writes the journal to w. Closing the journal flushes it but leaves w open.
+func NewJSONLJournal(w io.Writer) *JSONLJournal {
+	buf := bufio.NewWriter(w)
+	return &JSONLJournal{w: buf, enc: json.NewEncoder(buf)}
+}
+
+// NewJSONLJournalFile appends the journal to the file at path, creating it when needed.
+func NewJSONLJournalFile(path string) (*JSONLJournal, error) {
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("open journal file: %w", err)
+	}
+	journal := NewJSONLJournal(f)
+	journal.closer = f
+	return journal, nil
+}
+
+func (j *JSONLJournal) Write(event JournalEvent) error {
+	if err := j.enc.Encode(event); err != nil {
+		return fmt.Errorf("write journal event: %w", err)
+	}
+	return nil
+}
+
+func (j *JSONLJournal) Close() error {
+	if err := j.w.Flush(); err != nil {
+		return fmt.Errorf("flush journal: %w", err)
+	}
+	if j.closer != nil {
+		return j.closer.Close()
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package engine
+
+import (
+	"backtester/types"
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestBacktest_Journal(t *testing.T) {
+	testStrat := allocatorStrategy{callAllocator: 1}
+	testAllocator := &orderAllocator{
+		orders: []types.Order{types.NewOrder("AAPL", decimal.NewFromInt(100), decimal.NewFromInt(1), types.TypeLimit, types.SideTypeBuy, "", time.UnixMilli(0))},
+	}
+
+	var buf bytes.Buffer
+	engine := mockEngine(&testStrat, mockInstrument(), testAllocator, &mockBroker{}).
+		WithRiskManager(NewRiskManager().MaxNotionalPerOrder(decimal.NewFromInt(10))).
+		WithJournal(NewJSONLJournal(&buf)).
+		WithRunID("run-1").
+		Quiet()
+
+	report, err := engine.Run()
+	if err != nil {
+		t.Fatalf("Error running engine: %v", err)
+	}
+	if report.RunID != "run-1" {
+		t.Errorf("report run id = %q, want run-1", report.RunID)
+	}
+
+	counts := make(map[EventType]int)
+	var seq int64
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		var event struct {
+			RunID string          `json:"run_id"`
+			Seq   int64           `json:"seq"`
+			Type  EventType       `json:"type"`
+			Data  json.RawMessage `json:"data"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatalf("line %d is not valid JSON: %v", seq+1, err)
+		}
+		if event.RunID != "run-1" || event.Seq != seq+1 || len(event.Data) == 0 {
+			t.Fatalf("got run=%q seq=%d data=%s after seq %d", event.RunID, event.Seq, event.Data, seq)
+		}
+		seq = event.Seq
+		counts[event.Type]++
+	}
+
+	want := map[EventType]int{
+		EventCandle:        len(engine.feeds[0].primary.candles),
+		EventSignal:        1,
+		EventSnapshot:      len(engine.portfolio.snapshots),
+		EventRiskRejection: len(engine.portfolio.rejections),
+	}
+	for eventType, n := range want {
+		if counts[eventType] != n {
+			t.Errorf("%s events = %d, want %d", eventType, counts[eventType], n)
+		}
+	}
+	if counts[EventOrder] == 0 || counts[EventOrder] != counts[EventRiskRejection] {
+		t.Errorf("got %d orders and %d rejections, want every order rejected", counts[EventOrder], counts[EventRiskRejection])
+	}
+}
+
+func TestBacktest_JournalRiskResize(t *testing.T) {
+	testStrat := allocatorStrategy{callAllocator: 1}
+	testAllocator := &orderAllocator{
+		orders: []types.Order{types.NewOrder("AAPL", decimal.NewFromInt(100), decimal.NewFromInt(5), types.TypeLimit, types.SideTypeBuy, "", time.UnixMilli(0))},
+	}
+
+	var buf bytes.Buffer
+	engine := mockEngine(&testStrat, mockInstrument(), testAllocator, &mockBroker{}).
+		WithRiskManager(NewRiskManager().MaxNotionalPerOrder(decimal.NewFromInt(250))).
+		WithJournal(NewJSONLJournal(&buf)).
+		Quiet()
+	if _, err := engine.Run(); err != nil {
+		t.Fatalf("Error running engine: %v", err)
+	}
+
+	orders, resizes := 0, 0
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		var event struct {
+			Type EventType  `json:"type"`
+			Data RiskResize `json:"data"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			continue
+		}
+		switch event.Type {
+		case EventOrder:
+			orders++
+		case EventRiskResize:
+			resizes++
+			if !event.Data.OriginalQuantity.Equal(decimal.NewFromInt(5)) || !event.Data.Order.Quantity.Equal(decimal.NewFromInt(2)) ||
+				event.Data.Code != RejectMaxNotionalPerOrder {
+				t.Errorf("resize = %+v, want 5 cut to 2 by %s", event.Data, RejectMaxNotionalPerOrder)
+			}
+		}
+	}
+	if orders == 0 || resizes != orders {
+		t.Errorf("got %d orders and %d resizes, want every order resized", orders, resizes)
+	}
+}
+
+type failingSink struct {
+	closed bool
+}
+
+var sinkErr = errors.New("disk full")
+
+func (s *failingSink) Write(event JournalEvent) error {
+	return sinkErr
+}
+
+func (s *failingSink) Close() error {
+	s.closed = true
+	return nil
+}
+
+func TestBacktest_JournalError(t *testing.T) {
+	sink := &failingSink{}
+	engine := mockEngine(&allocatorStrategy{}, mockInstrument(), &mockAllocator{}, &mockBroker{}).
+		WithJournal(sink).
+		Quiet()
+
+	if _, err := engine.Run(); !errors.Is(err, sinkErr) {
+		t.Errorf("got err=%v, want %v", err, sinkErr)
+	}
+	if !sink.closed {
+		t.Errorf("sink was not closed")
+	}
+}
@@ -0,0 +1,195 @@
+package engine
+
+import (
+	"backtester/types"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// reportSchemaVersion is raised on every change to the JSON report that can break a consumer,
+// like a renamed or removed field. New fields do not change it.
+const reportSchemaVersion = 1
+
+// JSONReport also writes the full report as a single JSON document next to the CSV files.
+func (c *ReportingConfig) JSONReport() *ReportingConfig {
+	c.jsonReport = true
+	return c
+}
+
+type jsonReportDocument struct {
+	SchemaVersion int                `json:"schema_version"`
+	Name          string             `json:"name"`
+	Config        jsonRunConfig      `json:"config"`
+	Metrics       jsonMetrics        `json:"metrics"`
+	Tickers       []PerformanceStats `json:"tickers"`
+	AssetTypes    []PerformanceStats `json:"asset_types"`
+	Sides         []PerformanceStats `json:"sides"`
+	Reasons       []PerformanceStats `json:"reasons"`
+	Rolling       []RollingMetrics   `json:"rolling"`
+	Trades        []TradeRecord      `json:"trades"`
+	Snapshots     []jsonSnapshot     `json:"snapshots"`
+}
+
+// jsonMetrics flattens the report metrics and writes durations as days.
+type jsonMetrics struct {
+	*Report
+	TotalPeriodDays      float64 `json:"total_period_days"`
+	MaxDrawdownDays      float64 `json:"max_drawdown_days"`
+	TimeUnderWaterDays   float64 `json:"time_under_water_days"`
+	AvgHoldingPeriodDays float64 `json:"avg_holding_period_days"`
+}
+
+type jsonRunConfig struct {
+	Start                time.Time            `json:"start"`
+	End                  time.Time            `json:"end"`
+	Instruments          []jsonInstrument     `json:"instruments"`
+	ExecutionInterval    types.Interval       `json:"execution_interval"`
+	InitialCash          decimal.Decimal      `json:"initial_cash"`
+	AllowShortSelling    bool                 `json:"allow_short_selling"`
+	RiskFreeRate         decimal.Decimal      `json:"risk_free_rate"`
+	ConflictResolution   ConflictResolution   `json:"conflict_resolution"`
+	RiskManager          bool                 `json:"risk_manager"`
+	Benchmark            string               `json:"benchmark,omitempty"`
+	AnnualizationPeriods int                  `json:"annualization_periods"`
+	MonteCarlo           *jsonMonteCarloInput `json:"monte_carlo,omitempty"`
+}
+
+type jsonInstrument struct {
+	Ticker   string           `json:"ticker"`
+	Interval types.Interval   `json:"interval"`
+	Context  []types.Interval `json:"context,omitempty"`
+}
+
+type jsonMonteCarloInput struct {
+	Method       MonteCarloMethod `json:"method"`
+	Simulations  int              `json:"simulations"`
+	Seed         uint64           `json:"seed"`
+	RuinFraction decimal.Decimal  `json:"ruin_fraction"`
+}
+
+type jsonSnapshot struct {
+	Time             time.Time       `json:"time"`
+	Cash             decimal.Decimal `json:"cash"`
+	PositionsValue   decimal.Decimal `json:"positions_value"`
+	TotalValue       decimal.Decimal `json:"total_value"`
+	Positions        int             `json:"positions"`
+	NetContributions decimal.Decimal `json:"net_contributions"`
+	LongValue        decimal.Decimal `json:"long_value"`
+	ShortValue       decimal.Decimal `json:"short_value"`
+	GrossExposure    decimal.Decimal `json:"gross_exposure"`
+	NetExposure      decimal.Decimal `json:"net_exposure"`
+	CashUtilisation  decimal.Decimal `json:"cash_utilisation"`
+	Benchmark        decimal.Decimal `json:"benchmark,omitzero"`
+}
+
+func (e *Engine) writeReportJSONFile(path string, report *Report) error {
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create json report file: %w", err)
+	}
+	defer f.Close()
+
+	return writeReportJSON(f, e.reportDocument(report))
+}
+
+func writeReportJSON(w io.Writer, doc jsonReportDocument) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return fmt.Errorf("encode json report: %w", err)
+	}
+	return nil
+}
+
+// reportDocument collects the report of a finished run together with the configuration it ran with.
+func (e *Engine) reportDocument(report *Report) jsonReportDocument {
+	return jsonReportDocument{
+		SchemaVersion: reportSchemaVersion,
+		Name:          e.reportingConfig.reportName,
+		Config:        e.runConfig(report),
+		Metrics:       newJSONMetrics(report),
+		Tickers:       report.ByTicker,
+		AssetTypes:    report.ByAssetType,
+		Sides:         report.BySide,
+		Reasons:       report.ByReason,
+		Rolling:       report.Rolling,
+		Trades:        report.Trades,
+		Snapshots:     jsonSnapshots(e.portfolio.snapshots, report.benchmark),
+	}
+}
+
+func (e *Engine) runConfig(report *Report) jsonRunConfig {
+	config := jsonRunConfig{
+		Start:                e.backtester.start,
+		End:                  e.backtester.curTime,
+		ExecutionInterval:    e.executionConfig.interval,
+		InitialCash:          e.portfolioConfig.initialCash,
+		AllowShortSelling:    e.portfolioConfig.allowShortSelling,
+		RiskFreeRate:         e.reportingConfig.sharpeRiskFreeRate,
+		ConflictResolution:   e.backtester.signalResolver.mode,
+		RiskManager:          e.riskManager != nil,
+		Benchmark:            e.benchmarkName(),
+		AnnualizationPeriods: report.AnnualizationPeriods,
+	}
+	for _, feed := range e.feeds {
+		instrument := jsonInstrument{Ticker: feed.ticker, Interval: feed.interval}
+		for _, ctx := range feed.context {
+			instrument.Context = append(instrument.Context, ctx.interval)
+		}
+		config.Instruments = append(config.Instruments, instrument)
+	}
+	if e.monteCarlo != nil {
+		config.MonteCarlo = &jsonMonteCarloInput{
+			Method:       e.monteCarlo.method,
+			Simulations:  e.monteCarlo.simulations,
+			Seed:         e.monteCarlo.seed,
+			RuinFraction: e.monteCarlo.ruinFraction,
+		}
+	}
+	return config
+}
+
+func newJSONMetrics(report *Report) jsonMetrics {
+	return jsonMetrics{
+		Report:               report,
+		TotalPeriodDays:      report.TotalPeriod.Hours() / 24,
+		MaxDrawdownDays:      report.MaxDrawdownDays.Hours() / 24,
+		TimeUnderWaterDays:   report.TimeUnderWater.Hours() / 24,
+		AvgHoldingPeriodDays: report.AvgHoldingPeriod.Hours() / 24,
+	}
+}
+
+func jsonSnapshots(views []types.PortfolioView, benchmark []decimal.Decimal) []jsonSnapshot {
+	out := make([]jsonSnapshot, len(views))
+	for i, pv := range views {
+		total := portfolioValue(pv)
+		exposure := exposureOf(pv)
+		out[i] = jsonSnapshot{
+			Time:             pv.Time,
+			Cash:             pv.Cash,
+			PositionsValue:   total.Sub(pv.Cash),
+			TotalValue:       total,
+			Positions:        exposure.openPositions,
+			NetContributions: pv.NetContributions,
+			LongValue:        exposure.longValue,
+			ShortValue:       exposure.shortValue,
+			GrossExposure:    exposure.grossPct,
+			NetExposure:      exposure.netPct,
+			CashUtilisation:  exposure.cashUtilisation,
+		}
+		if i < len(benchmark) {
+			out[i].Benchmark = benchmark[i]
+		}
+	}
+	return out
+}
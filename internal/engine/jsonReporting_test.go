@@ -0,0 +1,62 @@
+package engine
+
+import (
+	"backtester/types"
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestBacktest_JSONReport(t *testing.T) {
+	start := time.UnixMilli(0).UTC()
+	feeds := Instruments(Instrument("AAPL", start, start.Add(3*24*time.Hour), types.Hour))
+	engine := mockEngine(&allocatorStrategy{}, feeds, &mockAllocator{}, &mockBroker{})
+	engine.reportingConfig.BenchmarkBuyAndHold()
+
+	report, err := engine.Run()
+	if err != nil {
+		t.Fatalf("Error running engine: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := writeReportJSON(&buf, engine.reportDocument(report)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var doc struct {
+		SchemaVersion int `json:"schema_version"`
+		Config        struct {
+			Instruments []struct {
+				Ticker string `json:"ticker"`
+			} `json:"instruments"`
+			Benchmark string `json:"benchmark"`
+		} `json:"config"`
+		Metrics   map[string]any   `json:"metrics"`
+		Snapshots []map[string]any `json:"snapshots"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("report is not valid JSON: %v", err)
+	}
+
+	if doc.SchemaVersion != reportSchemaVersion {
+		t.Errorf("schema version = %d, want %d", doc.SchemaVersion, reportSchemaVersion)
+	}
+	if len(doc.Config.Instruments) != 1 || doc.Config.Instruments[0].Ticker != "AAPL" {
+		t.Errorf("instruments = %+v, want AAPL", doc.Config.Instruments)
+	}
+	if doc.Config.Benchmark != buyAndHoldBenchmark {
+		t.Errorf("benchmark = %q, want %q", doc.Config.Benchmark, buyAndHoldBenchmark)
+	}
+	for _, key := range []string{"net_profit", "cagr", "sortino_ratio", "total_period_days", "benchmark_cagr"} {
+		if _, ok := doc.Metrics[key]; !ok {
+			t.Errorf("metrics are missing %q", key)
+		}
+	}
+	if _, ok := doc.Metrics["TotalPeriod"]; ok {
+		t.Errorf("durations should only be written as days")
+	}
+	if len(doc.Snapshots) != len(engine.portfolio.snapshots) {
+		t.Errorf("got %d snapshots, want %d", len(doc.Snapshots), len(engine.portfolio.snapshots))
+	}
+}
@@ -0,0 +1,520 @@
+package engine
+
+import (
+	"backtester/types"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+var (
+	ManifestMismatchErr     = errors.New("run does not match manifest")
+	UnknownParameterTypeErr = errors.New("unknown parameter type")
+)
+
+// manifestSchemaVersion is raised on every change to the manifest that older manifests cannot be
+// verified against.
+const manifestSchemaVersion = 1
+
+// Manifest records everything that produced a run: the configuration, the strategy parameters, a
+// hash of every candle series the run loaded and the build of the backtester. Outputs holds hashes
+// of the metrics, trades and equity curve, so VerifyManifest can tell whether a new run is identical.
+type Manifest struct {
+	SchemaVersion int                          `json:"schema_version"`
+	RunID         string                       `json:"run_id"`
+	Name          string                       `json:"name"`
+	CreatedAt     time.Time                    `json:"created_at"`
+	Strategy      string                       `json:"strategy"`
+	Parameters    map[string]ManifestParameter `json:"parameters"`
+	Config        jsonRunConfig                `json:"config"`
+	Engine        manifestEngine               `json:"engine"`
+	Reporting     manifestReporting            `json:"reporting"`
+	Candles       []ManifestCandles            `json:"candles"`
+	Build         ManifestBuild                `json:"build"`
+	Outputs       ManifestOutputs              `json:"outputs"`
+}
+
+// ManifestParameter is a strategy parameter with the type it has in Params.
+type ManifestParameter struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// ManifestCandles identifies one candle series by its content. Feed is primary, context, execution
+// or benchmark.
+type ManifestCandles struct {
+	Ticker   string         `json:"ticker"`
+	Feed     string         `json:"feed"`
+	Interval types.Interval `json:"interval"`
+	Count    int            `json:"count"`
+	First    time.Time      `json:"first,omitzero"`
+	Last     time.Time      `json:"last,omitzero"`
+	SHA256   string         `json:"sha256"`
+}
+
+// ManifestBuild is the Go toolchain, module and VCS revision of the binary that ran.
+type ManifestBuild struct {
+	GoVersion    string `json:"go_version"`
+	Module       string `json:"module"`
+	Version      string `json:"version"`
+	Revision     string `json:"revision,omitempty"`
+	RevisionTime string `json:"revision_time,omitempty"`
+	Modified     bool   `json:"modified"`
+}
+
+// ManifestOutputs are hashes of the results of a run.
+type ManifestOutputs struct {
+	TotalTrades int             `json:"total_trades"`
+	FinalEquity decimal.Decimal `json:"final_equity"`
+	Metrics     string          `json:"metrics_sha256"`
+	Trades      string          `json:"trades_sha256"`
+	Equity      string          `json:"equity_sha256"`
+}
+
+// manifestEngine holds the settings that change results and are not part of the JSON report config.
+type manifestEngine struct {
+	BarsBefore int                   `json:"bars_before"`
+	BarsAfter  int                   `json:"bars_after"`
+	Snapshots  jsonSnapshotFrequency `json:"snapshots"`
+	CashFlows  []jsonCashFlow        `json:"cash_flows,omitempty"`
+	Risk       *jsonRiskLimits       `json:"risk,omitempty"`
+}
+
+type jsonSnapshotFrequency struct {
+	Mode     snapshotMode  `json:"mode"`
+	Every    time.Duration `json:"every_ns,omitempty"`
+	Location string        `json:"location,omitempty"`
+	Close    time.Duration `json:"close_ns,omitempty"`
+}
+
+type jsonCashFlow struct {
+	Type        CashFlowType    `json:"type"`
+	Amount      decimal.Decimal `json:"amount"`
+	Start       time.Time       `json:"start"`
+	EveryMonths int             `json:"every_months"`
+	Until       time.Time       `json:"until,omitzero"`
+}
+
+type jsonRiskLimits struct {
+	MaxPositionWeight   decimal.Decimal                     `json:"max_position_weight"`
+	MaxGrossExposure    decimal.Decimal                     `json:"max_gross_exposure"`
+	MaxNetExposure      decimal.Decimal                     `json:"max_net_exposure"`
+	MaxNotionalPerOrder decimal.Decimal                     `json:"max_notional_per_order"`
+	MaxOrdersPerDay     int                                 `json:"max_orders_per_day"`
+	MaxDrawdown         decimal.Decimal                     `json:"max_drawdown"`
+	AssetTypeCaps       map[types.AssetType]decimal.Decimal `json:"asset_type_caps,omitempty"`
+}
+
+// manifestReporting is informational, reporting settings that change metrics are in the config.
+type manifestReporting struct {
+	Path             string `json:"path"`
+	CSV              bool   `json:"csv"`
+	HTML             bool   `json:"html"`
+	JSON             bool   `json:"json"`
+	DrawdownEpisodes int    `json:"drawdown_episodes"`
+	BuyAndHold       bool   `json:"benchmark_buy_and_hold"`
+}
+
+// Manifest also writes a reproducibility manifest of the run next to the CSV files, which
+// VerifyManifest re-runs.
+func (c *ReportingConfig) Manifest() *ReportingConfig {
+	c.manifest = true
+	return c
+}
+
+func (e *Engine) newManifest(report *Report) *Manifest {
+	m := &Manifest{
+		SchemaVersion: manifestSchemaVersion,
+		RunID:         e.runID,
+		Name:          e.reportingConfig.reportName,
+		CreatedAt:     time.Now().UTC(),
+		Parameters:    make(map[string]ManifestParameter, len(e.params)),
+		Config:        e.runConfig(report),
+		Engine: manifestEngine{
+			BarsBefore: e.executionConfig.barsBefore,
+			BarsAfter:  e.executionConfig.barsAfter,
+		},
+		Reporting: manifestReporting{
+			Path:             e.reportingConfig.filePath,
+			CSV:              e.reportingConfig.printTrades,
+			HTML:             e.reportingConfig.htmlReport,
+			JSON:             e.reportingConfig.jsonReport,
+			DrawdownEpisodes: e.reportingConfig.drawdownEpisodeCount(),
+			BuyAndHold:       e.reportingConfig.benchmarkBuyAndHold,
+		},
+		Candles: e.candleHashes(),
+		Build:   buildInfo(),
+	}
+	if e.strategy != nil {
+		m.Strategy = fmt.Sprintf("%T", e.strategy)
+	}
+	for name, value := range e.params {
+		m.Parameters[name] = manifestParameter(value)
+	}
+
+	frequency := e.backtester.snapshotFrequency
+	m.Engine.Snapshots = jsonSnapshotFrequency{Mode: frequency.mode, Every: frequency.every, Close: frequency.close}
+	if m.Engine.Snapshots.Mode == "" {
+		m.Engine.Snapshots.Mode = snapshotDaily
+	}
+	if frequency.location != nil {
+		m.Engine.Snapshots.Location = frequency.location.String()
+	}
+	for _, flow := range e.portfolioConfig.cashFlows {
+		m.Engine.CashFlows = append(m.Engine.CashFlows, jsonCashFlow{
+			Type:        flow.flowType,
+			Amount:      flow.amount,
+			Start:       flow.start,
+			EveryMonths: flow.everyMonths,
+			Until:       flow.until,
+		})
+	}
+	if risk, ok := e.riskManager.(*RiskManager); ok {
+		m.Engine.Risk = &jsonRiskLimits{
+			MaxPositionWeight:   risk.maxPositionWeight,
+			MaxGrossExposure:    risk.maxGrossExposure,
+			MaxNetExposure:      risk.maxNetExposure,
+			MaxNotionalPerOrder: risk.maxNotionalPerOrder,
+			MaxOrdersPerDay:     risk.maxOrdersPerDay,
+			MaxDrawdown:         risk.maxDrawdown,
+			AssetTypeCaps:       risk.assetTypeCaps,
+		}
+	}
+
+	m.Outputs = ManifestOutputs{
+		TotalTrades: report.TotalTrades,
+		Metrics:     hashJSON(reportMetricValues(report)),
+		Trades:      hashJSON(report.Trades),
+		Equity:      hashJSON(jsonSnapshots(e.portfolio.snapshots, nil)),
+	}
+	if n := len(e.portfolio.snapshots); n > 0 {
+		m.Outputs.FinalEquity = portfolioValue(e.portfolio.snapshots[n-1])
+	}
+	return m
+}
+
+func manifestParameter(value any) ManifestParameter {
+	switch v := value.(type) {
+	case int:
+		return ManifestParameter{Type: "int", Value: strconv.Itoa(v)}
+	case decimal.Decimal:
+		return ManifestParameter{Type: "decimal", Value: v.String()}
+	case types.Interval:
+		return ManifestParameter{Type: "interval", Value: string(v)}
+	default:
+		return ManifestParameter{Type: "string", Value: fmt.Sprint(v)}
+	}
+}
+
+// Params returns the strategy parameters of the manifest with the types they had in the run.
+func (m *Manifest) Params() (Params, error) {
+	params := make(Params, len(m.Parameters))
+	for name, p := range m.Parameters {
+		switch p.Type {
+		case "int":
+			v, err := strconv.Atoi(p.Value)
+			if err != nil {
+				return nil, fmt.Errorf("parameter %s: %w", name, err)
+			}
+			params[name] = v
+		case "decimal":
+			v, err := decimal.NewFromString(p.Value)
+			if err != nil {
+				return nil, fmt.Errorf("parameter %s: %w", name, err)
+			}
+			params[name] = v
+		case "interval":
+			params[name] = types.Interval(p.Value)
+		case "string":
+			params[name] = p.Value
+		default:
+			return nil, fmt.Errorf("%w: %s is %s", UnknownParameterTypeErr, name, p.Type)
+		}
+	}
+	return params, nil
+}
+
+// candleHashes hashes every candle series of the run in feed order, then the execution and
+// benchmark series.
+func (e *Engine) candleHashes() []ManifestCandles {
+	var out []ManifestCandles
+	for _, feed := range e.feeds {
+		out = append(out, candleHash(feed.ticker, "primary", feed.primary.interval, feed.primary.candles))
+		for _, ctx := range feed.context {
+			out = append(out, candleHash(feed.ticker, "context", ctx.interval, ctx.candles))
+		}
+	}
+	tickers := make([]string, 0, len(e.executionConfig.candles))
+	for ticker := range e.executionConfig.candles {
+		tickers = append(tickers, ticker)
+	}
+	sort.Strings(tickers)
+	for _, ticker := range tickers {
+		out = append(out, candleHash(ticker, "execution", e.executionConfig.interval, e.executionConfig.candles[ticker]))
+	}
+	for _, series := range e.benchmarkSeries {
+		out = append(out, candleHash(e.benchmarkName(), "benchmark", series.interval, series.candles))
+	}
+	return out
+}
+
+func candleHash(ticker, feed string, interval types.Interval, candles []types.Candle) ManifestCandles {
+	h := sha256.New()
+	for _, c := range candles {
+		fmt.Fprintf(h, "%d|%s|%s|%s|%s|%s\n", c.Timestamp.UnixNano(), c.Open, c.High, c.Low, c.Close, c.Volume)
+	}
+	out := ManifestCandles{Ticker: ticker, Feed: feed, Interval: interval, Count: len(candles), SHA256: hexSum(h)}
+	if len(candles) > 0 {
+		out.First = candles[0].Timestamp
+		out.Last = candles[len(candles)-1].Timestamp
+	}
+	return out
+}
+
+func hashJSON(v any) string {
+	h := sha256.New()
+	if err := json.NewEncoder(h).Encode(v); err != nil {
+		return ""
+	}
+	return hexSum(h)
+}
+
+func hexSum(h hash.Hash) string {
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func buildInfo() ManifestBuild {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ManifestBuild{}
+	}
+	build := ManifestBuild{GoVersion: info.GoVersion, Module: info.Main.Path, Version: info.Main.Version}
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			build.Revision = setting.Value
+		case "vcs.time":
+			build.RevisionTime = setting.Value
+		case "vcs.modified":
+			build.Modified = setting.Value == "true"
+		}
+	}
+	return build
+}
+
+func (e *Engine) writeManifestFile(path string, m *Manifest) error {
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create manifest file: %w", err)
+	}
+	defer f.Close()
+
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(m); err != nil {
+		return fmt.Errorf("encode manifest: %w", err)
+	}
+	return nil
+}
+
+// ReadManifestFile reads a manifest written by ReportingConfig.Manifest.
+func ReadManifestFile(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read manifest: %w", err)
+	}
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("decode manifest: %w", err)
+	}
+	return &m, nil
+}
+
+// VerifyManifest re-runs the backtest of a manifest and reports every difference as a
+// ManifestMismatchErr. factory builds the engine from the parameters of the manifest, like for the
+// Optimizer, then the snapshots, cash flows, risk limits and conflict resolution of the manifest
+// replace those of the engine. The database replaces the one of the engine unless it is nil. The re-run writes no
+// files and saves no run. A different build only warns, since the point is often to check that a
+// code change did not change results.
+func VerifyManifest(manifest *Manifest, factory EngineFactory, db dataStore) (*Report, error) {
+	if manifest.SchemaVersion != manifestSchemaVersion {
+		return nil, fmt.Errorf("%w: schema version %d, want %d", ManifestMismatchErr, manifest.SchemaVersion, manifestSchemaVersion)
+	}
+	params, err := manifest.Params()
+	if err != nil {
+		return nil, err
+	}
+	eng, err := factory(params)
+	if err != nil {
+		return nil, err
+	}
+	if eng == nil {
+		return nil, MissingEngineErr
+	}
+	if db != nil {
+		eng.db = db
+	}
+	if err := eng.applyManifest(manifest); err != nil {
+		return nil, err
+	}
+	eng.params = params
+	eng.runID = manifest.RunID
+	eng.runStore = nil
+	eng.reportingConfig = eng.reportingConfig.withoutFiles()
+	eng.reportingConfig.drawdownEpisodes = manifest.Reporting.DrawdownEpisodes
+
+	report, err := eng.Run()
+	if err != nil {
+		return nil, err
+	}
+	got := eng.newManifest(report)
+	if got.Build.Revision != manifest.Build.Revision || got.Build.Modified != manifest.Build.Modified {
+		eng.logger.Warn("Verifying with a different build",
+			slog.String("manifest_revision", manifest.Build.Revision),
+			slog.String("revision", got.Build.Revision),
+			slog.Bool("modified", got.Build.Modified))
+	}
+	if diffs := manifest.diff(got); len(diffs) > 0 {
+		return report, fmt.Errorf("%w: %s", ManifestMismatchErr, strings.Join(diffs, "; "))
+	}
+	return report, nil
+}
+
+// applyManifest replaces the settings of the engine that the manifest recorded outside the strategy
+// parameters, so the run is reproduced with the settings it had rather than those of the factory.
+func (e *Engine) applyManifest(m *Manifest) error {
+	frequency, err := m.Engine.Snapshots.frequency()
+	if err != nil {
+		return err
+	}
+	e.WithSnapshotFrequency(frequency)
+	if m.Config.ConflictResolution != "" {
+		e.WithConflictResolution(m.Config.ConflictResolution)
+	}
+
+	execution := *e.executionConfig
+	execution.barsBefore, execution.barsAfter = m.Engine.BarsBefore, m.Engine.BarsAfter
+	e.executionConfig, e.backtester.executionConfig = &execution, &execution
+
+	portfolio := *e.portfolioConfig
+	portfolio.cashFlows = nil
+	for _, flow := range m.Engine.CashFlows {
+		portfolio.cashFlows = append(portfolio.cashFlows, &CashFlowConfig{
+			flowType:    flow.Type,
+			amount:      flow.Amount,
+			start:       flow.Start,
+			everyMonths: flow.EveryMonths,
+			until:       flow.Until,
+		})
+	}
+	e.portfolioConfig, e.backtester.portfolioConfig = &portfolio, &portfolio
+
+	// Only the default risk manager is recorded, other risk layers are left to the factory
+	if m.Engine.Risk != nil {
+		e.WithRiskManager(m.Engine.Risk.riskManager())
+	} else if _, ok := e.riskManager.(*RiskManager); ok {
+		e.WithRiskManager(nil)
+	}
+	return nil
+}
+
+func (f jsonSnapshotFrequency) frequency() (SnapshotFrequency, error) {
+	frequency := SnapshotFrequency{mode: f.Mode, every: f.Every, close: f.Close}
+	if f.Location != "" {
+		location, err := time.LoadLocation(f.Location)
+		if err != nil {
+			return frequency, fmt.Errorf("snapshot location: %w", err)
+		}
+		frequency.location = location
+	}
+	if frequency.mode == snapshotSessionClose && frequency.location == nil {
+		frequency.location = time.UTC
+	}
+	return frequency, nil
+}
+
+func (r *jsonRiskLimits) riskManager() *RiskManager {
+	rm := NewRiskManager().
+		MaxPositionWeight(r.MaxPositionWeight).
+		MaxGrossExposure(r.MaxGrossExposure).
+		MaxNetExposure(r.MaxNetExposure).
+		MaxNotionalPerOrder(r.MaxNotionalPerOrder).
+		MaxOrdersPerDay(r.MaxOrdersPerDay).
+		MaxDrawdown(r.MaxDrawdown)
+	for assetType, weight := range r.AssetTypeCaps {
+		rm.MaxAssetTypeWeight(assetType, weight)
+	}
+	return rm
+}
+
+// diff lists what differs between the manifest and the manifest of a re-run, inputs first.
+func (m *Manifest) diff(other *Manifest) []string {
+	var diffs []string
+	check := func(name string, a, b any) {
+		if !jsonEqual(a, b) {
+			diffs = append(diffs, name+" changed")
+		}
+	}
+	check("strategy", m.Strategy, other.Strategy)
+	check("parameters", m.Parameters, other.Parameters)
+	check("config", m.Config, other.Config)
+	check("engine settings", m.Engine, other.Engine)
+
+	type seriesKey struct {
+		ticker, feed string
+		interval     types.Interval
+	}
+	series := make(map[seriesKey]ManifestCandles, len(other.Candles))
+	for _, c := range other.Candles {
+		series[seriesKey{c.Ticker, c.Feed, c.Interval}] = c
+	}
+	for _, want := range m.Candles {
+		key := seriesKey{want.Ticker, want.Feed, want.Interval}
+		got, ok := series[key]
+		delete(series, key)
+		switch {
+		case !ok:
+			diffs = append(diffs, fmt.Sprintf("%s %s %s candles missing", want.Ticker, want.Feed, want.Interval))
+		case got.SHA256 != want.SHA256:
+			diffs = append(diffs, fmt.Sprintf("%s %s %s candles changed (%d, want %d)", want.Ticker, want.Feed, want.Interval, got.Count, want.Count))
+		}
+	}
+	for key := range series {
+		diffs = append(diffs, fmt.Sprintf("%s %s %s candles unexpected", key.ticker, key.feed, key.interval))
+	}
+
+	if m.Outputs.Metrics != other.Outputs.Metrics {
+		diffs = append(diffs, "metrics changed")
+	}
+	if m.Outputs.Trades != other.Outputs.Trades {
+		diffs = append(diffs, fmt.Sprintf("trades changed (%d, want %d)", other.Outputs.TotalTrades, m.Outputs.TotalTrades))
+	}
+	if m.Outputs.Equity != other.Outputs.Equity {
+		diffs = append(diffs, fmt.Sprintf("equity changed (final %s, want %s)", other.Outputs.FinalEquity, m.Outputs.FinalEquity))
+	}
+	return diffs
+}
+
+func jsonEqual(a, b any) bool {
+	x, errA := json.Marshal(a)
+	y, errB := json.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(x, y)
+}
@@ -0,0 +1,89 @@
+package engine
+
+import (
+	"backtester/types"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestManifest_Params(t *testing.T) {
+	params := Params{
+		"lookback": 4,
+		"percent":  decimal.RequireFromString("0.25"),
+		"interval": types.Hour,
+		"mode":     "fast",
+	}
+	m := &Manifest{Parameters: make(map[string]ManifestParameter)}
+	for name, value := range params {
+		m.Parameters[name] = manifestParameter(value)
+	}
+
+	got, err := m.Params()
+	if err != nil {
+		t.Fatalf("Params() error = %v", err)
+	}
+	if got.String() != params.String() {
+		t.Errorf("Params() = %s, want %s", got, params)
+	}
+	if _, err := got.Decimal("percent"); err != nil {
+		t.Errorf("Params() percent: %v", err)
+	}
+
+	m.Parameters["bad"] = ManifestParameter{Type: "complex", Value: "1i"}
+	if _, err := m.Params(); !errors.Is(err, UnknownParameterTypeErr) {
+		t.Errorf("Params() error = %v, want %v", err, UnknownParameterTypeErr)
+	}
+}
+
+func TestVerifyManifest(t *testing.T) {
+	factory := func(candles int) EngineFactory {
+		return func(params Params) (*Engine, error) {
+			instruments := mockInstrument()
+			instruments[0].end = instruments[0].start.Add(types.IntervalToTime[testInterval] * time.Duration(candles))
+			return mockEngine(&alternatingStrategy{}, instruments, &signalAllocator{}, &fillingBroker{}).Quiet(), nil
+		}
+	}
+	params := Params{"lookback": 4}
+
+	dir := t.TempDir()
+	engine, _ := factory(5)(params)
+	engine.reportingConfig.reportName = "verify"
+	engine.reportingConfig.filePath = dir
+	engine.reportingConfig.Manifest()
+	// The factory adds no cash flows, verifying applies them from the manifest
+	engine.portfolioConfig.AddCashFlow(Deposit(decimal.NewFromInt(1000), engine.backtester.start.Add(2*time.Minute)))
+	if _, err := engine.WithParameters(params).WithRunID("run-1").Run(); err != nil {
+		t.Fatalf("Error running engine: %v", err)
+	}
+	manifest, err := ReadManifestFile(filepath.Join(dir, "verify_manifest.json"))
+	if err != nil {
+		t.Fatalf("ReadManifestFile() error = %v", err)
+	}
+	if manifest.RunID != "run-1" || len(manifest.Candles) != 2 || manifest.Parameters["lookback"].Value != "4" || manifest.Build.GoVersion == "" ||
+		len(manifest.Engine.CashFlows) != 1 {
+		t.Fatalf("manifest = %+v, want run-1 with primary and execution candles, lookback 4 and a deposit", manifest)
+	}
+
+	tests := []struct {
+		name    string
+		factory EngineFactory
+		wantErr error
+	}{
+		{"should verify identical run", factory(5), nil},
+		{"should detect changed candles", factory(4), ManifestMismatchErr},
+		{"should detect changed strategy", func(params Params) (*Engine, error) {
+			return mockEngine(&allocatorStrategy{}, mockInstrument(), &signalAllocator{}, &fillingBroker{}).Quiet(), nil
+		}, ManifestMismatchErr},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := VerifyManifest(manifest, tt.factory, nil); !errors.Is(err, tt.wantErr) {
+				t.Errorf("VerifyManifest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
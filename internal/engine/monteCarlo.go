@@ -0,0 +1,265 @@
+package engine
+
+import (
+	"backtester/types"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"sort"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+var NotEnoughSamplesErr = errors.New("monte carlo needs at least two closed trades or daily returns")
+
+type MonteCarloMethod string
+
+const (
+	// MonteCarloReshuffle replays the closed trades in a random order. Final equity is the same on
+	// every path, drawdowns and losing streaks are not.
+	MonteCarloReshuffle MonteCarloMethod = "RESHUFFLE"
+	// MonteCarloResample draws closed trades with replacement (bootstrap).
+	MonteCarloResample MonteCarloMethod = "RESAMPLE"
+	// MonteCarloDailyReturns draws daily returns of the portfolio snapshots with replacement.
+	MonteCarloDailyReturns MonteCarloMethod = "DAILY_RETURNS"
+)
+
+type MonteCarloConfig struct {
+	method         MonteCarloMethod
+	simulations    int
+	seed           uint64
+	ruinFraction   decimal.Decimal
+	startingEquity decimal.Decimal
+}
+
+// NewMonteCarloConfig runs the given number of simulated paths. The same seed gives the same results.
+// Ruin defaults to losing half of the starting equity.
+func NewMonteCarloConfig(method MonteCarloMethod, simulations int, seed uint64) *MonteCarloConfig {
+	return &MonteCarloConfig{
+		method:       method,
+		simulations:  simulations,
+		seed:         seed,
+		ruinFraction: decimal.RequireFromString("0.5"),
+	}
+}
+
+// RuinAt counts a path as ruined once equity falls the given fraction below the starting equity.
+func (c *MonteCarloConfig) RuinAt(fraction decimal.Decimal) *MonteCarloConfig {
+	c.ruinFraction = fraction
+	return c
+}
+
+// StartingEquity overrides the equity every path starts from, by default the first snapshot value of the run.
+// Trade paths add fixed trade PnL to it, so a smaller starting equity shows the risk of trading the same size with less capital.
+func (c *MonteCarloConfig) StartingEquity(equity decimal.Decimal) *MonteCarloConfig {
+	c.startingEquity = equity
+	return c
+}
+
+// Distribution summarises a metric over all simulated paths.
+type Distribution struct {
+	Mean decimal.Decimal `json:"mean"`
+	P5   decimal.Decimal `json:"p5"`
+	P25  decimal.Decimal `json:"p25"`
+	P50  decimal.Decimal `json:"p50"`
+	P75  decimal.Decimal `json:"p75"`
+	P95  decimal.Decimal `json:"p95"`
+}
+
+type MonteCarloReport struct {
+	Method      MonteCarloMethod `json:"method"`
+	Simulations int              `json:"simulations"`
+	// Samples is the number of trades or daily returns every path is built from
+	Samples int `json:"samples"`
+
+	FinalEquity        Distribution `json:"final_equity"`
+	CAGR               Distribution `json:"cagr"`
+	MaxDrawdownPercent Distribution `json:"max_drawdown_percent"`
+	// MaxConsecutiveLosses counts losing trades, or losing days for MonteCarloDailyReturns
+	MaxConsecutiveLosses Distribution    `json:"max_consecutive_losses"`
+	ProbabilityOfRuin    decimal.Decimal `json:"probability_of_ruin"`
+}
+
+// WithMonteCarlo runs a Monte Carlo analysis on the results after the backtest and adds it to the report.
+func (e *Engine) WithMonteCarlo(config *MonteCarloConfig) *Engine {
+	e.monteCarlo = config
+	return e
+}
+
+type monteCarloPath struct {
+	finalEquity float64
+	maxDrawdown float64
+	maxLosses   int
+	ruined      bool
+}
+
+// runMonteCarlo simulates paths from the trades or snapshots of a finished run.
+func runMonteCarlo(config *MonteCarloConfig, trades []trade, snapshots []types.PortfolioView, period time.Duration) (*MonteCarloReport, error) {
+	if config.simulations < 1 {
+		return nil, fmt.Errorf("monte carlo needs at least one simulation, got %d", config.simulations)
+	}
+
+	startEquity := config.startingEquity.InexactFloat64()
+	if !config.startingEquity.IsPositive() && len(snapshots) > 0 {
+		startEquity = portfolioValue(snapshots[0]).InexactFloat64()
+	}
+	if startEquity <= 0 {
+		return nil, fmt.Errorf("monte carlo needs a positive starting equity")
+	}
+	ruinLevel := startEquity * (1 - config.ruinFraction.InexactFloat64())
+
+	var samples []float64
+	compound := false
+	switch config.method {
+	case MonteCarloReshuffle, MonteCarloResample:
+		for _, tr := range trades {
+			if pnl, ok := tradeNetPnL(tr); ok {
+				samples = append(samples, pnl.InexactFloat64())
+			}
+		}
+	case MonteCarloDailyReturns:
+		samples = dailyReturns(snapshots, false)
+		compound = true
+	default:
+		return nil, fmt.Errorf("unknown monte carlo method %q", config.method)
+	}
+	if len(samples) < 2 {
+		return nil, NotEnoughSamplesErr
+	}
+
+	rng := rand.New(rand.NewPCG(config.seed, config.seed))
+	path := make([]float64, len(samples))
+	results := make([]monteCarloPath, config.simulations)
+
+	for i := range results {
+		switch config.method {
+		case MonteCarloReshuffle:
+			copy(path, samples)
+			rng.Shuffle(len(path), func(a, b int) { path[a], path[b] = path[b], path[a] })
+		default:
+			for j := range path {
+				path[j] = samples[rng.IntN(len(samples))]
+			}
+		}
+		results[i] = simulatePath(path, startEquity, ruinLevel, compound)
+	}
+
+	years := period.Seconds() / 31557600 // 365.25 * 24 * 3600
+	finals := make([]float64, len(results))
+	cagrs := make([]float64, len(results))
+	drawdowns := make([]float64, len(results))
+	streaks := make([]float64, len(results))
+	ruined := 0
+	for i, r := range results {
+		finals[i] = r.finalEquity
+		drawdowns[i] = r.maxDrawdown
+		streaks[i] = float64(r.maxLosses)
+		if years > 0 && r.finalEquity > 0 {
+			cagrs[i] = math.Pow(r.finalEquity/startEquity, 1/years) - 1
+		} else if r.finalEquity <= 0 {
+			cagrs[i] = -1
+		}
+		if r.ruined {
+			ruined++
+		}
+	}
+
+	return &MonteCarloReport{
+		Method:               config.method,
+		Simulations:          config.simulations,
+		Samples:              len(samples),
+		FinalEquity:          newDistribution(finals),
+		CAGR:                 newDistribution(cagrs),
+		MaxDrawdownPercent:   newDistribution(drawdowns),
+		MaxConsecutiveLosses: newDistribution(streaks),
+		ProbabilityOfRuin:    decimal.NewFromFloat(float64(ruined) / float64(len(results))),
+	}, nil
+}
+
+// simulatePath walks one path. Trade samples are PnL amounts added to equity, return samples compound.
+func simulatePath(samples []float64, startEquity, ruinLevel float64, compound bool) monteCarloPath {
+	equity, peak := startEquity, startEquity
+	result := monteCarloPath{}
+	streak := 0
+
+	for _, s := range samples {
+		if compound {
+			equity *= 1 + s
+		} else {
+			equity += s
+		}
+
+		if s < 0 {
+			streak++
+			if streak > result.maxLosses {
+				result.maxLosses = streak
+			}
+		} else {
+			streak = 0
+		}
+
+		if equity > peak {
+			peak = equity
+		}
+		if dd := (peak - equity) / peak; dd > result.maxDrawdown {
+			result.maxDrawdown = dd
+		}
+		if equity <= ruinLevel {
+			result.ruined = true
+		}
+	}
+	result.finalEquity = equity
+	return result
+}
+
+func newDistribution(values []float64) Distribution {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	sum := 0.0
+	for _, v := range sorted {
+		sum += v
+	}
+	return Distribution{
+		Mean: decimal.NewFromFloat(sum / float64(len(sorted))),
+		P5:   decimal.NewFromFloat(percentile(sorted, 0.05)),
+		P25:  decimal.NewFromFloat(percentile(sorted, 0.25)),
+		P50:  decimal.NewFromFloat(percentile(sorted, 0.50)),
+		P75:  decimal.NewFromFloat(percentile(sorted, 0.75)),
+		P95:  decimal.NewFromFloat(percentile(sorted, 0.95)),
+	}
+}
+
+// percentile interpolates linearly between the closest ranks of an ascending slice.
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	pos := p * float64(len(sorted)-1)
+	lower := int(math.Floor(pos))
+	upper := int(math.Ceil(pos))
+	if lower == upper {
+		return sorted[lower]
+	}
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(pos-float64(lower))
+}
+
+func printMonteCarlo(mc *MonteCarloReport) {
+	row := func(name string, d Distribution, scale float64, unit string) {
+		fmt.Printf("%-22s %10.2f%s %10.2f%s %10.2f%s %10.2f%s %10.2f%s\n", name,
+			d.P5.InexactFloat64()*scale, unit,
+			d.P25.InexactFloat64()*scale, unit,
+			d.P50.InexactFloat64()*scale, unit,
+			d.P75.InexactFloat64()*scale, unit,
+			d.P95.InexactFloat64()*scale, unit)
+	}
+
+	fmt.Printf("\n-- Monte Carlo (%s, %d paths of %d samples) --\n", mc.Method, mc.Simulations, mc.Samples)
+	fmt.Printf("%-22s %11s %11s %11s %11s %11s\n", "", "P5", "P25", "P50", "P75", "P95")
+	row("Final Equity:", mc.FinalEquity, 1, " ")
+	row("CAGR:", mc.CAGR, 100, "%")
+	row("Max Drawdown:", mc.MaxDrawdownPercent, 100, "%")
+	row("Max Losing Streak:", mc.MaxConsecutiveLosses, 1, " ")
+	fmt.Printf("Probability of Ruin:   %.2f%%\n", mc.ProbabilityOfRuin.Mul(decimal.NewFromInt(100)).InexactFloat64())
+}
@@ -0,0 +1,121 @@
+package engine
+
+import (
+	"backtester/types"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestTradeNetPnL(t *testing.T) {
+	at := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	buy := newExecutionReport("AAA", types.SideTypeBuy, newFill(at, "10", "10", "2"))
+	sell := newExecutionReport("AAA", types.SideTypeSell, newFill(at.Add(time.Hour), "12", "5", "1"))
+
+	// Half of the buy is matched, so half of its fee counts: (12 - 10) * 5 - 1 - 1
+	got, ok := tradeNetPnL(trade{buy: &buy, sell: &sell, qty: decimal.NewFromInt(5)})
+	if !ok || !got.Equal(decimal.NewFromInt(8)) {
+		t.Errorf("got=%s ok=%v, want 8", got, ok)
+	}
+	if _, ok := tradeNetPnL(trade{buy: &buy}); ok {
+		t.Errorf("open trade should not report a PnL")
+	}
+}
+
+func TestRunMonteCarlo(t *testing.T) {
+	at := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	closedTrade := func(entry, exit string) trade {
+		buy := newExecutionReport("AAA", types.SideTypeBuy, newFill(at, entry, "1", "0"))
+		sell := newExecutionReport("AAA", types.SideTypeSell, newFill(at.Add(time.Hour), exit, "1", "0"))
+		return trade{buy: &buy, sell: &sell, qty: decimal.NewFromInt(1)}
+	}
+	trades := []trade{
+		closedTrade("100", "400"),
+		closedTrade("100", "0"),
+		closedTrade("100", "50"),
+		closedTrade("100", "150"),
+	}
+	snapshots := []types.PortfolioView{newPv(at, "1000"), newPv(at.AddDate(1, 0, 0), "1200")}
+	year := 365 * 24 * time.Hour
+
+	t.Run("reshuffle keeps final equity", func(t *testing.T) {
+		mc, err := runMonteCarlo(NewMonteCarloConfig(MonteCarloReshuffle, 200, 1), trades, snapshots, year)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		want := decimal.NewFromInt(1200)
+		if !mc.FinalEquity.P5.Equal(want) || !mc.FinalEquity.P95.Equal(want) {
+			t.Errorf("final equity = %s..%s, want %s", mc.FinalEquity.P5, mc.FinalEquity.P95, want)
+		}
+		// Both losers back to back happen on some paths, never three
+		if !mc.MaxConsecutiveLosses.P95.Equal(decimal.NewFromInt(2)) {
+			t.Errorf("P95 losing streak = %s, want 2", mc.MaxConsecutiveLosses.P95)
+		}
+		if !mc.ProbabilityOfRuin.IsZero() {
+			t.Errorf("probability of ruin = %s, want 0", mc.ProbabilityOfRuin)
+		}
+	})
+
+	t.Run("same seed gives the same result", func(t *testing.T) {
+		a, _ := runMonteCarlo(NewMonteCarloConfig(MonteCarloResample, 100, 7), trades, snapshots, year)
+		b, _ := runMonteCarlo(NewMonteCarloConfig(MonteCarloResample, 100, 7), trades, snapshots, year)
+		if !a.FinalEquity.Mean.Equal(b.FinalEquity.Mean) || !a.ProbabilityOfRuin.Equal(b.ProbabilityOfRuin) {
+			t.Errorf("results differ: %+v vs %+v", a.FinalEquity, b.FinalEquity)
+		}
+	})
+
+	t.Run("small capital is ruined", func(t *testing.T) {
+		config := NewMonteCarloConfig(MonteCarloResample, 500, 3).StartingEquity(decimal.NewFromInt(200))
+		mc, err := runMonteCarlo(config, trades, snapshots, year)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !mc.ProbabilityOfRuin.IsPositive() {
+			t.Errorf("expected a positive probability of ruin")
+		}
+	})
+
+	t.Run("too few trades", func(t *testing.T) {
+		_, err := runMonteCarlo(NewMonteCarloConfig(MonteCarloResample, 10, 1), trades[:1], snapshots, year)
+		if !errors.Is(err, NotEnoughSamplesErr) {
+			t.Errorf("expected NotEnoughSamplesErr, got %v", err)
+		}
+	})
+
+	t.Run("daily returns compound", func(t *testing.T) {
+		daily := []types.PortfolioView{
+			newPv(at, "1000"),
+			newPv(at.AddDate(0, 0, 1), "1100"),
+			newPv(at.AddDate(0, 0, 2), "1100"),
+		}
+		mc, err := runMonteCarlo(NewMonteCarloConfig(MonteCarloDailyReturns, 100, 1), nil, daily, 2*24*time.Hour)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		// Paths draw from {+10%, 0%}: 1000, 1100 or 1210
+		if mc.FinalEquity.P5.LessThan(decimal.NewFromInt(1000)) || mc.FinalEquity.P95.GreaterThan(decimal.NewFromInt(1210)) {
+			t.Errorf("final equity out of range: %+v", mc.FinalEquity)
+		}
+	})
+}
+
+func TestPercentile(t *testing.T) {
+	sorted := []float64{1, 2, 3, 4, 5}
+	tests := []struct {
+		p    float64
+		want float64
+	}{
+		{0, 1},
+		{0.5, 3},
+		{0.25, 2},
+		{0.9, 4.6},
+		{1, 5},
+	}
+	for _, tt := range tests {
+		if got := percentile(sorted, tt.p); got-tt.want > 1e-9 || tt.want-got > 1e-9 {
+			t.Errorf("percentile(%v) = %v, want %v", tt.p, got, tt.want)
+		}
+	}
+}
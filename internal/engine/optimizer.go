@@ -0,0 +1,536 @@
+package engine
+
+import (
+	"backtester/types"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+var (
+	NoParametersErr       = errors.New("optimizer needs at least one parameter")
+	UnknownMetricErr      = errors.New("unknown metric")
+	InvalidParamRangeErr  = errors.New("parameter range must have a positive step and from <= to")
+	MissingEngineErr      = errors.New("engine factory returned no engine")
+	WrongParameterTypeErr = errors.New("parameter has a different type")
+)
+
+// Metric names a Report metric that results can be ranked by.
+type Metric string
+
+const (
+	MetricNetProfit          Metric = "net_profit"
+	MetricCAGR               Metric = "cagr"
+	MetricSharpe             Metric = "sharpe_ratio"
+	MetricSortino            Metric = "sortino_ratio"
+	MetricProfitFactor       Metric = "profit_factor"
+	MetricWinLossRatio       Metric = "win_loss_ratio"
+	MetricMaxDrawdownPercent Metric = "max_drawdown_percent"
+	MetricTotalTrades        Metric = "total_trades"
+	MetricTotalFees          Metric = "total_fees"
+	MetricExcessCAGR         Metric = "excess_cagr"
+	MetricAlpha              Metric = "alpha"
+	MetricBeta               Metric = "beta"
+	MetricInformationRatio   Metric = "information_ratio"
+	MetricCalmar             Metric = "calmar_ratio"
+	MetricMAR                Metric = "mar_ratio"
+	MetricUlcerIndex         Metric = "ulcer_index"
+	MetricUPI                Metric = "ulcer_performance_index"
+	MetricOmega              Metric = "omega_ratio"
+	MetricExpectancy         Metric = "expectancy"
+	MetricSQN                Metric = "sqn"
+)
+
+// reportMetrics is the column order of the optimization table.
+var reportMetrics = []Metric{
+	MetricNetProfit,
+	MetricCAGR,
+	MetricSharpe,
+	MetricSortino,
+	MetricProfitFactor,
+	MetricWinLossRatio,
+	MetricMaxDrawdownPercent,
+	MetricTotalTrades,
+	MetricTotalFees,
+	MetricExcessCAGR,
+	MetricAlpha,
+	MetricBeta,
+	MetricInformationRatio,
+	MetricCalmar,
+	MetricMAR,
+	MetricUlcerIndex,
+	MetricUPI,
+	MetricOmega,
+	MetricExpectancy,
+	MetricSQN,
+}
+
+// Value reads the metric from a report.
+func (m Metric) Value(r *Report) (decimal.Decimal, error) {
+	switch m {
+	case MetricNetProfit:
+		return r.NetProfit, nil
+	case MetricCAGR:
+		return r.CAGR, nil
+	case MetricSharpe:
+		return r.SharpeRatio, nil
+	case MetricSortino:
+		return r.SortinoRatio, nil
+	case MetricProfitFactor:
+		return r.ProfitFactor, nil
+	case MetricWinLossRatio:
+		return r.WinLossRatio, nil
+	case MetricMaxDrawdownPercent:
+		return r.MaxDrawdownPercent, nil
+	case MetricTotalTrades:
+		return decimal.NewFromInt(int64(r.TotalTrades)), nil
+	case MetricTotalFees:
+		return r.TotalFees, nil
+	case MetricExcessCAGR:
+		return r.ExcessCAGR, nil
+	case MetricAlpha:
+		return r.Alpha, nil
+	case MetricBeta:
+		return r.Beta, nil
+	case MetricInformationRatio:
+		return r.InformationRatio, nil
+	case MetricCalmar:
+		return r.CalmarRatio, nil
+	case MetricMAR:
+		return r.MARRatio, nil
+	case MetricUlcerIndex:
+		return r.UlcerIndex, nil
+	case MetricUPI:
+		return r.UlcerPerformanceIndex, nil
+	case MetricOmega:
+		return r.OmegaRatio, nil
+	case MetricExpectancy:
+		return r.Expectancy, nil
+	case MetricSQN:
+		return r.SQN, nil
+	}
+	return decimal.Zero, fmt.Errorf("%w: %s", UnknownMetricErr, m)
+}
+
+// LowerIsBetter reports whether a smaller value of the metric ranks higher.
+func (m Metric) LowerIsBetter() bool {
+	return m == MetricMaxDrawdownPercent || m == MetricTotalFees || m == MetricUlcerIndex
+}
+
+// Parameter is a named list of values to sweep.
+type Parameter struct {
+	Name   string
+	Values []any
+}
+
+// Values sweeps an explicit list, for example a set of execution intervals.
+func Values(name string, values ...any) Parameter {
+	return Parameter{Name: name, Values: values}
+}
+
+// IntRange sweeps from, from+step, ... up to and including to.
+func IntRange(name string, from, to, step int) (Parameter, error) {
+	if step <= 0 || from > to {
+		return Parameter{}, fmt.Errorf("%w: %s", InvalidParamRangeErr, name)
+	}
+	p := Parameter{Name: name}
+	for v := from; v <= to; v += step {
+		p.Values = append(p.Values, v)
+	}
+	return p, nil
+}
+
+// DecimalRange sweeps from, from+step, ... up to and including to.
+func DecimalRange(name string, from, to, step decimal.Decimal) (Parameter, error) {
+	if !step.IsPositive() || from.GreaterThan(to) {
+		return Parameter{}, fmt.Errorf("%w: %s", InvalidParamRangeErr, name)
+	}
+	p := Parameter{Name: name}
+	for v := from; v.LessThanOrEqual(to); v = v.Add(step) {
+		p.Values = append(p.Values, v)
+	}
+	return p, nil
+}
+
+// Params is one combination of parameter values handed to the EngineFactory.
+type Params map[string]any
+
+func (p Params) Int(name string) (int, error) {
+	v, ok := p[name].(int)
+	if !ok {
+		return 0, fmt.Errorf("%w: %s is %T, want int", WrongParameterTypeErr, name, p[name])
+	}
+	return v, nil
+}
+
+func (p Params) Decimal(name string) (decimal.Decimal, error) {
+	v, ok := p[name].(decimal.Decimal)
+	if !ok {
+		return decimal.Zero, fmt.Errorf("%w: %s is %T, want decimal", WrongParameterTypeErr, name, p[name])
+	}
+	return v, nil
+}
+
+func (p Params) Interval(name string) (types.Interval, error) {
+	v, ok := p[name].(types.Interval)
+	if !ok {
+		return "", fmt.Errorf("%w: %s is %T, want interval", WrongParameterTypeErr, name, p[name])
+	}
+	return v, nil
+}
+
+func (p Params) names() []string {
+	names := make([]string, 0, len(p))
+	for name := range p {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (p Params) String() string {
+	s := ""
+	for i, name := range p.names() {
+		if i > 0 {
+			s += " "
+		}
+		s += fmt.Sprintf("%s=%v", name, p[name])
+	}
+	return s
+}
+
+// EngineFactory builds a fresh engine for one parameter combination. Every call must return new
+// feeds, configs, strategy, allocator and broker since engines run concurrently. The database passed
+// to NewEngine is replaced by the optimizer's shared candle cache, so it may be nil.
+type EngineFactory func(params Params) (*Engine, error)
+
+type OptimizationResult struct {
+	Rank   int
+	Params Params
+	Score  decimal.Decimal
+	Report *Report
+	Err    error
+}
+
+// Optimizer runs one backtest per parameter combination in parallel and ranks the results.
+type Optimizer struct {
+	db        dataStore
+	factory   EngineFactory
+	workers   int
+	objective Metric
+
+	// timeRange clips every engine to a sub range of its feeds, set by walk-forward runs
+	timeRange *timeRange
+}
+
+type timeRange struct {
+	start time.Time
+	end   time.Time
+}
+
+// NewOptimizer creates an optimizer that ranks by Sharpe ratio. workers limits the number of engines
+// running at once, values below one mean one.
+func NewOptimizer(db dataStore, factory EngineFactory, workers int) *Optimizer {
+	if workers < 1 {
+		workers = 1
+	}
+	return &Optimizer{
+		db:        db,
+		factory:   factory,
+		workers:   workers,
+		objective: MetricSharpe,
+	}
+}
+
+// RankBy sets the metric the results are sorted by.
+func (o *Optimizer) RankBy(metric Metric) *Optimizer {
+	o.objective = metric
+	return o
+}
+
+// Run sweeps the full grid of the given parameters and returns the results ranked best first.
+// Combinations that failed are ranked last and carry their error.
+func (o *Optimizer) Run(params ...Parameter) ([]OptimizationResult, error) {
+	if len(params) == 0 {
+		return nil, NoParametersErr
+	}
+	if _, err := o.objective.Value(&Report{}); err != nil {
+		return nil, err
+	}
+	return o.runCombinations(gridCombinations(params), newSharedStore(o.db))
+}
+
+// within returns a copy of the optimizer that clips every engine to the given range.
+func (o *Optimizer) within(start, end time.Time) *Optimizer {
+	clipped := *o
+	clipped.timeRange = &timeRange{start: start, end: end}
+	return &clipped
+}
+
+func (o *Optimizer) runCombinations(combinations []Params, store *sharedStore) ([]OptimizationResult, error) {
+	results := make([]OptimizationResult, len(combinations))
+	sem := make(chan struct{}, o.workers)
+	var wg sync.WaitGroup
+
+	for i, combination := range combinations {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			result := OptimizationResult{Params: combination}
+			result.Report, result.Err = o.runOne(combination, store)
+			if result.Err == nil {
+				result.Score, _ = o.objective.Value(result.Report)
+			}
+			results[i] = result
+		}()
+	}
+	wg.Wait()
+
+	rankResults(results, o.objective)
+	return results, nil
+}
+
+func (o *Optimizer) runOne(params Params, store *sharedStore) (*Report, error) {
+	eng, err := o.buildEngine(params, store)
+	if err != nil {
+		return nil, err
+	}
+	return eng.Run()
+}
+
+func (o *Optimizer) buildEngine(params Params, store *sharedStore) (*Engine, error) {
+	eng, err := o.factory(params)
+	if err != nil {
+		return nil, err
+	}
+	if eng == nil {
+		return nil, MissingEngineErr
+	}
+	eng.db = store
+	eng.params = params
+	// Engines run side by side, so none may write files, save its run or share a journal
+	eng.reportingConfig = eng.reportingConfig.withoutFiles()
+	eng.runStore = nil
+	eng.journalSink = nil
+	if o.timeRange != nil {
+		eng.restrictTimeRange(o.timeRange.start, o.timeRange.end)
+	}
+	return eng.Quiet(), nil
+}
+
+// rankResults orders successful results by score, keeping grid order on ties, and numbers them from 1.
+func rankResults(results []OptimizationResult, objective Metric) {
+	sort.SliceStable(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if (a.Err == nil) != (b.Err == nil) {
+			return a.Err == nil
+		}
+		if objective.LowerIsBetter() {
+			return a.Score.LessThan(b.Score)
+		}
+		return a.Score.GreaterThan(b.Score)
+	})
+	for i := range results {
+		results[i].Rank = i + 1
+	}
+}
+
+// gridCombinations returns the cartesian product of all parameter values, the last parameter varying fastest.
+func gridCombinations(params []Parameter) []Params {
+	combinations := []Params{{}}
+	for _, param := range params {
+		next := make([]Params, 0, len(combinations)*len(param.Values))
+		for _, combination := range combinations {
+			for _, value := range param.Values {
+				p := make(Params, len(combination)+1)
+				for k, v := range combination {
+					p[k] = v
+				}
+				p[param.Name] = value
+				next = append(next, p)
+			}
+		}
+		combinations = next
+	}
+	return combinations
+}
+
+// sharedStore loads every asset and candle series once and hands the same slices to all engines.
+// Engines only read candles, so sharing them between goroutines is safe.
+type sharedStore struct {
+	db dataStore
+
+	mu      sync.Mutex
+	assets  map[string]*storeEntry[*types.Asset]
+	candles map[candleKey]*storeEntry[[]types.Candle]
+}
+
+type candleKey struct {
+	assetId  int
+	interval types.Interval
+	start    time.Time
+	end      time.Time
+}
+
+type storeEntry[T any] struct {
+	once  sync.Once
+	value T
+	err   error
+}
+
+func newSharedStore(db dataStore) *sharedStore {
+	return &sharedStore{
+		db:      db,
+		assets:  make(map[string]*storeEntry[*types.Asset]),
+		candles: make(map[candleKey]*storeEntry[[]types.Candle]),
+	}
+}
+
+func (s *sharedStore) GetAssetByTicker(ticker string, ctx context.Context) (*types.Asset, error) {
+	s.mu.Lock()
+	entry, ok := s.assets[ticker]
+	if !ok {
+		entry = &storeEntry[*types.Asset]{}
+		s.assets[ticker] = entry
+	}
+	s.mu.Unlock()
+
+	entry.once.Do(func() {
+		entry.value, entry.err = s.db.GetAssetByTicker(ticker, ctx)
+	})
+	return entry.value, entry.err
+}
+
+func (s *sharedStore) GetAggregates(assetId int, ticker string, interval types.Interval, start, end time.Time, ctx context.Context) ([]types.Candle, error) {
+	key := candleKey{assetId: assetId, interval: interval, start: start, end: end}
+	s.mu.Lock()
+	entry, ok := s.candles[key]
+	if !ok {
+		entry = &storeEntry[[]types.Candle]{}
+		s.candles[key] = entry
+	}
+	s.mu.Unlock()
+
+	entry.once.Do(func() {
+		entry.value, entry.err = s.db.GetAggregates(assetId, ticker, interval, start, end, ctx)
+	})
+	return entry.value, entry.err
+}
+
+// WriteOptimizationCSVFile writes the ranked results as one row per combination.
+func WriteOptimizationCSVFile(path string, results []OptimizationResult) error {
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create optimization file: %w", err)
+	}
+	defer f.Close()
+
+	return writeOptimizationCSV(f, results)
+}
+
+func writeOptimizationCSV(w io.Writer, results []OptimizationResult) error {
+	cw := csv.NewWriter(w)
+	defer cw.Flush()
+
+	var paramNames []string
+	if len(results) > 0 {
+		paramNames = results[0].Params.names()
+	}
+
+	header := append([]string{"rank"}, paramNames...)
+	header = append(header, "score")
+	for _, m := range reportMetrics {
+		header = append(header, string(m))
+	}
+	header = append(header, "error")
+	if err := cw.Write(header); err != nil {
+		return fmt.Errorf("write header: %w", err)
+	}
+
+	for _, r := range results {
+		row := []string{fmt.Sprint(r.Rank)}
+		for _, name := range paramNames {
+			row = append(row, fmt.Sprint(r.Params[name]))
+		}
+		row = append(row, r.Score.String())
+		for _, m := range reportMetrics {
+			if r.Report == nil {
+				row = append(row, "")
+				continue
+			}
+			v, _ := m.Value(r.Report)
+			row = append(row, v.String())
+		}
+		errMsg := ""
+		if r.Err != nil {
+			errMsg = r.Err.Error()
+		}
+		row = append(row, errMsg)
+
+		if err := cw.Write(row); err != nil {
+			return fmt.Errorf("write row: %w", err)
+		}
+	}
+	return cw.Error()
+}
+
+type optimizationRow struct {
+	Rank    int                        `json:"rank"`
+	Params  Params                     `json:"params"`
+	Score   decimal.Decimal            `json:"score"`
+	Metrics map[Metric]decimal.Decimal `json:"metrics,omitempty"`
+	Error   string                     `json:"error,omitempty"`
+}
+
+// WriteOptimizationJSONFile writes the ranked results as a JSON array.
+func WriteOptimizationJSONFile(path string, results []OptimizationResult) error {
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create optimization file: %w", err)
+	}
+	defer f.Close()
+
+	return writeOptimizationJSON(f, results)
+}
+
+func writeOptimizationJSON(w io.Writer, results []OptimizationResult) error {
+	rows := make([]optimizationRow, 0, len(results))
+	for _, r := range results {
+		row := optimizationRow{Rank: r.Rank, Params: r.Params, Score: r.Score}
+		if r.Report != nil {
+			row.Metrics = make(map[Metric]decimal.Decimal, len(reportMetrics))
+			for _, m := range reportMetrics {
+				row.Metrics[m], _ = m.Value(r.Report)
+			}
+		}
+		if r.Err != nil {
+			row.Error = r.Err.Error()
+		}
+		rows = append(rows, row)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(rows)
+}
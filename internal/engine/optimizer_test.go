@@ -0,0 +1,182 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"backtester/types"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestGridCombinations(t *testing.T) {
+	lookback, err := IntRange("lookback", 2, 6, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fraction, err := DecimalRange("fraction", decimal.RequireFromString("0.1"), decimal.RequireFromString("0.2"), decimal.RequireFromString("0.1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := gridCombinations([]Parameter{lookback, fraction, Values("interval", types.Hour)})
+	if len(got) != 6 {
+		t.Fatalf("len(got)=%d, want 6", len(got))
+	}
+	// The last parameter varies fastest
+	want := []string{
+		"fraction=0.1 interval=60 lookback=2",
+		"fraction=0.2 interval=60 lookback=2",
+		"fraction=0.1 interval=60 lookback=4",
+	}
+	for i, w := range want {
+		if got[i].String() != w {
+			t.Errorf("combination %d = %q, want %q", i, got[i].String(), w)
+		}
+	}
+
+	if _, err := IntRange("bad", 5, 1, 1); !errors.Is(err, InvalidParamRangeErr) {
+		t.Errorf("expected InvalidParamRangeErr, got %v", err)
+	}
+}
+
+func TestRankResults(t *testing.T) {
+	results := []OptimizationResult{
+		{Params: Params{"id": 0}, Score: decimal.NewFromInt(1)},
+		{Params: Params{"id": 1}, Err: errors.New("boom")},
+		{Params: Params{"id": 2}, Score: decimal.NewFromInt(3)},
+		{Params: Params{"id": 3}, Score: decimal.NewFromInt(3)},
+	}
+
+	rankResults(results, MetricSharpe)
+	wantOrder := []int{2, 3, 0, 1}
+	for i, want := range wantOrder {
+		if results[i].Params["id"] != want || results[i].Rank != i+1 {
+			t.Errorf("position %d: id=%v rank=%d, want id=%d rank=%d", i, results[i].Params["id"], results[i].Rank, want, i+1)
+		}
+	}
+
+	rankResults(results, MetricMaxDrawdownPercent)
+	if results[0].Params["id"] != 0 {
+		t.Errorf("lower is better should rank the smallest score first, got id=%v", results[0].Params["id"])
+	}
+}
+
+func TestOptimizer_RunSharesCandleData(t *testing.T) {
+	db := &countingDb{mockDb: mockDb{assets: map[string]*types.Asset{
+		"AAPL": {Id: 1, Ticker: "AAPL", Type: types.AssetTypeStock},
+	}}}
+
+	factory := func(params Params) (*Engine, error) {
+		cash, err := params.Decimal("cash")
+		if err != nil {
+			return nil, err
+		}
+		eng := mockEngine(&allocatorStrategy{}, mockInstrument(), &mockAllocator{}, &mockBroker{})
+		eng.portfolioConfig.initialCash = cash
+		eng.portfolio.cash = cash
+		return eng, nil
+	}
+
+	cash, _ := DecimalRange("cash", decimal.NewFromInt(1000), decimal.NewFromInt(4000), decimal.NewFromInt(1000))
+	results, err := NewOptimizer(db, factory, 2).RankBy(MetricNetProfit).Run(cash)
+	if err != nil {
+		t.Fatalf("Error running optimizer: %v", err)
+	}
+
+	if len(results) != 4 {
+		t.Fatalf("len(results)=%d, want 4", len(results))
+	}
+	for _, r := range results {
+		if r.Err != nil || r.Report == nil {
+			t.Fatalf("combination %s failed: %v", r.Params, r.Err)
+		}
+	}
+	// Primary and execution feed share ticker, interval and range, so one load serves every engine
+	if db.aggregateCalls != 1 {
+		t.Errorf("GetAggregates called %d times, want 1", db.aggregateCalls)
+	}
+
+	var buf bytes.Buffer
+	if err := writeOptimizationCSV(&buf, results); err != nil {
+		t.Fatalf("write csv: %v", err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("read csv: %v", err)
+	}
+	if len(rows) != 5 || rows[0][1] != "cash" {
+		t.Errorf("unexpected csv: %v", rows)
+	}
+
+	buf.Reset()
+	if err := writeOptimizationJSON(&buf, results); err != nil {
+		t.Fatalf("write json: %v", err)
+	}
+	if !strings.Contains(buf.String(), `"sharpe_ratio"`) {
+		t.Errorf("json output misses metrics: %s", buf.String())
+	}
+}
+
+func TestOptimizer_RunWritesNothing(t *testing.T) {
+	dir := t.TempDir()
+	var journal bytes.Buffer
+	factory := func(params Params) (*Engine, error) {
+		eng := mockEngine(&allocatorStrategy{}, mockInstrument(), &mockAllocator{}, &mockBroker{})
+		eng.reportingConfig = NewReportingConfig(decimal.NewFromFloat(0.03), true, "sweep", dir).HTMLReport().JSONReport().Manifest()
+		return eng.WithJournal(NewJSONLJournal(&journal)), nil
+	}
+
+	db := mockDb{assets: map[string]*types.Asset{"AAPL": {Id: 1, Ticker: "AAPL", Type: types.AssetTypeStock}}}
+	results, err := NewOptimizer(db, factory, 2).Run(Values("id", 1, 2))
+	if err != nil {
+		t.Fatalf("Error running optimizer: %v", err)
+	}
+	for _, r := range results {
+		if r.Err != nil {
+			t.Fatalf("combination %s failed: %v", r.Params, r.Err)
+		}
+	}
+	if files, _ := os.ReadDir(dir); len(files) != 0 {
+		t.Errorf("optimizer wrote %d report files, want none", len(files))
+	}
+	if journal.Len() != 0 {
+		t.Errorf("optimizer wrote to the journal of the factory: %s", journal.String())
+	}
+}
+
+func TestOptimizer_FactoryErrorIsReported(t *testing.T) {
+	factory := func(params Params) (*Engine, error) {
+		_, err := params.Int("lookback")
+		return nil, err
+	}
+
+	results, err := NewOptimizer(mockDb{}, factory, 1).Run(Values("lookback", "not an int"))
+	if err != nil {
+		t.Fatalf("Error running optimizer: %v", err)
+	}
+	if len(results) != 1 || !errors.Is(results[0].Err, WrongParameterTypeErr) {
+		t.Errorf("expected WrongParameterTypeErr, got %+v", results)
+	}
+}
+
+// countingDb counts the candle loads that reach the database.
+type countingDb struct {
+	mockDb
+	mu             sync.Mutex
+	aggregateCalls int
+}
+
+func (c *countingDb) GetAggregates(assetId int, ticker string, interval types.Interval, start, end time.Time, ctx context.Context) ([]types.Candle, error) {
+	c.mu.Lock()
+	c.aggregateCalls++
+	c.mu.Unlock()
+	return c.mockDb.GetAggregates(assetId, ticker, interval, start, end, ctx)
+}
@@ -0,0 +1,377 @@
+package engine
+
+import (
+	"backtester/types"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// rollingWindowMonths are the trailing windows of the rolling metrics.
+var rollingWindowMonths = []int{3, 6, 12}
+
+// PeriodReturn is the time-weighted return of one calendar month, or of one year when Month is zero.
+type PeriodReturn struct {
+	Year   int             `json:"year"`
+	Month  time.Month      `json:"month,omitempty"`
+	Return decimal.Decimal `json:"return"`
+}
+
+// RollingMetrics holds metrics over a trailing window of Months, one point per daily return once the
+// run is longer than the window. Sharpe and volatility are annualised like the other daily ratios.
+type RollingMetrics struct {
+	Months int            `json:"months"`
+	Points []RollingPoint `json:"points"`
+}
+
+type RollingPoint struct {
+	Time        time.Time       `json:"time"`
+	Sharpe      decimal.Decimal `json:"sharpe"`
+	Volatility  decimal.Decimal `json:"volatility"`
+	MaxDrawdown decimal.Decimal `json:"max_drawdown"`
+}
+
+type periodicSummary struct {
+	monthly, yearly       []PeriodReturn
+	bestMonth, worstMonth PeriodReturn
+	bestYear, worstYear   PeriodReturn
+	positiveMonths        decimal.Decimal
+}
+
+// calcPeriodicReturns builds the monthly and yearly return tables with their extremes.
+func calcPeriodicReturns(snapshots []types.PortfolioView, wg *sync.WaitGroup) periodicSummary {
+	defer wg.Done()
+	summary := periodicSummary{
+		monthly: monthlyReturns(snapshots),
+		yearly:  yearlyReturns(snapshots),
+	}
+	summary.bestMonth, summary.worstMonth = bestAndWorst(summary.monthly)
+	summary.bestYear, summary.worstYear = bestAndWorst(summary.yearly)
+
+	if len(summary.monthly) > 0 {
+		positive := 0
+		for _, r := range summary.monthly {
+			if r.Return.IsPositive() {
+				positive++
+			}
+		}
+		summary.positiveMonths = decimal.NewFromInt(int64(positive)).Div(decimal.NewFromInt(int64(len(summary.monthly))))
+	}
+	return summary
+}
+
+// monthlyReturns returns the time-weighted return of every calendar month, measured from the
+// previous month's last snapshot. The first month starts at the first snapshot.
+func monthlyReturns(snapshots []types.PortfolioView) []PeriodReturn {
+	returns, _ := measuredMonthlyReturns(snapshots)
+	return returns
+}
+
+// measuredMonthlyReturns is monthlyReturns that also reports per month whether it started from a
+// positive value. The others carry a zero return that was never measured.
+func measuredMonthlyReturns(snapshots []types.PortfolioView) ([]PeriodReturn, []bool) {
+	return periodReturns(snapshots, sameMonth, func(t time.Time) PeriodReturn {
+		return PeriodReturn{Year: t.Year(), Month: t.Month()}
+	})
+}
+
+// yearlyReturns is monthlyReturns per calendar year.
+func yearlyReturns(snapshots []types.PortfolioView) []PeriodReturn {
+	returns, _ := periodReturns(snapshots, func(a, b time.Time) bool { return a.Year() == b.Year() }, func(t time.Time) PeriodReturn {
+		return PeriodReturn{Year: t.Year()}
+	})
+	return returns
+}
+
+func periodReturns(snapshots []types.PortfolioView, samePeriod func(a, b time.Time) bool, period func(time.Time) PeriodReturn) ([]PeriodReturn, []bool) {
+	equity := timeWeightedEquity(snapshots)
+	var (
+		out      []PeriodReturn
+		measured []bool
+	)
+	prev := decimal.Zero
+	for i, snap := range snapshots {
+		if i == 0 {
+			prev = equity[0]
+		}
+		last := i == len(snapshots)-1 || !samePeriod(snap.Time, snapshots[i+1].Time)
+		if !last {
+			continue
+		}
+		r := period(snap.Time)
+		if prev.IsPositive() {
+			r.Return = equity[i].Div(prev).Sub(decimal.NewFromInt(1))
+		}
+		out = append(out, r)
+		measured = append(measured, prev.IsPositive())
+		prev = equity[i]
+	}
+	return out, measured
+}
+
+func sameMonth(a, b time.Time) bool {
+	return a.Year() == b.Year() && a.Month() == b.Month()
+}
+
+func bestAndWorst(returns []PeriodReturn) (PeriodReturn, PeriodReturn) {
+	if len(returns) == 0 {
+		return PeriodReturn{}, PeriodReturn{}
+	}
+	best, worst := returns[0], returns[0]
+	for _, r := range returns[1:] {
+		if r.Return.GreaterThan(best.Return) {
+			best = r
+		}
+		if r.Return.LessThan(worst.Return) {
+			worst = r
+		}
+	}
+	return best, worst
+}
+
+// calcRollingMetrics computes Sharpe, volatility and max drawdown over every trailing window. With
+// weekdaysOnly weekend snapshots are skipped, like for the daily ratios of the report.
+func calcRollingMetrics(snapshots []types.PortfolioView, annualRiskFree decimal.Decimal, periodsPerYear int, weekdaysOnly bool, wg *sync.WaitGroup) []RollingMetrics {
+	defer wg.Done()
+
+	equity := timeWeightedEquity(snapshots)
+	var times []time.Time
+	var values []float64
+	for i, snap := range snapshots {
+		if weekdaysOnly && isWeekend(snap.Time) {
+			continue
+		}
+		times = append(times, snap.Time)
+		values = append(values, equity[i].InexactFloat64())
+	}
+	if len(values) < 3 {
+		return nil
+	}
+
+	// returns[i] is the return from values[i-1] to values[i], zero for i == 0
+	returns := make([]float64, len(values))
+	for i := 1; i < len(values); i++ {
+		if values[i-1] > 0 {
+			returns[i] = values[i]/values[i-1] - 1
+		}
+	}
+
+	periods := float64(periodsPerYear)
+	rf := math.Pow(1+annualRiskFree.InexactFloat64(), 1/periods) - 1
+
+	var out []RollingMetrics
+	for _, months := range rollingWindowMonths {
+		metrics := RollingMetrics{Months: months}
+		start := 0
+		var sum, sumSq float64
+		for end := 1; end < len(values); end++ {
+			sum += returns[end]
+			sumSq += returns[end] * returns[end]
+
+			from := times[end].AddDate(0, -months, 0)
+			for start < end && times[start].Before(from) {
+				start++
+				sum -= returns[start]
+				sumSq -= returns[start] * returns[start]
+			}
+			// Only full windows
+			if times[0].After(from) {
+				continue
+			}
+
+			n := float64(end - start)
+			if n < 2 {
+				continue
+			}
+			mean := sum / n
+			variance := math.Max(sumSq/n-mean*mean, 0) * n / (n - 1)
+			sd := math.Sqrt(variance)
+
+			point := RollingPoint{
+				Time:        times[end],
+				Volatility:  decimal.NewFromFloat(sd * math.Sqrt(periods)),
+				MaxDrawdown: decimal.NewFromFloat(maxDrawdownFraction(values[start : end+1])),
+			}
+			if sd > 0 {
+				point.Sharpe = decimal.NewFromFloat((mean - rf) / sd * math.Sqrt(periods))
+			}
+			metrics.Points = append(metrics.Points, point)
+		}
+		out = append(out, metrics)
+	}
+	return out
+}
+
+func maxDrawdownFraction(values []float64) float64 {
+	peak, maxDD := 0.0, 0.0
+	for _, v := range values {
+		peak = math.Max(peak, v)
+		if peak > 0 {
+			maxDD = math.Max(maxDD, (peak-v)/peak)
+		}
+	}
+	return maxDD
+}
+
+func (e *Engine) writeRollingCSVFile(path string, rolling []RollingMetrics) error {
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create rolling metrics file: %w", err)
+	}
+	defer f.Close()
+
+	return writeRollingCSV(f, rolling)
+}
+
+// writeRollingCSV writes one row per point in time with the metrics of every window as columns.
+// Windows that do not cover a row yet are left empty.
+func writeRollingCSV(w io.Writer, rolling []RollingMetrics) error {
+	cw := csv.NewWriter(w)
+	defer cw.Flush()
+
+	header := []string{"time"}
+	byTime := make(map[time.Time][]string)
+	var times []time.Time
+	for i, window := range rolling {
+		header = append(header,
+			fmt.Sprintf("sharpe_%dm", window.Months),
+			fmt.Sprintf("volatility_%dm", window.Months),
+			fmt.Sprintf("max_drawdown_%dm", window.Months),
+		)
+		for _, p := range window.Points {
+			row, ok := byTime[p.Time]
+			if !ok {
+				row = make([]string, 3*len(rolling))
+				byTime[p.Time] = row
+				times = append(times, p.Time)
+			}
+			copy(row[3*i:], []string{p.Sharpe.StringFixed(4), p.Volatility.StringFixed(4), p.MaxDrawdown.StringFixed(4)})
+		}
+	}
+	if err := cw.Write(header); err != nil {
+		return fmt.Errorf("write header: %w", err)
+	}
+
+	sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+	for _, t := range times {
+		if err := cw.Write(append([]string{t.Format(time.RFC3339)}, byTime[t]...)); err != nil {
+			return fmt.Errorf("write rolling record: %w", err)
+		}
+	}
+
+	if err := cw.Error(); err != nil {
+		return fmt.Errorf("flush csv: %w", err)
+	}
+	return nil
+}
+
+func (e *Engine) writeReturnsCSVFile(path string, monthly, yearly []PeriodReturn) error {
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create returns file: %w", err)
+	}
+	defer f.Close()
+
+	return writeReturnsCSV(f, monthly, yearly)
+}
+
+// writeReturnsCSV writes one row per year with its monthly returns and the year's return, in percent.
+func writeReturnsCSV(w io.Writer, monthly, yearly []PeriodReturn) error {
+	cw := csv.NewWriter(w)
+	defer cw.Flush()
+
+	header := []string{"year"}
+	for m := time.January; m <= time.December; m++ {
+		header = append(header, m.String()[:3])
+	}
+	header = append(header, "year_return")
+	if err := cw.Write(header); err != nil {
+		return fmt.Errorf("write header: %w", err)
+	}
+
+	pct := func(d decimal.Decimal) string { return d.Mul(decimal.NewFromInt(100)).StringFixed(2) }
+	for _, year := range yearly {
+		record := make([]string, len(header))
+		record[0] = fmt.Sprintf("%d", year.Year)
+		for _, month := range monthly {
+			if month.Year == year.Year {
+				record[int(month.Month)] = pct(month.Return)
+			}
+		}
+		record[len(record)-1] = pct(year.Return)
+		if err := cw.Write(record); err != nil {
+			return fmt.Errorf("write returns record: %w", err)
+		}
+	}
+
+	if err := cw.Error(); err != nil {
+		return fmt.Errorf("flush csv: %w", err)
+	}
+	return nil
+}
+
+func printPeriodicReturns(report *Report) {
+	if len(report.YearlyReturns) == 0 {
+		return
+	}
+	pct := func(d decimal.Decimal) float64 { return d.Mul(decimal.NewFromInt(100)).InexactFloat64() }
+
+	fmt.Println("\n-- Monthly Returns (%) --")
+	fmt.Printf("%-6s", "Year")
+	for m := time.January; m <= time.December; m++ {
+		fmt.Printf("%7s", m.String()[:3])
+	}
+	fmt.Printf("%8s\n", "Year")
+	for _, year := range report.YearlyReturns {
+		fmt.Printf("%-6d", year.Year)
+		cells := make([]string, 12)
+		for _, month := range report.MonthlyReturns {
+			if month.Year == year.Year {
+				cells[month.Month-1] = fmt.Sprintf("%.1f", pct(month.Return))
+			}
+		}
+		for _, cell := range cells {
+			fmt.Printf("%7s", cell)
+		}
+		fmt.Printf("%8.1f\n", pct(year.Return))
+	}
+
+	fmt.Printf("Best Month:            %.2f%% (%d-%02d)\n", pct(report.BestMonth.Return), report.BestMonth.Year, report.BestMonth.Month)
+	fmt.Printf("Worst Month:           %.2f%% (%d-%02d)\n", pct(report.WorstMonth.Return), report.WorstMonth.Year, report.WorstMonth.Month)
+	fmt.Printf("Best Year:             %.2f%% (%d)\n", pct(report.BestYear.Return), report.BestYear.Year)
+	fmt.Printf("Worst Year:            %.2f%% (%d)\n", pct(report.WorstYear.Return), report.WorstYear.Year)
+	fmt.Printf("Positive Months:       %.2f%%\n", pct(report.PositiveMonthsPercent))
+
+	for _, window := range report.Rolling {
+		if len(window.Points) == 0 {
+			continue
+		}
+		last := window.Points[len(window.Points)-1]
+		minSharpe, maxSharpe := last.Sharpe, last.Sharpe
+		maxDD := decimal.Zero
+		for _, p := range window.Points {
+			minSharpe = decimal.Min(minSharpe, p.Sharpe)
+			maxSharpe = decimal.Max(maxSharpe, p.Sharpe)
+			maxDD = decimal.Max(maxDD, p.MaxDrawdown)
+		}
+		fmt.Printf("Rolling %2dm:           Sharpe %.2f (min %.2f, max %.2f), Volatility %.2f%%, Worst Drawdown %.2f%%\n",
+			window.Months, last.Sharpe.InexactFloat64(), minSharpe.InexactFloat64(), maxSharpe.InexactFloat64(),
+			pct(last.Volatility), pct(maxDD))
+	}
+}
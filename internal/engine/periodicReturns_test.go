@@ -0,0 +1,142 @@
+package engine
+
+import (
+	"backtester/types"
+	"bytes"
+	"encoding/csv"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestMonthlyReturns(t *testing.T) {
+	base := time.Date(2020, 1, 15, 0, 0, 0, 0, time.UTC)
+	snapshots := []types.PortfolioView{
+		newPv(base, "1000"),
+		newPv(base.AddDate(0, 0, 10), "1100"),  // Jan 25
+		newPv(base.AddDate(0, 1, 0), "990"),    // Feb 15
+		newPv(base.AddDate(0, 11, 17), "1089"), // Jan 1 2021
+	}
+
+	got := monthlyReturns(snapshots)
+	want := []PeriodReturn{
+		{Year: 2020, Month: time.January, Return: decimal.RequireFromString("0.1")},
+		{Year: 2020, Month: time.February, Return: decimal.RequireFromString("-0.1")},
+		{Year: 2021, Month: time.January, Return: decimal.RequireFromString("0.1")},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d months, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i].Year != want[i].Year || got[i].Month != want[i].Month || !got[i].Return.Round(6).Equal(want[i].Return) {
+			t.Errorf("month %d: got=%d-%s %s, want=%d-%s %s", i, got[i].Year, got[i].Month, got[i].Return, want[i].Year, want[i].Month, want[i].Return)
+		}
+	}
+}
+
+func TestCalcPeriodicReturns(t *testing.T) {
+	base := time.Date(2020, 1, 31, 0, 0, 0, 0, time.UTC)
+	snapshots := []types.PortfolioView{
+		newPv(base, "1000"),
+		newPv(base.AddDate(0, 1, 0), "1200"), // Mar 2
+		newPv(base.AddDate(0, 2, 0), "900"),  // Mar 31
+		newPv(base.AddDate(1, 0, 0), "1800"), // Jan 31 2021
+	}
+
+	got := calcPeriodicReturns(snapshots, noopWaitGroup())
+
+	tests := []struct {
+		name       string
+		got        PeriodReturn
+		wantYear   int
+		wantMonth  time.Month
+		wantReturn string
+	}{
+		{"best month", got.bestMonth, 2021, time.January, "1"},
+		{"worst month", got.worstMonth, 2020, time.March, "-0.1"},
+		{"best year", got.bestYear, 2021, 0, "1"},
+		{"worst year", got.worstYear, 2020, 0, "-0.1"},
+	}
+	for _, tt := range tests {
+		if tt.got.Year != tt.wantYear || tt.got.Month != tt.wantMonth || !tt.got.Return.Round(6).Equal(decimal.RequireFromString(tt.wantReturn)) {
+			t.Errorf("%s: got=%+v, want %d-%d %s", tt.name, tt.got, tt.wantYear, tt.wantMonth, tt.wantReturn)
+		}
+	}
+	if len(got.yearly) != 2 {
+		t.Errorf("got %d years, want 2", len(got.yearly))
+	}
+	// January 2020 is flat, March 2020 is negative and February has no snapshot
+	if !got.positiveMonths.Mul(decimal.NewFromInt(3)).Round(6).Equal(decimal.NewFromInt(1)) {
+		t.Errorf("positive months: got=%s, want 1/3", got.positiveMonths)
+	}
+}
+
+func TestCalcRollingMetrics(t *testing.T) {
+	base := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	var snapshots []types.PortfolioView
+	for day := 0; day <= 400; day++ {
+		// Alternate gains and losses so volatility is not zero
+		value := 1000 + day + 5*(day%2)
+		snapshots = append(snapshots, newPv(base.AddDate(0, 0, day), decimal.NewFromInt(int64(value)).String()))
+	}
+
+	rolling := calcRollingMetrics(snapshots, decimal.Zero, calendarDaysPerYear, false, noopWaitGroup())
+	if len(rolling) != len(rollingWindowMonths) {
+		t.Fatalf("got %d windows, want %d", len(rolling), len(rollingWindowMonths))
+	}
+	for i, window := range rolling {
+		if window.Months != rollingWindowMonths[i] {
+			t.Errorf("window %d: got %d months, want %d", i, window.Months, rollingWindowMonths[i])
+		}
+		if len(window.Points) == 0 {
+			t.Fatalf("%d month window has no points", window.Months)
+		}
+		first := window.Points[0]
+		if first.Time.Before(base.AddDate(0, window.Months, 0)) {
+			t.Errorf("%d month window starts at %s before it is fully covered", window.Months, first.Time)
+		}
+		if !first.Volatility.IsPositive() || !first.Sharpe.IsPositive() || !first.MaxDrawdown.IsPositive() {
+			t.Errorf("%d month window: got %+v, want positive metrics", window.Months, first)
+		}
+	}
+	// Longer windows start later
+	if len(rolling[0].Points) <= len(rolling[2].Points) {
+		t.Errorf("3 month window has %d points, 12 month window %d", len(rolling[0].Points), len(rolling[2].Points))
+	}
+
+	if got := calcRollingMetrics(snapshots[:2], decimal.Zero, calendarDaysPerYear, false, noopWaitGroup()); got != nil {
+		t.Errorf("too few snapshots: got %d windows, want none", len(got))
+	}
+}
+
+func TestWriteReturnsCSV(t *testing.T) {
+	monthly := []PeriodReturn{
+		{Year: 2020, Month: time.November, Return: decimal.RequireFromString("0.01")},
+		{Year: 2020, Month: time.December, Return: decimal.RequireFromString("-0.02")},
+		{Year: 2021, Month: time.January, Return: decimal.RequireFromString("0.005")},
+	}
+	yearly := []PeriodReturn{
+		{Year: 2020, Return: decimal.RequireFromString("-0.0102")},
+		{Year: 2021, Return: decimal.RequireFromString("0.005")},
+	}
+
+	var buf bytes.Buffer
+	if err := writeReturnsCSV(&buf, monthly, yearly); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("invalid csv: %v", err)
+	}
+
+	if len(records) != 3 || len(records[0]) != 14 {
+		t.Fatalf("got %d rows with %d columns, want 3 rows with 14 columns", len(records), len(records[0]))
+	}
+	if records[1][0] != "2020" || records[1][11] != "1.00" || records[1][12] != "-2.00" || records[1][13] != "-1.02" || records[1][1] != "" {
+		t.Errorf("2020 row: got %v", records[1])
+	}
+	if records[2][1] != "0.50" || records[2][13] != "0.50" {
+		t.Errorf("2021 row: got %v", records[2])
+	}
+}
@@ -17,12 +17,19 @@ type portfolio struct {
 	cash              decimal.Decimal
 	positions         map[string]*Position
 	executions        []types.ExecutionReport
+	rejections        []types.ExecutionReport
 	realizedPnL       decimal.Decimal
 	snapshots         []types.PortfolioView
+	cashFlows         []CashFlow
+	netContributions  decimal.Decimal
 	backtesterApi     backtesterApi
 	allowShortSelling bool
 }
 
+func (p *portfolio) shortSellingAllowed() bool {
+	return p.allowShortSelling
+}
+
 func (p *portfolio) GetExecutionReportsForTicker(ticker string) []types.ExecutionReport {
 	var reports []types.ExecutionReport
 	for _, report := range p.executions {
@@ -45,6 +52,22 @@ func (p *portfolio) GetExecutionReportsForTicker(ticker string) []types.Executio
 	return reports
 }
 
+// GetLastPrice returns the close of the last completed primary candle for the ticker, also when it is not held.
+func (p *portfolio) GetLastPrice(ticker string) decimal.Decimal {
+	return p.backtesterApi.getLastPriceForTicker(ticker)
+}
+
+// GetCandles returns the last n completed primary candles of the ticker, oldest first, or all of them
+// when n is not positive. The candles are shared with the engine and must not be modified.
+func (p *portfolio) GetCandles(ticker string, n int) []types.Candle {
+	return p.backtesterApi.getCandlesForTicker(ticker, n)
+}
+
+// GetTickers returns the tickers of every instrument of the run.
+func (p *portfolio) GetTickers() []string {
+	return p.backtesterApi.getTickers()
+}
+
 type Position struct {
 	Ticker             string
 	Quantity           decimal.Decimal
@@ -65,6 +88,8 @@ func (p *portfolio) GetPortfolioSnapshot() types.PortfolioView {
 		Cash:      p.cash,
 		Positions: make(map[string]types.PositionSnapshot),
 		Time:      p.backtesterApi.getCurrentTime(),
+
+		NetContributions: p.netContributions,
 	}
 
 	for sym, pos := range p.positions {
@@ -89,6 +114,10 @@ func (p *portfolio) processExecutions(execs []types.ExecutionReport) error {
 	})
 
 	for _, er := range execs {
+		if er.Status == types.OrderRejected {
+			p.rejections = append(p.rejections, er)
+			continue
+		}
 		if len(er.Fills) == 0 {
 			continue
 		}
@@ -179,6 +208,37 @@ func (p *portfolio) processExecutions(execs []types.ExecutionReport) error {
 	return nil
 }
 
+// applyCashFlow books an external deposit or withdrawal against cash.
+// Withdrawals never sell positions, so they are capped at the cash currently available.
+func (p *portfolio) applyCashFlow(flow scheduledCashFlow) error {
+	if flow.amount.IsNegative() {
+		return NegativeCashFlowErr
+	}
+
+	var amount decimal.Decimal
+	switch flow.flowType {
+	case CashFlowDeposit:
+		amount = flow.amount
+	case CashFlowWithdrawal:
+		amount = decimal.Min(flow.amount, p.cash).Neg()
+	case CashFlowPercentWithdrawal:
+		value := portfolioValue(p.GetPortfolioSnapshot())
+		amount = decimal.Min(value.Mul(flow.amount), p.cash).Neg()
+	}
+	if amount.IsZero() {
+		return nil
+	}
+
+	p.cash = p.cash.Add(amount)
+	p.netContributions = p.netContributions.Add(amount)
+	p.cashFlows = append(p.cashFlows, CashFlow{
+		Type:   flow.flowType,
+		Amount: amount,
+		Time:   flow.time,
+	})
+	return nil
+}
+
 func sameSide(a, b decimal.Decimal) bool {
 	return (a.GreaterThan(decimal.Zero) && b.GreaterThan(decimal.Zero)) ||
 		(a.LessThan(decimal.Zero) && b.LessThan(decimal.Zero))
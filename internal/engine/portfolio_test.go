@@ -535,6 +535,112 @@ func TestPortfolioProcessExecutions(t *testing.T) {
 	}
 }
 
+func TestPortfolioGetCandles(t *testing.T) {
+	feed := Instrument("AAPL", time.UnixMilli(0), time.UnixMilli(5), testInterval)
+	feed.primary.candles = mockCandles(0, 5, 1)
+	bt := &backtester{
+		instruments:         []*InstrumentConfig{feed},
+		instrumentFeedIndex: map[string]int{"AAPL": 3},
+	}
+	p := newPortfolio(decimal.NewFromInt(1000), false)
+	p.backtesterApi = bt
+
+	tests := []struct {
+		name   string
+		ticker string
+		n      int
+		wantTS []int64
+	}{
+		{name: "only completed candles", ticker: "AAPL", n: 0, wantTS: []int64{0, 1, 2}},
+		{name: "last n candles", ticker: "AAPL", n: 2, wantTS: []int64{1, 2}},
+		{name: "unknown ticker", ticker: "MSFT", n: 2},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := p.GetCandles(tc.ticker, tc.n)
+			if len(got) != len(tc.wantTS) {
+				t.Fatalf("got %d candles, want %d", len(got), len(tc.wantTS))
+			}
+			for i, ts := range tc.wantTS {
+				if got[i].Timestamp.UnixMilli() != ts {
+					t.Errorf("candle %d at %d, want %d", i, got[i].Timestamp.UnixMilli(), ts)
+				}
+			}
+		})
+	}
+	if tickers := p.GetTickers(); len(tickers) != 1 || tickers[0] != "AAPL" {
+		t.Errorf("GetTickers() = %v, want [AAPL]", tickers)
+	}
+}
+
+func TestPortfolioApplyCashFlow(t *testing.T) {
+	at := time.UnixMilli(0)
+
+	tests := []struct {
+		name     string
+		cash     string
+		flow     scheduledCashFlow
+		wantCash string
+		wantNet  string
+		wantErr  error
+	}{
+		{
+			name:     "deposit adds cash",
+			cash:     "1000",
+			flow:     scheduledCashFlow{flowType: CashFlowDeposit, amount: decimal.NewFromInt(500), time: at},
+			wantCash: "1500",
+			wantNet:  "500",
+		},
+		{
+			name:     "withdrawal removes cash",
+			cash:     "1000",
+			flow:     scheduledCashFlow{flowType: CashFlowWithdrawal, amount: decimal.NewFromInt(400), time: at},
+			wantCash: "600",
+			wantNet:  "-400",
+		},
+		{
+			name:     "withdrawal is capped at available cash",
+			cash:     "300",
+			flow:     scheduledCashFlow{flowType: CashFlowWithdrawal, amount: decimal.NewFromInt(400), time: at},
+			wantCash: "0",
+			wantNet:  "-300",
+		},
+		{
+			name:     "percent withdrawal uses portfolio value",
+			cash:     "2000",
+			flow:     scheduledCashFlow{flowType: CashFlowPercentWithdrawal, amount: decimal.RequireFromString("0.04"), time: at},
+			wantCash: "1920",
+			wantNet:  "-80",
+		},
+		{
+			name:     "negative amount is rejected",
+			cash:     "1000",
+			flow:     scheduledCashFlow{flowType: CashFlowDeposit, amount: decimal.NewFromInt(-1), time: at},
+			wantCash: "1000",
+			wantNet:  "0",
+			wantErr:  NegativeCashFlowErr,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			p := newPortfolio(decimal.RequireFromString(tc.cash), false)
+			p.backtesterApi = &backtester{}
+
+			err := p.applyCashFlow(tc.flow)
+			if err != tc.wantErr {
+				t.Fatalf("got error %v, want %v", err, tc.wantErr)
+			}
+			if !p.cash.Equal(decimal.RequireFromString(tc.wantCash)) {
+				t.Errorf("cash = %s, want %s", p.cash, tc.wantCash)
+			}
+			if !p.netContributions.Equal(decimal.RequireFromString(tc.wantNet)) {
+				t.Errorf("net contributions = %s, want %s", p.netContributions, tc.wantNet)
+			}
+		})
+	}
+}
+
 func TestWeightedAvgPrice(t *testing.T) {
 	tests := []struct {
 		name             string
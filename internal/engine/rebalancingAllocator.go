@@ -0,0 +1,224 @@
+package engine
+
+import (
+	"backtester/types"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// RebalancingAllocator trades the portfolio towards target weights per ticker.
+// Targets come from a schedule (replacing all targets) or from signals carrying a TargetWeight (updating one ticker).
+// Held tickers without a target have a target of zero. Negative targets are shorts when the portfolio
+// allows short selling and a target of zero otherwise. Sells are emitted before buys and buys are
+// capped to the cash available after the sells.
+type RebalancingAllocator struct {
+	api         PortfolioApi
+	allowShorts bool
+
+	driftThreshold decimal.Decimal
+	minTradeValue  decimal.Decimal
+	lotSize        decimal.Decimal
+	onDrift        bool
+
+	targets       map[string]decimal.Decimal
+	schedule      []rebalanceTargets
+	scheduleIndex int
+	targetsDirty  bool
+}
+
+type rebalanceTargets struct {
+	at      time.Time
+	weights map[string]decimal.Decimal
+}
+
+// NewRebalancingAllocator creates an allocator that leaves tickers alone while their weight is within
+// driftThreshold (absolute, 0.05 = 5 percentage points) of the target, and skips trades below minTradeValue.
+func NewRebalancingAllocator(driftThreshold, minTradeValue decimal.Decimal) *RebalancingAllocator {
+	return &RebalancingAllocator{
+		driftThreshold: driftThreshold,
+		minTradeValue:  minTradeValue,
+		targets:        make(map[string]decimal.Decimal),
+	}
+}
+
+// AddTargets replaces all target weights at the given time.
+func (a *RebalancingAllocator) AddTargets(at time.Time, weights map[string]decimal.Decimal) *RebalancingAllocator {
+	a.schedule = append(a.schedule, rebalanceTargets{at: at, weights: weights})
+	sort.SliceStable(a.schedule, func(i, j int) bool {
+		return a.schedule[i].at.Before(a.schedule[j].at)
+	})
+	return a
+}
+
+// RebalanceEveryMonths schedules the same target weights every n months from start up to and including until.
+func (a *RebalancingAllocator) RebalanceEveryMonths(n int, start, until time.Time, weights map[string]decimal.Decimal) *RebalancingAllocator {
+	for i := 0; n > 0; i++ {
+		at := start.AddDate(0, i*n, 0)
+		if at.After(until) {
+			break
+		}
+		a.AddTargets(at, weights)
+	}
+	return a
+}
+
+// RebalanceOnDrift also rebalances between target updates whenever a ticker drifts beyond the threshold.
+func (a *RebalancingAllocator) RebalanceOnDrift() *RebalancingAllocator {
+	a.onDrift = true
+	return a
+}
+
+// LotSize sets the smallest tradable quantity. Zero (the default) trades whole units.
+func (a *RebalancingAllocator) LotSize(lot decimal.Decimal) *RebalancingAllocator {
+	a.lotSize = lot
+	return a
+}
+
+// shortSeller is a portfolio that tells whether positions may go short.
+type shortSeller interface {
+	shortSellingAllowed() bool
+}
+
+func (a *RebalancingAllocator) Init(api PortfolioApi) error {
+	a.api = api
+	if portfolio, ok := api.(shortSeller); ok {
+		a.allowShorts = portfolio.shortSellingAllowed()
+	}
+	return nil
+}
+
+func (a *RebalancingAllocator) Allocate(signals map[string][]types.Signal, view types.PortfolioView) []types.Order {
+	for a.scheduleIndex < len(a.schedule) && !a.schedule[a.scheduleIndex].at.After(view.Time) {
+		a.targets = make(map[string]decimal.Decimal, len(a.schedule[a.scheduleIndex].weights))
+		for ticker, w := range a.schedule[a.scheduleIndex].weights {
+			a.targets[ticker] = w
+		}
+		a.targetsDirty = true
+		a.scheduleIndex++
+	}
+
+	prices := make(map[string]decimal.Decimal)
+	for ticker, sigs := range signals {
+		for _, sig := range sigs {
+			if !sig.TargetWeight.Valid {
+				continue
+			}
+			a.targets[ticker] = sig.TargetWeight.Decimal
+			a.targetsDirty = true
+			if sig.Price.IsPositive() {
+				prices[ticker] = sig.Price
+			}
+		}
+	}
+
+	if !a.targetsDirty && !a.onDrift {
+		return nil
+	}
+
+	equity := portfolioValue(view)
+	if !equity.IsPositive() {
+		return nil
+	}
+
+	tickers := make(map[string]struct{}, len(a.targets)+len(view.Positions))
+	for ticker := range a.targets {
+		tickers[ticker] = struct{}{}
+	}
+	for ticker, pos := range view.Positions {
+		if !pos.Quantity.IsZero() {
+			tickers[ticker] = struct{}{}
+		}
+	}
+	sorted := make([]string, 0, len(tickers))
+	for ticker := range tickers {
+		sorted = append(sorted, ticker)
+	}
+	sort.Strings(sorted)
+
+	var sells, buys []types.Order
+	cash := view.Cash
+	missingPrice := false
+	for _, ticker := range sorted {
+		pos := view.Positions[ticker]
+		price := a.priceFor(ticker, pos, prices)
+		if !price.IsPositive() {
+			missingPrice = true
+			continue
+		}
+
+		target := a.targets[ticker]
+		if target.IsNegative() && !a.allowShorts {
+			target = decimal.Zero
+		}
+		curValue := pos.Quantity.Mul(price)
+		drift := curValue.Div(equity).Sub(target)
+		if drift.IsZero() || drift.Abs().LessThanOrEqual(a.driftThreshold) {
+			continue
+		}
+
+		tradeValue := drift.Abs().Mul(equity)
+		if tradeValue.LessThan(a.minTradeValue) {
+			continue
+		}
+
+		reason := fmt.Sprintf("Rebalance to target weight %s", target.String())
+		if drift.IsPositive() {
+			qty := a.roundToLot(tradeValue.Div(price))
+			// Close out completely rather than leaving a rounding residue, shorts sell through zero
+			if target.IsZero() || (!target.IsNegative() && qty.GreaterThan(pos.Quantity)) {
+				qty = pos.Quantity
+			}
+			if !qty.IsPositive() {
+				continue
+			}
+			sells = append(sells, types.NewOrder(ticker, price, qty, types.TypeMarket, types.SideTypeSell, reason, view.Time))
+			cash = cash.Add(qty.Mul(price))
+			continue
+		}
+
+		buys = append(buys, types.NewOrder(ticker, price, a.roundToLot(tradeValue.Div(price)), types.TypeMarket, types.SideTypeBuy, reason, view.Time))
+	}
+
+	// Retry on the next bar for tickers that had no price yet
+	a.targetsDirty = missingPrice
+
+	orders := sells
+	for _, order := range buys {
+		maxQty := a.roundToLot(cash.Div(order.Price))
+		if order.Quantity.GreaterThan(maxQty) {
+			order.Quantity = maxQty
+		}
+		if !order.Quantity.IsPositive() {
+			continue
+		}
+		cash = cash.Sub(order.Quantity.Mul(order.Price))
+		orders = append(orders, order)
+	}
+	return orders
+}
+
+func (a *RebalancingAllocator) priceFor(ticker string, pos types.PositionSnapshot, signalPrices map[string]decimal.Decimal) decimal.Decimal {
+	if price, ok := signalPrices[ticker]; ok {
+		return price
+	}
+	if pos.LastMarketPrice.IsPositive() {
+		return pos.LastMarketPrice
+	}
+	if a.api != nil {
+		return a.api.GetLastPrice(ticker)
+	}
+	return decimal.Zero
+}
+
+func (a *RebalancingAllocator) roundToLot(qty decimal.Decimal) decimal.Decimal {
+	if !qty.IsPositive() {
+		return decimal.Zero
+	}
+	if !a.lotSize.IsPositive() {
+		return qty.Floor()
+	}
+	return qty.Div(a.lotSize).Floor().Mul(a.lotSize)
+}
@@ -0,0 +1,182 @@
+package engine
+
+import (
+	"backtester/types"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestRebalancingAllocator_Allocate(t *testing.T) {
+	base := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	weights := func(kv ...string) map[string]decimal.Decimal {
+		out := make(map[string]decimal.Decimal)
+		for i := 0; i < len(kv); i += 2 {
+			out[kv[i]] = decimal.RequireFromString(kv[i+1])
+		}
+		return out
+	}
+
+	shorting := func(a *RebalancingAllocator) *RebalancingAllocator {
+		_ = a.Init(newPortfolio(decimal.Zero, true))
+		return a
+	}
+
+	type wantOrder struct {
+		ticker string
+		side   types.Side
+		qty    string
+	}
+
+	tests := []struct {
+		name      string
+		allocator *RebalancingAllocator
+		view      types.PortfolioView
+		signals   map[string][]types.Signal
+		want      []wantOrder
+	}{
+		{
+			name:      "no targets yet -> no orders",
+			allocator: NewRebalancingAllocator(decimal.Zero, decimal.Zero).AddTargets(base.AddDate(0, 1, 0), weights("AAA", "1")),
+			view:      pvWithPosition(base, "1000", "AAA", "0", "10"),
+			want:      nil,
+		},
+		{
+			name:      "scheduled targets sell before buy",
+			allocator: NewRebalancingAllocator(decimal.Zero, decimal.Zero).AddTargets(base, weights("AAA", "0.2", "BBB", "0.5")),
+			// equity 10000: AAA 8000 -> 2000, BBB 0 -> 5000
+			view: withPosition(pvWithPosition(base, "2000", "AAA", "80", "100"), "BBB", "0", "50"),
+			want: []wantOrder{
+				{"AAA", types.SideTypeSell, "60"},
+				{"BBB", types.SideTypeBuy, "100"},
+			},
+		},
+		{
+			name:      "held ticker without target is closed",
+			allocator: NewRebalancingAllocator(decimal.Zero, decimal.Zero).AddTargets(base, weights("BBB", "0")),
+			view:      pvWithPosition(base, "0", "AAA", "3", "7"),
+			want: []wantOrder{
+				{"AAA", types.SideTypeSell, "3"},
+			},
+		},
+		{
+			name:      "drift within threshold is left alone",
+			allocator: NewRebalancingAllocator(decimal.RequireFromString("0.05"), decimal.Zero).AddTargets(base, weights("AAA", "0.5", "BBB", "0.5")),
+			// AAA at 0.53, BBB at 0.40
+			view: withPosition(pvWithPosition(base, "700", "AAA", "53", "100"), "BBB", "40", "100"),
+			want: []wantOrder{
+				{"BBB", types.SideTypeBuy, "7"},
+			},
+		},
+		{
+			name:      "trades below the minimum value are skipped",
+			allocator: NewRebalancingAllocator(decimal.Zero, decimal.NewFromInt(500)).AddTargets(base, weights("AAA", "0.5", "BBB", "0.5")),
+			view:      withPosition(pvWithPosition(base, "700", "AAA", "53", "100"), "BBB", "40", "100"),
+			want: []wantOrder{
+				{"BBB", types.SideTypeBuy, "7"},
+			},
+		},
+		{
+			name:      "buys are capped by cash after sells",
+			allocator: NewRebalancingAllocator(decimal.Zero, decimal.Zero).AddTargets(base, weights("AAA", "0.9", "BBB", "0.9")),
+			view:      withPosition(pvWithPosition(base, "1000", "AAA", "0", "100"), "BBB", "0", "100"),
+			want: []wantOrder{
+				{"AAA", types.SideTypeBuy, "9"},
+				{"BBB", types.SideTypeBuy, "1"},
+			},
+		},
+		{
+			name:      "target weight signal updates a single ticker",
+			allocator: NewRebalancingAllocator(decimal.Zero, decimal.Zero),
+			view:      newPv(base, "1000"),
+			signals: map[string][]types.Signal{
+				"AAA": {types.NewTargetWeightSignal("AAA", decimal.RequireFromString("0.25"), decimal.NewFromInt(10), "", base)},
+			},
+			want: []wantOrder{
+				{"AAA", types.SideTypeBuy, "25"},
+			},
+		},
+		{
+			name:      "negative target opens a short",
+			allocator: shorting(NewRebalancingAllocator(decimal.Zero, decimal.Zero).AddTargets(base, weights("AAA", "-0.2"))),
+			view:      pvWithPosition(base, "1000", "AAA", "0", "10"),
+			want: []wantOrder{
+				{"AAA", types.SideTypeSell, "20"},
+			},
+		},
+		{
+			name:      "negative target increases a short",
+			allocator: shorting(NewRebalancingAllocator(decimal.Zero, decimal.Zero).AddTargets(base, weights("AAA", "-0.3"))),
+			// equity 1000 with AAA at -0.1
+			view: pvWithPosition(base, "1100", "AAA", "-10", "10"),
+			want: []wantOrder{
+				{"AAA", types.SideTypeSell, "20"},
+			},
+		},
+		{
+			name:      "negative target reverses a long",
+			allocator: shorting(NewRebalancingAllocator(decimal.Zero, decimal.Zero).AddTargets(base, weights("AAA", "-0.2"))),
+			view:      pvWithPosition(base, "500", "AAA", "50", "10"),
+			want: []wantOrder{
+				{"AAA", types.SideTypeSell, "70"},
+			},
+		},
+		{
+			name:      "negative target closes a long without short selling",
+			allocator: NewRebalancingAllocator(decimal.Zero, decimal.Zero).AddTargets(base, weights("AAA", "-0.5")),
+			view:      pvWithPosition(base, "0", "AAA", "3", "7"),
+			want: []wantOrder{
+				{"AAA", types.SideTypeSell, "3"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.allocator.Allocate(tt.signals, tt.view)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d orders, want %d: %v", len(got), len(tt.want), got)
+			}
+			for i, want := range tt.want {
+				if got[i].Ticker != want.ticker || got[i].Side != want.side || !got[i].Quantity.Equal(decimal.RequireFromString(want.qty)) {
+					t.Errorf("order %d = %s %s %s, want %s %s %s", i, got[i].Ticker, got[i].Side, got[i].Quantity, want.ticker, want.side, want.qty)
+				}
+			}
+		})
+	}
+}
+
+func TestRebalancingAllocator_OnlyRebalancesWhenDue(t *testing.T) {
+	base := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	targets := map[string]decimal.Decimal{"AAA": decimal.RequireFromString("0.5")}
+
+	a := NewRebalancingAllocator(decimal.Zero, decimal.Zero).RebalanceEveryMonths(1, base, base.AddDate(0, 2, 0), targets)
+	if len(a.schedule) != 3 {
+		t.Fatalf("expected 3 scheduled rebalances, got %d", len(a.schedule))
+	}
+
+	if orders := a.Allocate(nil, pvWithPosition(base, "1000", "AAA", "0", "10")); len(orders) != 1 {
+		t.Fatalf("expected a rebalance at the first scheduled date, got %v", orders)
+	}
+	// Drifted but not scheduled
+	if orders := a.Allocate(nil, pvWithPosition(base.AddDate(0, 0, 1), "1000", "AAA", "0", "10")); len(orders) != 0 {
+		t.Fatalf("expected no orders between scheduled dates, got %v", orders)
+	}
+
+	a.RebalanceOnDrift()
+	if orders := a.Allocate(nil, pvWithPosition(base.AddDate(0, 0, 2), "1000", "AAA", "0", "10")); len(orders) != 1 {
+		t.Fatalf("expected drift to trigger a rebalance, got %v", orders)
+	}
+}
+
+// Helper functions
+func withPosition(pv types.PortfolioView, ticker, qty, price string) types.PortfolioView {
+	pv.Positions[ticker] = types.PositionSnapshot{
+		Ticker:          ticker,
+		Quantity:        decimal.RequireFromString(qty),
+		AvgEntryPrice:   decimal.RequireFromString(price),
+		LastMarketPrice: decimal.RequireFromString(price),
+	}
+	return pv
+}
@@ -0,0 +1,182 @@
+package engine
+
+import (
+	"backtester/types"
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+var (
+	EmptyJournalErr = errors.New("journal has no events")
+	MixedJournalErr = errors.New("journal holds several runs")
+)
+
+// NewReplayEngine returns an engine that rebuilds the report of a journaled run, see Engine.Replay.
+// A journal file that several runs appended to is replayed one run at a time, picked with WithRunID.
+func NewReplayEngine(journal io.Reader, reportingConfig *ReportingConfig) *Engine {
+	// Short selling is allowed since the journaled run already checked every fill
+	portfolioConfig := NewPortfolioConfig(decimal.Zero, true)
+	executionConfig := NewExecutionConfig(types.OneMinute, 0, 0)
+	initPortfolio := newPortfolio(portfolioConfig.initialCash, portfolioConfig.allowShortSelling)
+	backtester := &backtester{
+		executionConfig: executionConfig,
+		portfolioConfig: portfolioConfig,
+		signalResolver:  newSignalResolver(ResolvePassThrough),
+		portfolio:       initPortfolio,
+	}
+	initPortfolio.backtesterApi = backtester
+
+	return &Engine{
+		executionConfig: executionConfig,
+		portfolioConfig: portfolioConfig,
+		reportingConfig: reportingConfig,
+		portfolio:       initPortfolio,
+		backtester:      backtester,
+		assets:          make(map[string]*types.Asset),
+		replay:          journal,
+		logger:          slog.New(slog.NewTextHandler(os.Stdout, nil)),
+	}
+}
+
+// Replay rebuilds the portfolio from the execution reports, cash flows and snapshots of the journal
+// and generates the report without the data store or the strategy. The journaled primary candles
+// stand in for the execution candles, so bars, MAE and MFE of trades are measured on those. Without
+// a data store there is no benchmark and the Monte Carlo analysis is skipped.
+func (e *Engine) Replay() (*Report, error) {
+	e.logger.Info("Replaying journal")
+	start, end, err := e.readJournal()
+	if err != nil {
+		e.logger.Error("Failed to read journal", slog.Any("error", err))
+		return nil, err
+	}
+	e.monteCarlo = nil
+
+	report, err := e.buildReport(start, end)
+	if err != nil {
+		return nil, err
+	}
+	e.logger.Info("Journal replayed successfully", slog.String("run_id", e.runID))
+
+	if !e.quiet {
+		e.printReport(report)
+	}
+	return report, nil
+}
+
+type journalLine struct {
+	RunID  string          `json:"run_id"`
+	Time   time.Time       `json:"time"`
+	Type   EventType       `json:"type"`
+	Ticker string          `json:"ticker"`
+	Data   json.RawMessage `json:"data"`
+}
+
+// readJournal fills the portfolio from the journal and returns the simulated period of the run.
+// Execution reports are processed per simulated minute, the way the run processed them. When a run ID
+// is set only the events of that run are read, otherwise the journal must hold a single run.
+func (e *Engine) readJournal() (time.Time, time.Time, error) {
+	var start, end time.Time
+	var executions []types.ExecutionReport
+	events := 0
+	selected := e.runID != ""
+
+	scanner := bufio.NewScanner(e.replay)
+	// Snapshots of large portfolios make long lines
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var line journalLine
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			return start, end, fmt.Errorf("journal event %d: %w", events+1, err)
+		}
+		switch {
+		case selected && line.RunID != e.runID:
+			continue
+		case events > 0 && line.RunID != e.runID:
+			return start, end, fmt.Errorf("%w: %s and %s, pick one by its run ID", MixedJournalErr, e.runID, line.RunID)
+		}
+		events++
+		if start.IsZero() {
+			start = line.Time
+		}
+		if !line.Time.Equal(end) {
+			if err := e.portfolio.processExecutions(executions); err != nil {
+				return start, end, err
+			}
+			executions = nil
+		}
+		end = line.Time
+		e.runID = line.RunID
+
+		var err error
+		switch line.Type {
+		case EventRun:
+			var run JournalRun
+			if err = json.Unmarshal(line.Data, &run); err == nil {
+				start = run.Start
+				e.portfolioConfig.initialCash = run.InitialCash
+				e.portfolio.cash = run.InitialCash
+				for ticker, asset := range run.Assets {
+					e.assets[ticker] = asset
+				}
+				if !run.DailySnapshots {
+					e.backtester.snapshotFrequency = SnapshotOnFill()
+				}
+			}
+		case EventCandle:
+			var candle types.Candle
+			if err = json.Unmarshal(line.Data, &candle); err == nil {
+				e.backtester.executionConfig.candles[line.Ticker] = append(e.backtester.executionConfig.candles[line.Ticker], candle)
+			}
+		case EventSignalDropped:
+			var dropped DroppedSignal
+			if err = json.Unmarshal(line.Data, &dropped); err == nil {
+				e.backtester.signalResolver.dropped = append(e.backtester.signalResolver.dropped, dropped)
+			}
+		case EventExecution, EventRiskRejection:
+			var execution types.ExecutionReport
+			if err = json.Unmarshal(line.Data, &execution); err == nil {
+				executions = append(executions, execution)
+			}
+		case EventCashFlow:
+			var flow CashFlow
+			if err = json.Unmarshal(line.Data, &flow); err == nil {
+				e.portfolio.cash = e.portfolio.cash.Add(flow.Amount)
+				e.portfolio.cashFlows = append(e.portfolio.cashFlows, flow)
+			}
+		case EventSnapshot:
+			var snapshot types.PortfolioView
+			if err = json.Unmarshal(line.Data, &snapshot); err == nil {
+				e.portfolio.snapshots = append(e.portfolio.snapshots, snapshot)
+			}
+		}
+		if err != nil {
+			return start, end, fmt.Errorf("journal event %d (%s): %w", events, line.Type, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return start, end, fmt.Errorf("read journal: %w", err)
+	}
+	if events == 0 && selected {
+		return start, end, fmt.Errorf("%w: no run %s", EmptyJournalErr, e.runID)
+	}
+	if events == 0 {
+		return start, end, EmptyJournalErr
+	}
+
+	e.backtester.start, e.backtester.end, e.backtester.curTime = start, end, end
+	if err := e.portfolio.processExecutions(executions); err != nil {
+		return start, end, err
+	}
+	return start, end, nil
+}
@@ -0,0 +1,164 @@
+package engine
+
+import (
+	"backtester/types"
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// alternatingStrategy buys on even candles and sells on odd ones.
+type alternatingStrategy struct {
+	candles int
+}
+
+func (s *alternatingStrategy) Init(api PortfolioApi) error {
+	return nil
+}
+
+func (s *alternatingStrategy) OnCandle(candle types.Candle, contexts map[types.Interval][]types.Candle) []types.Signal {
+	side := types.SideTypeBuy
+	if s.candles%2 == 1 {
+		side = types.SideTypeSell
+	}
+	s.candles++
+	return []types.Signal{{Ticker: "AAPL", Side: side, Price: candle.Close, Reason: "alternate", CreatedAt: candle.Timestamp}}
+}
+
+// signalAllocator turns every signal into a small market order.
+type signalAllocator struct{}
+
+func (a *signalAllocator) Init(api PortfolioApi) error {
+	return nil
+}
+
+func (a *signalAllocator) Allocate(signals map[string][]types.Signal, view types.PortfolioView) []types.Order {
+	var orders []types.Order
+	for _, sigs := range signals {
+		for _, s := range sigs {
+			orders = append(orders, types.NewOrder(s.Ticker, s.Price, decimal.RequireFromString("0.0001"), types.TypeMarket, s.Side, s.Reason, s.CreatedAt))
+		}
+	}
+	return orders
+}
+
+// fillingBroker fills every order completely at its price.
+type fillingBroker struct{}
+
+func (b *fillingBroker) Execute(orders []types.Order, ctx types.ExecutionContext) []types.ExecutionReport {
+	var reports []types.ExecutionReport
+	for _, o := range orders {
+		fee := decimal.RequireFromString("0.5")
+		fill := types.NewFill(ctx.CurTime, o.Price, o.Quantity, fee)
+		reports = append(reports, *types.NewExecutionReport(o.Ticker, o.Side, types.OrderFilled, []types.Fill{fill}, o.Quantity, o.Price, fee, decimal.Zero, o.SignalReason, "", ctx.CurTime))
+	}
+	return reports
+}
+
+func TestReplay(t *testing.T) {
+	start := time.UnixMilli(0).UTC()
+	feeds := Instruments(Instrument("AAPL", start, start.Add(10*24*time.Hour), types.Hour))
+
+	var journal bytes.Buffer
+	engine := mockEngine(&alternatingStrategy{}, feeds, &signalAllocator{}, &fillingBroker{}).
+		WithJournal(NewJSONLJournal(&journal)).
+		Quiet()
+	want, err := engine.Run()
+	if err != nil {
+		t.Fatalf("Error running engine: %v", err)
+	}
+	if want.TotalTrades == 0 {
+		t.Fatalf("the run made no trades")
+	}
+
+	// Writing the JSON report reads the engine configs, which a replay engine rebuilds from the journal
+	got, err := NewReplayEngine(&journal, (&ReportingConfig{filePath: t.TempDir(), reportName: "replay"}).JSONReport()).Quiet().Replay()
+	if err != nil {
+		t.Fatalf("Error replaying journal: %v", err)
+	}
+
+	if got.RunID != want.RunID || got.TotalTrades != want.TotalTrades || len(got.Trades) != len(want.Trades) {
+		t.Fatalf("got run=%s trades=%d/%d, want run=%s trades=%d/%d",
+			got.RunID, got.TotalTrades, len(got.Trades), want.RunID, want.TotalTrades, len(want.Trades))
+	}
+	if got.TotalPeriod != want.TotalPeriod || got.AnnualizationPeriods != want.AnnualizationPeriods {
+		t.Errorf("got period=%s periods=%d, want %s and %d", got.TotalPeriod, got.AnnualizationPeriods, want.TotalPeriod, want.AnnualizationPeriods)
+	}
+	for name, tt := range map[string]struct {
+		got, want decimal.Decimal
+	}{
+		"net profit":   {got.NetProfit, want.NetProfit},
+		"fees":         {got.TotalFees, want.TotalFees},
+		"cagr":         {got.CAGR, want.CAGR},
+		"max drawdown": {got.MaxDrawdownPercent, want.MaxDrawdownPercent},
+		"sortino":      {got.SortinoRatio, want.SortinoRatio},
+		"exposure":     {got.AvgGrossExposure, want.AvgGrossExposure},
+		"last trade":   {got.Trades[len(got.Trades)-1].NetPnL, want.Trades[len(want.Trades)-1].NetPnL},
+		"mfe":          {got.Trades[0].MFE, want.Trades[0].MFE},
+	} {
+		if !tt.got.Equal(tt.want) {
+			t.Errorf("%s: got=%s, want=%s", name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestReplay_AppendedRuns(t *testing.T) {
+	var journal bytes.Buffer
+	runs := make([]*Report, 2)
+	for i, id := range []string{"run-1", "run-2"} {
+		report, err := mockEngine(&alternatingStrategy{}, mockInstrument(), &signalAllocator{}, &fillingBroker{}).
+			WithJournal(NewJSONLJournal(&journal)).
+			WithRunID(id).
+			Quiet().
+			Run()
+		if err != nil {
+			t.Fatalf("Error running engine: %v", err)
+		}
+		runs[i] = report
+	}
+
+	for _, want := range runs {
+		got, err := NewReplayEngine(bytes.NewReader(journal.Bytes()), &ReportingConfig{}).WithRunID(want.RunID).Quiet().Replay()
+		if err != nil {
+			t.Fatalf("Error replaying %s: %v", want.RunID, err)
+		}
+		if got.RunID != want.RunID || got.TotalTrades != want.TotalTrades || !got.NetProfit.Equal(want.NetProfit) {
+			t.Errorf("got run=%s trades=%d profit=%s, want run=%s trades=%d profit=%s",
+				got.RunID, got.TotalTrades, got.NetProfit, want.RunID, want.TotalTrades, want.NetProfit)
+		}
+	}
+	if _, err := NewReplayEngine(bytes.NewReader(journal.Bytes()), &ReportingConfig{}).WithRunID("run-3").Quiet().Replay(); !errors.Is(err, EmptyJournalErr) {
+		t.Errorf("got err=%v, want %v", err, EmptyJournalErr)
+	}
+	if _, err := NewReplayEngine(bytes.NewReader(journal.Bytes()), &ReportingConfig{}).Quiet().Replay(); !errors.Is(err, MixedJournalErr) {
+		t.Errorf("got err=%v, want %v", err, MixedJournalErr)
+	}
+}
+
+func TestReplay_InvalidJournal(t *testing.T) {
+	tests := []struct {
+		name    string
+		journal string
+		wantErr error
+	}{
+		{"empty", "", EmptyJournalErr},
+		{"not json", "{\n", nil},
+		{"bad snapshot", `{"run_id":"a","type":"SNAPSHOT","data":{"Cash":"x"}}`, nil},
+		{"mixed runs", `{"run_id":"a","type":"RUN_END"}` + "\n" + `{"run_id":"b","type":"RUN_END"}`, MixedJournalErr},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewReplayEngine(strings.NewReader(tt.journal), &ReportingConfig{}).Quiet().Replay()
+			if err == nil {
+				t.Fatalf("expected an error")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("got err=%v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"backtester/types"
 	"fmt"
 	"math"
+	"slices"
 	"sort"
 	"sync"
 	"time"
@@ -12,44 +13,118 @@ import (
 )
 
 type Report struct {
+	RunID string `json:"run_id"`
+
 	// Meta / period info
-	StartDate   time.Time
-	TotalPeriod time.Duration
-	TotalTrades int
+	StartDate   time.Time     `json:"start_date"`
+	TotalPeriod time.Duration `json:"-"`
+	TotalTrades int           `json:"total_trades"`
+
+	// Signals the resolver removed before they reached the allocator
+	DroppedSignals int `json:"dropped_signals"`
 
 	// Absolute performance
-	NetProfit            decimal.Decimal
-	NetAvgProfitPerTrade decimal.Decimal
-	CAGR                 decimal.Decimal
+	NetProfit            decimal.Decimal `json:"net_profit"`
+	NetAvgProfitPerTrade decimal.Decimal `json:"net_avg_profit_per_trade"`
+	CAGR                 decimal.Decimal `json:"cagr"`
 
 	// Trade-level distribution metrics
-	AvgWin       decimal.Decimal
-	AvgLoss      decimal.Decimal
-	WinLossRatio decimal.Decimal
+	AvgWin       decimal.Decimal `json:"avg_win"`
+	AvgLoss      decimal.Decimal `json:"avg_loss"`
+	WinLossRatio decimal.Decimal `json:"win_loss_ratio"`
 
 	// Drawdown & loss streak metrics
-	MaxDrawdown          decimal.Decimal
-	MaxDrawdownPercent   decimal.Decimal
-	MaxDrawdownDays      time.Duration
-	MaxConsecutiveLosses int
-
-	// Risk-adjusted metrics
-	SharpeRatio  decimal.Decimal
-	SortinoRatio decimal.Decimal
-	ProfitFactor decimal.Decimal
+	MaxDrawdown          decimal.Decimal `json:"max_drawdown"`
+	MaxDrawdownPercent   decimal.Decimal `json:"max_drawdown_percent"`
+	MaxDrawdownDays      time.Duration   `json:"-"`
+	MaxConsecutiveLosses int             `json:"max_consecutive_losses"`
+	// Deepest drawdowns first, see ReportingConfig.DrawdownEpisodes. The average and time under
+	// water cover every drawdown of the run.
+	Drawdowns             []DrawdownEpisode `json:"drawdowns"`
+	AvgDrawdownPercent    decimal.Decimal   `json:"avg_drawdown_percent"`
+	TimeUnderWater        time.Duration     `json:"-"`
+	TimeUnderWaterPercent decimal.Decimal   `json:"time_under_water_percent"`
+
+	// Risk-adjusted metrics. Ratios built on snapshot returns are annualised with AnnualizationPeriods,
+	// the periods per year of the snapshot frequency.
+	SharpeRatio           decimal.Decimal `json:"sharpe_ratio"`
+	SortinoRatio          decimal.Decimal `json:"sortino_ratio"`
+	ProfitFactor          decimal.Decimal `json:"profit_factor"`
+	CalmarRatio           decimal.Decimal `json:"calmar_ratio"`
+	MARRatio              decimal.Decimal `json:"mar_ratio"`
+	UlcerIndex            decimal.Decimal `json:"ulcer_index"`
+	UlcerPerformanceIndex decimal.Decimal `json:"ulcer_performance_index"`
+	OmegaRatio            decimal.Decimal `json:"omega_ratio"`
+	TailRatio             decimal.Decimal `json:"tail_ratio"`
+	Skewness              decimal.Decimal `json:"skewness"`
+	Kurtosis              decimal.Decimal `json:"kurtosis"`
+	ValueAtRisk95         decimal.Decimal `json:"value_at_risk_95"`
+	ConditionalVaR95      decimal.Decimal `json:"conditional_var_95"`
+	ReturnPeriod          string          `json:"return_period"`
+	Expectancy            decimal.Decimal `json:"expectancy"`
+	SQN                   decimal.Decimal `json:"sqn"`
+	AnnualizationPeriods  int             `json:"annualization_periods"`
+
+	// Periodic returns. Month is zero in the yearly entries.
+	MonthlyReturns        []PeriodReturn  `json:"monthly_returns"`
+	YearlyReturns         []PeriodReturn  `json:"yearly_returns"`
+	BestMonth             PeriodReturn    `json:"best_month"`
+	WorstMonth            PeriodReturn    `json:"worst_month"`
+	BestYear              PeriodReturn    `json:"best_year"`
+	WorstYear             PeriodReturn    `json:"worst_year"`
+	PositiveMonthsPercent decimal.Decimal `json:"positive_months_percent"`
+	// Rolling 3, 6 and 12 month series, written to the JSON report next to the metrics
+	Rolling []RollingMetrics `json:"-"`
+
+	// Exposure as a fraction of equity, averaged over the snapshots
+	TimeInMarket     decimal.Decimal `json:"time_in_market"`
+	AvgGrossExposure decimal.Decimal `json:"avg_gross_exposure"`
+	MaxGrossExposure decimal.Decimal `json:"max_gross_exposure"`
+	AvgNetExposure   decimal.Decimal `json:"avg_net_exposure"`
+	AnnualTurnover   decimal.Decimal `json:"annual_turnover"`
+	AvgHoldingPeriod time.Duration   `json:"-"`
 
 	// Costs
-	TotalFees decimal.Decimal
-
-	trades []trade
-
-	// TODO: UPI (brent pentfold book)
+	TotalFees decimal.Decimal `json:"total_fees"`
+
+	// External cash flows
+	TotalDeposits    decimal.Decimal `json:"total_deposits"`
+	TotalWithdrawals decimal.Decimal `json:"total_withdrawals"`
+
+	// Benchmark comparison, zero without a benchmark. Alpha is annualised Jensen's alpha,
+	// tracking error and information ratio use annualised daily active returns.
+	BenchmarkName    string          `json:"benchmark_name"`
+	BenchmarkCAGR    decimal.Decimal `json:"benchmark_cagr"`
+	ExcessCAGR       decimal.Decimal `json:"excess_cagr"`
+	Alpha            decimal.Decimal `json:"alpha"`
+	Beta             decimal.Decimal `json:"beta"`
+	Correlation      decimal.Decimal `json:"correlation"`
+	TrackingError    decimal.Decimal `json:"tracking_error"`
+	InformationRatio decimal.Decimal `json:"information_ratio"`
+	UpCapture        decimal.Decimal `json:"up_capture"`
+	DownCapture      decimal.Decimal `json:"down_capture"`
+
+	// Optional Monte Carlo analysis, see Engine.WithMonteCarlo
+	MonteCarlo *MonteCarloReport `json:"monte_carlo,omitempty"`
+
+	// Round trips and their breakdowns, written to the JSON report next to the metrics
+	Trades      []TradeRecord      `json:"-"`
+	ByTicker    []PerformanceStats `json:"-"`
+	ByAssetType []PerformanceStats `json:"-"`
+	BySide      []PerformanceStats `json:"-"`
+	// ByReason groups on the signal reason of the entry
+	ByReason []PerformanceStats `json:"-"`
+
+	trades    []trade
+	benchmark []decimal.Decimal
 }
 
 type trade struct {
 	buy  *types.ExecutionReport
 	sell *types.ExecutionReport
 	qty  decimal.Decimal
+	// open is the quantity of an open leg that is still held, qty is zero for those
+	open decimal.Decimal
 }
 
 // Report metrics
@@ -58,6 +133,7 @@ func (e *Engine) printReport(report *Report) {
 	fmt.Printf("Start Date:            %s\n", report.StartDate.Format("2006-01-02"))
 	fmt.Printf("Total Period:          %d days\n", int(report.TotalPeriod.Hours()/24))
 	fmt.Printf("Total Trades:          %d\n", report.TotalTrades)
+	fmt.Printf("Dropped Signals:       %d\n", report.DroppedSignals)
 
 	fmt.Println("\n-- Absolute Performance --")
 	fmt.Printf("Net Profit:            %.2f\n", report.NetProfit.InexactFloat64())
@@ -74,59 +150,153 @@ func (e *Engine) printReport(report *Report) {
 	fmt.Printf("Max Drawdown %%:        %.2f%%\n", report.MaxDrawdownPercent.Mul(decimal.NewFromFloat(100)).InexactFloat64())
 	fmt.Printf("Max Drawdown Days:     %d\n", int(report.MaxDrawdownDays.Hours()/24))
 	fmt.Printf("Max Consecutive Losses: %d\n", report.MaxConsecutiveLosses)
+	printDrawdownEpisodes(report)
 
 	fmt.Println("\n-- Risk-Adjusted Metrics --")
 	fmt.Printf("Sharpe Ratio:          %.2f\n", report.SharpeRatio.InexactFloat64())
 	fmt.Printf("Sortino Ratio:         %.2f\n", report.SortinoRatio.InexactFloat64())
 	fmt.Printf("Profit Factor:         %.2f\n", report.ProfitFactor.InexactFloat64())
+	printRiskMetrics(report)
+	printPeriodicReturns(report)
+	printExposure(report)
 
 	fmt.Println("\n-- Costs --")
 	fmt.Printf("Total Fees:            %.2f\n", report.TotalFees.InexactFloat64())
 
+	if !report.TotalDeposits.IsZero() || !report.TotalWithdrawals.IsZero() {
+		fmt.Println("\n-- Cash Flows --")
+		fmt.Printf("Total Deposits:        %.2f\n", report.TotalDeposits.InexactFloat64())
+		fmt.Printf("Total Withdrawals:     %.2f\n", report.TotalWithdrawals.InexactFloat64())
+	}
+
+	if report.BenchmarkName != "" {
+		printBenchmark(report)
+	}
+
+	if report.MonteCarlo != nil {
+		printMonteCarlo(report.MonteCarlo)
+	}
+
+	printBreakdown("Ticker", report.ByTicker)
+	printBreakdown("Asset Type", report.ByAssetType)
+	printBreakdown("Side", report.BySide)
+	printBreakdown("Signal Reason", report.ByReason)
+
 	fmt.Println("==========================")
 }
 
-// Generate metrics
-func (e *Engine) generateReport(start, end time.Time, results *portfolio) *Report {
-	trades := executionsToTrades(results)
-	//
+// Generate metrics. The trades and the snapshots that exposure is measured on are passed apart from the
+// portfolio, a walk-forward report matches them per out-of-sample window.
+func (e *Engine) generateReport(start, end time.Time, results *portfolio, trades []trade, exposed []types.PortfolioView) *Report {
 	report := &Report{}
 	report.StartDate = start
 	report.TotalPeriod = end.Sub(start).Truncate(time.Hour * 24)
 	report.TotalTrades = len(trades)
 	report.trades = trades
+	report.Trades = tradeRecords(trades, e.backtester.executionConfig.candles)
+	e.addBreakdowns(report, start, end)
+	report.TotalDeposits, report.TotalWithdrawals = sumCashFlows(results.cashFlows)
+	report.DroppedSignals = len(e.backtester.signalResolver.dropped)
+	var weekdaysOnly bool
+	report.AnnualizationPeriods, weekdaysOnly = e.returnPeriods(results.snapshots)
+	report.ReturnPeriod = e.backtester.snapshotFrequency.period()
+	returns := dailyReturns(results.snapshots, weekdaysOnly)
+	riskFree := e.reportingConfig.sharpeRiskFreeRate
 
 	var wg sync.WaitGroup
-	wg.Add(8)
-	go func() {
-		report.NetProfit, report.TotalFees = calcNetProfitAndFees(trades, &wg)
-	}()
-	go func() {
-		report.NetAvgProfitPerTrade = calcNetAvgProfitPerTrade(trades, &wg)
-	}()
-	go func() {
-		report.AvgWin, report.AvgLoss = calcAvgWinLossPerTrade(trades, &wg)
-	}()
-	go func() {
-		report.CAGR = calcCAGR(results.snapshots, &wg)
-	}()
-	go func() {
-		report.MaxDrawdown, report.MaxDrawdownPercent, report.MaxDrawdownDays = calcDrawdownMetrics(results.snapshots, &wg)
-	}()
-	go func() {
-		report.MaxConsecutiveLosses = calcMaxConsecutiveLosses(trades, &wg)
-	}()
-	go func() {
-		report.SharpeRatio = calcSharpeRatio(results.snapshots, e.reportingConfig.sharpeRiskFreeRate, &wg)
-	}()
-	go func() {
-		report.WinLossRatio = calcWinLossRatio(trades, &wg)
-	}()
+	wg.Add(16)
+	goMetric(&wg, func(done *sync.WaitGroup) {
+		report.NetProfit, report.TotalFees = calcNetProfitAndFees(trades, done)
+	})
+	goMetric(&wg, func(done *sync.WaitGroup) {
+		report.NetAvgProfitPerTrade = calcNetAvgProfitPerTrade(trades, done)
+	})
+	goMetric(&wg, func(done *sync.WaitGroup) {
+		report.AvgWin, report.AvgLoss = calcAvgWinLossPerTrade(trades, done)
+	})
+	goMetric(&wg, func(done *sync.WaitGroup) {
+		report.CAGR = calcCAGR(results.snapshots, done)
+	})
+	goMetric(&wg, func(done *sync.WaitGroup) {
+		report.MaxDrawdown, report.MaxDrawdownPercent, report.MaxDrawdownDays = calcDrawdownMetrics(results.snapshots, done)
+	})
+	goMetric(&wg, func(done *sync.WaitGroup) {
+		drawdowns := calcDrawdownEpisodes(results.snapshots, e.reportingConfig.drawdownEpisodeCount(), done)
+		report.Drawdowns, report.AvgDrawdownPercent = drawdowns.episodes, drawdowns.avgDepth
+		report.TimeUnderWater, report.TimeUnderWaterPercent = drawdowns.timeUnderWater, drawdowns.underWaterPct
+	})
+	goMetric(&wg, func(done *sync.WaitGroup) {
+		report.MaxConsecutiveLosses = calcMaxConsecutiveLosses(trades, done)
+	})
+	goMetric(&wg, func(done *sync.WaitGroup) {
+		report.SharpeRatio = calcSharpeRatio(returns, riskFree, report.AnnualizationPeriods, done)
+	})
+	goMetric(&wg, func(done *sync.WaitGroup) {
+		report.WinLossRatio = calcWinLossRatio(trades, done)
+	})
+	goMetric(&wg, func(done *sync.WaitGroup) {
+		report.SortinoRatio = calcSortinoRatio(returns, riskFree, report.AnnualizationPeriods, done)
+	})
+	goMetric(&wg, func(done *sync.WaitGroup) {
+		report.ProfitFactor = calcProfitFactor(trades, done)
+	})
+	goMetric(&wg, func(done *sync.WaitGroup) {
+		dist := calcReturnDistribution(returns, done)
+		report.OmegaRatio, report.TailRatio = dist.omega, dist.tailRatio
+		report.Skewness, report.Kurtosis = dist.skewness, dist.kurtosis
+		report.ValueAtRisk95, report.ConditionalVaR95 = dist.valueAtRisk, dist.conditionalVaR
+	})
+	goMetric(&wg, func(done *sync.WaitGroup) {
+		report.Expectancy, report.SQN = calcExpectancyAndSQN(trades, done)
+	})
+	goMetric(&wg, func(done *sync.WaitGroup) {
+		periodic := calcPeriodicReturns(results.snapshots, done)
+		report.MonthlyReturns, report.YearlyReturns = periodic.monthly, periodic.yearly
+		report.BestMonth, report.WorstMonth = periodic.bestMonth, periodic.worstMonth
+		report.BestYear, report.WorstYear = periodic.bestYear, periodic.worstYear
+		report.PositiveMonthsPercent = periodic.positiveMonths
+	})
+	goMetric(&wg, func(done *sync.WaitGroup) {
+		exposure := calcExposureStats(exposed, results.executions, report.Trades, done)
+		report.TimeInMarket, report.AnnualTurnover, report.AvgHoldingPeriod = exposure.timeInMarket, exposure.annualTurnover, exposure.avgHolding
+		report.AvgGrossExposure, report.MaxGrossExposure, report.AvgNetExposure = exposure.avgGross, exposure.maxGross, exposure.avgNet
+	})
+	goMetric(&wg, func(done *sync.WaitGroup) {
+		report.Rolling = calcRollingMetrics(results.snapshots, riskFree, report.AnnualizationPeriods, weekdaysOnly, done)
+	})
+	wg.Wait()
+
+	// These build on CAGR and drawdown from the first pass
+	wg.Add(2)
+	goMetric(&wg, func(done *sync.WaitGroup) {
+		report.CalmarRatio, report.MARRatio = calcCalmarAndMAR(results.snapshots, report.CAGR, report.MaxDrawdownPercent, done)
+	})
+	goMetric(&wg, func(done *sync.WaitGroup) {
+		report.UlcerIndex, report.UlcerPerformanceIndex = calcUlcerIndex(results.snapshots, report.CAGR, riskFree, done)
+	})
 	wg.Wait()
 
 	return report
 }
 
+// goMetric runs a metric calculation in its own goroutine. The calc functions signal their WaitGroup
+// on return, before the caller has stored the result, so wg is only released after the assignment.
+func goMetric(wg *sync.WaitGroup, calc func(done *sync.WaitGroup)) {
+	go func() {
+		defer wg.Done()
+		var done sync.WaitGroup
+		done.Add(1)
+		calc(&done)
+	}()
+}
+
+// noopWaitGroup lets a calc function run synchronously outside generateReport.
+func noopWaitGroup() *sync.WaitGroup {
+	var wg sync.WaitGroup
+	wg.Add(1)
+	return &wg
+}
+
 func calcNetProfitAndFees(trades []trade, wg *sync.WaitGroup) (decimal.Decimal, decimal.Decimal) {
 	defer wg.Done()
 
@@ -237,8 +407,10 @@ func calcCAGR(snapshots []types.PortfolioView, wg *sync.WaitGroup) decimal.Decim
 	startSnap := snapshots[0]
 	endSnap := snapshots[len(snapshots)-1]
 
-	startVal := portfolioValue(startSnap)
-	endVal := portfolioValue(endSnap)
+	// Use the time-weighted curve so deposits and withdrawals do not count as growth
+	equity := timeWeightedEquity(snapshots)
+	startVal := equity[0]
+	endVal := equity[len(equity)-1]
 
 	if startVal.LessThanOrEqual(decimal.Zero) {
 		return decimal.Zero
@@ -352,8 +524,9 @@ func calcDrawdownMetrics(
 	maxDDPct := decimal.Zero
 	var maxDDDuration time.Duration
 
+	curve := timeWeightedEquity(snapshots)
 	for i, snap := range snapshots {
-		equity := portfolioValue(snap)
+		equity := curve[i]
 
 		// Initialize peak with first snapshot that has a value
 		if i == 0 || equity.GreaterThan(peak) || peak.IsZero() {
@@ -451,57 +624,44 @@ func calcMaxConsecutiveLosses(trades []trade, wg *sync.WaitGroup) int {
 	return maxLossStreak
 }
 
+// calcSharpeRatio annualises the mean excess snapshot return over its sample standard deviation with
+// periodsPerYear, like the other ratios built on snapshot returns.
 func calcSharpeRatio(
-	snapshots []types.PortfolioView,
+	returns []float64,
 	annualRiskFree decimal.Decimal,
+	periodsPerYear int,
 	wg *sync.WaitGroup,
 ) decimal.Decimal {
 	defer wg.Done()
-	monthlyReturns := getMonthlyReturns(snapshots)
-	if len(monthlyReturns) < 2 {
-		// Need at least 2 months to compute stddev
+	if len(returns) < 2 || periodsPerYear <= 0 {
+		// Need at least 2 returns to compute stddev
 		return decimal.Zero
 	}
 
-	// Convert annual risk-free to *monthly* risk-free:
-	// rf_monthly = (1 + rf_annual)^(1/12) - 1
-	rfAnnualFloat := annualRiskFree.InexactFloat64()
-	rfMonthlyFloat := math.Pow(1.0+rfAnnualFloat, 1.0/12.0) - 1.0
-
-	// Build slice of monthly *excess* returns in float64
-	excess := make([]float64, 0, len(monthlyReturns))
-	for _, r := range monthlyReturns {
-		rFloat := r.InexactFloat64()
-		excess = append(excess, rFloat-rfMonthlyFloat)
-	}
-
-	if len(excess) < 2 {
-		return decimal.Zero
-	}
+	// Convert annual risk-free to the snapshot period:
+	// rf_period = (1 + rf_annual)^(1/periods) - 1
+	rfPeriod := math.Pow(1.0+annualRiskFree.InexactFloat64(), 1.0/float64(periodsPerYear)) - 1.0
 
-	// Mean of monthly excess returns
+	// Mean of excess returns
 	var sum float64
-	for _, x := range excess {
-		sum += x
+	for _, r := range returns {
+		sum += r - rfPeriod
 	}
-	meanMonthlyExcess := sum / float64(len(excess))
+	meanExcess := sum / float64(len(returns))
 
-	// Sample standard deviation of monthly excess returns
+	// Sample standard deviation of excess returns
 	var varianceSum float64
-	for _, x := range excess {
-		diff := x - meanMonthlyExcess
+	for _, r := range returns {
+		diff := r - rfPeriod - meanExcess
 		varianceSum += diff * diff
 	}
-	stdMonthly := math.Sqrt(varianceSum / float64(len(excess)-1))
-	if stdMonthly == 0 {
+	std := math.Sqrt(varianceSum / float64(len(returns)-1))
+	if std == 0 {
 		return decimal.Zero
 	}
 
-	// Monthly Sharpe, then annualize by sqrt(12)
-	sharpeMonthly := meanMonthlyExcess / stdMonthly
-	sharpeAnnual := sharpeMonthly * math.Sqrt(12.0)
-
-	return decimal.NewFromFloat(sharpeAnnual)
+	// Sharpe per period, then annualize by sqrt(periods)
+	return decimal.NewFromFloat(meanExcess / std * math.Sqrt(float64(periodsPerYear)))
 }
 
 func calcWinLossRatio(trades []trade, wg *sync.WaitGroup) decimal.Decimal {
@@ -544,93 +704,65 @@ func calcWinLossRatio(trades []trade, wg *sync.WaitGroup) decimal.Decimal {
 	return wins.Div(total)
 }
 
+// getMonthlyReturns returns the monthly returns without the first month, which only runs from the
+// first snapshot, so every return spans a month end to the next. Months without a positive value to
+// start from are left out. The snapshots are sorted by time on a copy, other metrics read them concurrently.
 func getMonthlyReturns(snapshots []types.PortfolioView) []decimal.Decimal {
-	if len(snapshots) == 0 {
-		return nil
-	}
-
-	sort.Slice(snapshots, func(i, j int) bool {
-		return snapshots[i].Time.Before(snapshots[j].Time)
+	sorted := slices.Clone(snapshots)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Time.Before(sorted[j].Time)
 	})
-
-	type monthKey struct {
-		year  int
-		month time.Month
-	}
-
-	type monthBounds struct {
-		first types.PortfolioView
-		last  types.PortfolioView
+	monthly, measured := measuredMonthlyReturns(sorted)
+	if len(monthly) < 2 {
+		return nil
 	}
-
-	months := make(map[monthKey]*monthBounds)
-
-	// Find first/last snapshot in each calendar month
-	for _, snap := range snapshots {
-		y, m, _ := snap.Time.Date()
-		key := monthKey{year: y, month: m}
-
-		if b, ok := months[key]; !ok {
-			months[key] = &monthBounds{
-				first: snap,
-				last:  snap,
-			}
-		} else {
-			if snap.Time.Before(b.first.Time) {
-				b.first = snap
-			}
-			if snap.Time.After(b.last.Time) {
-				b.last = snap
-			}
+	returns := make([]decimal.Decimal, 0, len(monthly)-1)
+	for i, r := range monthly[1:] {
+		if measured[i+1] {
+			returns = append(returns, r.Return)
 		}
 	}
+	return returns
+}
 
-	// Sort months chronologically
-	keys := make([]monthKey, 0, len(months))
-	for k := range months {
-		keys = append(keys, k)
-	}
-	sort.Slice(keys, func(i, j int) bool {
-		if keys[i].year != keys[j].year {
-			return keys[i].year < keys[j].year
+// Helper functions
+func sumCashFlows(flows []CashFlow) (decimal.Decimal, decimal.Decimal) {
+	deposits := decimal.Zero
+	withdrawals := decimal.Zero
+	for _, flow := range flows {
+		if flow.Amount.IsPositive() {
+			deposits = deposits.Add(flow.Amount)
+		} else {
+			withdrawals = withdrawals.Add(flow.Amount.Abs())
 		}
-		return keys[i].month < keys[j].month
-	})
-
-	// Collect month-end values (using the "last" snapshot in each month)
-	monthEnds := make([]decimal.Decimal, 0, len(keys))
-	for _, k := range keys {
-		b := months[k]
-		endVal := portfolioValue(b.last)
-		monthEnds = append(monthEnds, endVal)
 	}
+	return deposits, withdrawals
+}
 
-	// Now compute returns BETWEEN consecutive month-end values
-	if len(monthEnds) < 2 {
-		return nil
+// tradeNetPnL returns the PnL of the matched quantity of a closed trade after fees. Fees of a leg
+// are split pro rata when the leg was matched against several trades. Open trades report false.
+func tradeNetPnL(tr trade) (decimal.Decimal, bool) {
+	if tr.buy == nil || tr.sell == nil || tr.qty.IsZero() {
+		return decimal.Zero, false
 	}
 
-	returns := make([]decimal.Decimal, 0, len(monthEnds)-1)
-	prev := monthEnds[0]
-
-	for i := 1; i < len(monthEnds); i++ {
-		curr := monthEnds[i]
-
-		if !prev.GreaterThan(decimal.Zero) {
-			prev = curr
-			continue
-		}
+	gross := tr.sell.AvgFillPrice.Sub(tr.buy.AvgFillPrice).Mul(tr.qty)
+	return gross.Sub(tradeFees(tr)), true
+}
 
-		r := curr.Div(prev).Sub(decimal.NewFromInt(1))
-		returns = append(returns, r)
+// tradeFees returns the share of the fees of both legs that belongs to the matched quantity.
+func tradeFees(tr trade) decimal.Decimal {
+	return legFees(tr.buy, tr.qty).Add(legFees(tr.sell, tr.qty))
+}
 
-		prev = curr
+// legFees returns the share of the fees of an execution that belongs to qty of its filled quantity.
+func legFees(report *types.ExecutionReport, qty decimal.Decimal) decimal.Decimal {
+	if report == nil || report.TotalFilledQty.IsZero() {
+		return decimal.Zero
 	}
-
-	return returns
+	return report.TotalFees.Mul(qty).Div(report.TotalFilledQty)
 }
 
-// Helper functions
 func executionsToTrades(p *portfolio) []trade {
 	// Group executions by ticker
 	execsByTicker := make(map[string][]types.ExecutionReport)
@@ -728,7 +860,8 @@ func executionsToTrades(p *portfolio) []trade {
 			trades = append(trades, trade{
 				buy: leg.exec,
 				// sell: nil,
-				qty: decimal.Zero, // NOTE: open long → qty = 0 per tests
+				qty:  decimal.Zero, // NOTE: open long → qty = 0 per tests
+				open: leg.remaining,
 			})
 		}
 		for _, leg := range openSells {
@@ -739,6 +872,7 @@ func executionsToTrades(p *portfolio) []trade {
 				// buy: nil,
 				sell: leg.exec,
 				qty:  decimal.Zero, // NOTE: open short → qty = 0 per tests
+				open: leg.remaining,
 			})
 		}
 
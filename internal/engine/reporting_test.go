@@ -1544,7 +1544,8 @@ func TestCalcSharpeRatioFromSnapshots(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			var wg sync.WaitGroup
 			wg.Add(1)
-			got := calcSharpeRatio(tt.snapshots, tt.riskFree, &wg)
+			// One snapshot per month, so the snapshot returns are monthly
+			got := calcSharpeRatio(dailyReturns(tt.snapshots, false), tt.riskFree, 12, &wg)
 			if !got.Round(4).Equal(tt.wantSharpe.Round(4)) {
 				t.Fatalf("got=%s, wantIndex=%s", got.Round(4), tt.wantSharpe.Round(4))
 			}
@@ -1743,6 +1744,7 @@ func TestExecutionsToTrades(t *testing.T) {
 					},
 					sell: nil,
 					qty:  decimal.Zero,
+					open: decimal.NewFromInt(5),
 				},
 			},
 		},
@@ -1793,7 +1795,8 @@ func TestExecutionsToTrades(t *testing.T) {
 						ReportTime:     baseTime.Add(2 * time.Minute),
 						TotalFilledQty: decimal.NewFromInt(2),
 					},
-					qty: decimal.Zero,
+					qty:  decimal.Zero,
+					open: decimal.NewFromInt(2),
 				},
 			},
 		},
@@ -1995,6 +1998,7 @@ func TestExecutionsToTrades(t *testing.T) {
 					},
 					sell: nil,
 					qty:  decimal.Zero,
+					open: decimal.NewFromInt(4),
 				},
 			},
 		},
@@ -2068,7 +2072,8 @@ func TestExecutionsToTrades(t *testing.T) {
 						ReportTime:     baseTime.Add(2 * time.Minute),
 						TotalFilledQty: decimal.NewFromInt(10),
 					},
-					qty: decimal.Zero,
+					qty:  decimal.Zero,
+					open: decimal.NewFromInt(4),
 				},
 			},
 		},
@@ -0,0 +1,351 @@
+package engine
+
+import (
+	"backtester/types"
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	RejectNoReferencePrice    types.RejectCode = "NO_REFERENCE_PRICE"
+	RejectMaxDrawdown         types.RejectCode = "MAX_DRAWDOWN"
+	RejectMaxOrdersPerDay     types.RejectCode = "MAX_ORDERS_PER_DAY"
+	RejectMaxNotionalPerOrder types.RejectCode = "MAX_NOTIONAL_PER_ORDER"
+	RejectMaxPositionWeight   types.RejectCode = "MAX_POSITION_WEIGHT"
+	RejectMaxGrossExposure    types.RejectCode = "MAX_GROSS_EXPOSURE"
+	RejectMaxNetExposure      types.RejectCode = "MAX_NET_EXPOSURE"
+	RejectMaxAssetTypeWeight  types.RejectCode = "MAX_ASSET_TYPE_WEIGHT"
+)
+
+// RiskManager is the default pre-trade risk layer. It sits between the allocator and the broker and
+// resizes or rejects orders that would breach one of its limits. A zero limit means the rule is disabled.
+// Orders that only reduce an existing position always pass so the strategy can get out of risk.
+type RiskManager struct {
+	api    PortfolioApi
+	assets map[string]*types.Asset
+
+	maxPositionWeight   decimal.Decimal
+	maxGrossExposure    decimal.Decimal
+	maxNetExposure      decimal.Decimal
+	maxNotionalPerOrder decimal.Decimal
+	maxOrdersPerDay     int
+	maxDrawdown         decimal.Decimal
+	assetTypeCaps       map[types.AssetType]decimal.Decimal
+
+	// Equity is tracked with deposits and withdrawals removed, so they neither set a peak nor trip the switch
+	flowScale         decimal.Decimal
+	lastContributions decimal.Decimal
+	checked           bool
+	peakEquity        decimal.Decimal
+	halted            bool
+	ordersDay         time.Time
+	ordersToday       int
+
+	// Decisions of the last Check besides rejections
+	resized []RiskResize
+	halt    *RiskHalt
+}
+
+// RiskResize is an order the risk manager cut down to what its limits allow. Order carries the
+// allowed quantity and Code the rule that bound hardest.
+type RiskResize struct {
+	Order            types.Order      `json:"order"`
+	OriginalQuantity decimal.Decimal  `json:"original_quantity"`
+	Code             types.RejectCode `json:"code"`
+	Reason           string           `json:"reason"`
+}
+
+// RiskHalt is the drawdown kill switch tripping, after which new risk is rejected for the rest of the run.
+// Equity and PeakEquity have deposits and withdrawals removed and are expressed in the currency of the
+// first check, like the time-weighted equity of the reports.
+type RiskHalt struct {
+	Equity      decimal.Decimal `json:"equity"`
+	PeakEquity  decimal.Decimal `json:"peak_equity"`
+	MaxDrawdown decimal.Decimal `json:"max_drawdown"`
+}
+
+func NewRiskManager() *RiskManager {
+	return &RiskManager{
+		assetTypeCaps: make(map[types.AssetType]decimal.Decimal),
+	}
+}
+
+// MaxPositionWeight caps a single position at a fraction of portfolio equity.
+func (r *RiskManager) MaxPositionWeight(weight decimal.Decimal) *RiskManager {
+	r.maxPositionWeight = weight
+	return r
+}
+
+// MaxGrossExposure caps the sum of absolute position values as a multiple of equity.
+func (r *RiskManager) MaxGrossExposure(multiple decimal.Decimal) *RiskManager {
+	r.maxGrossExposure = multiple
+	return r
+}
+
+// MaxNetExposure caps the absolute value of long minus short position values as a multiple of equity.
+func (r *RiskManager) MaxNetExposure(multiple decimal.Decimal) *RiskManager {
+	r.maxNetExposure = multiple
+	return r
+}
+
+func (r *RiskManager) MaxNotionalPerOrder(notional decimal.Decimal) *RiskManager {
+	r.maxNotionalPerOrder = notional
+	return r
+}
+
+func (r *RiskManager) MaxOrdersPerDay(n int) *RiskManager {
+	r.maxOrdersPerDay = n
+	return r
+}
+
+// MaxAssetTypeWeight caps the combined absolute value of all positions of an asset type as a fraction of equity.
+func (r *RiskManager) MaxAssetTypeWeight(assetType types.AssetType, weight decimal.Decimal) *RiskManager {
+	r.assetTypeCaps[assetType] = weight
+	return r
+}
+
+// MaxDrawdown halts all new risk once equity falls the given fraction below its peak. The halt is permanent for the run.
+func (r *RiskManager) MaxDrawdown(fraction decimal.Decimal) *RiskManager {
+	r.maxDrawdown = fraction
+	return r
+}
+
+func (r *RiskManager) Init(api PortfolioApi, assets map[string]*types.Asset) error {
+	r.api = api
+	r.assets = assets
+	return nil
+}
+
+func (r *RiskManager) Check(orders []types.Order, view types.PortfolioView) ([]types.Order, []types.ExecutionReport) {
+	r.resized, r.halt = nil, nil
+	equity := portfolioValue(view)
+	r.updateKillSwitch(r.flowAdjusted(equity, view.NetContributions))
+
+	if len(orders) == 0 {
+		return orders, nil
+	}
+
+	y, m, d := view.Time.Date()
+	today := time.Date(y, m, d, 0, 0, 0, 0, view.Time.Location())
+	if !today.Equal(r.ordersDay) {
+		r.ordersDay = today
+		r.ordersToday = 0
+	}
+
+	// Project positions forward as orders in this batch are accepted, so limits apply to the batch as a whole
+	qty := make(map[string]decimal.Decimal, len(view.Positions))
+	prices := make(map[string]decimal.Decimal, len(view.Positions))
+	for ticker, pos := range view.Positions {
+		qty[ticker] = pos.Quantity
+		prices[ticker] = pos.LastMarketPrice
+	}
+
+	accepted := make([]types.Order, 0, len(orders))
+	var rejected []types.ExecutionReport
+
+	for _, order := range orders {
+		sideSign := decimal.NewFromInt(1)
+		if order.Side == types.SideTypeSell {
+			sideSign = sideSign.Neg()
+		}
+		oldQty := qty[order.Ticker]
+		newQty := oldQty.Add(order.Quantity.Mul(sideSign))
+
+		// Pure reductions always pass
+		if !oldQty.IsZero() && (sameSide(oldQty, newQty) || newQty.IsZero()) && newQty.Abs().LessThan(oldQty.Abs()) {
+			accepted = append(accepted, order)
+			qty[order.Ticker] = newQty
+			continue
+		}
+
+		price := order.Price
+		if !price.IsPositive() {
+			price = prices[order.Ticker]
+		}
+
+		code, reason, maxQty := r.maxQuantity(order, sideSign, price, equity, qty, prices)
+		if maxQty.LessThan(order.Quantity) {
+			maxQty = roundQuantity(maxQty, order.Quantity)
+		}
+		if !maxQty.IsPositive() {
+			rejected = append(rejected, newRiskRejection(order, code, reason, view.Time))
+			continue
+		}
+
+		if maxQty.LessThan(order.Quantity) {
+			r.resized = append(r.resized, RiskResize{
+				Order:            order,
+				OriginalQuantity: order.Quantity,
+				Code:             code,
+				Reason:           reason,
+			})
+			r.resized[len(r.resized)-1].Order.Quantity = maxQty
+		}
+		order.Quantity = maxQty
+		accepted = append(accepted, order)
+		qty[order.Ticker] = oldQty.Add(maxQty.Mul(sideSign))
+		if price.IsPositive() {
+			prices[order.Ticker] = price
+		}
+		r.ordersToday++
+	}
+
+	return accepted, rejected
+}
+
+func (r *RiskManager) lastDecisions() ([]RiskResize, *RiskHalt) {
+	return r.resized, r.halt
+}
+
+// flowAdjusted removes the cash flows since the previous check from the equity, the same way as
+// timeWeightedEquity does for the snapshots.
+func (r *RiskManager) flowAdjusted(equity, contributions decimal.Decimal) decimal.Decimal {
+	if !r.checked {
+		r.flowScale = decimal.NewFromInt(1)
+		r.checked = true
+	} else if flow := contributions.Sub(r.lastContributions); !flow.IsZero() && !equity.IsZero() {
+		r.flowScale = r.flowScale.Mul(equity.Sub(flow)).Div(equity)
+	}
+	r.lastContributions = contributions
+	return equity.Mul(r.flowScale)
+}
+
+func (r *RiskManager) updateKillSwitch(equity decimal.Decimal) {
+	if equity.GreaterThan(r.peakEquity) {
+		r.peakEquity = equity
+	}
+	if r.maxDrawdown.IsPositive() && r.peakEquity.IsPositive() {
+		dd := r.peakEquity.Sub(equity).Div(r.peakEquity)
+		if dd.GreaterThanOrEqual(r.maxDrawdown) && !r.halted {
+			r.halted = true
+			r.halt = &RiskHalt{Equity: equity, PeakEquity: r.peakEquity, MaxDrawdown: r.maxDrawdown}
+		}
+	}
+}
+
+// maxQuantity returns the largest order quantity allowed by every enabled rule together with the
+// rule that binds hardest. An empty code means no rule restricts the order.
+func (r *RiskManager) maxQuantity(
+	order types.Order,
+	sideSign, price, equity decimal.Decimal,
+	qty, prices map[string]decimal.Decimal,
+) (types.RejectCode, string, decimal.Decimal) {
+	if r.halted {
+		return RejectMaxDrawdown, fmt.Sprintf("max drawdown of %s reached, new risk is halted", r.maxDrawdown), decimal.Zero
+	}
+	if r.maxOrdersPerDay > 0 && r.ordersToday >= r.maxOrdersPerDay {
+		return RejectMaxOrdersPerDay, fmt.Sprintf("max %d orders per day reached", r.maxOrdersPerDay), decimal.Zero
+	}
+	assetType := r.assetType(order.Ticker)
+	if !r.pricedLimits(assetType) {
+		return "", "", order.Quantity
+	}
+	if !price.IsPositive() {
+		return RejectNoReferencePrice, "no price available to evaluate risk limits", decimal.Zero
+	}
+
+	var code types.RejectCode
+	var reason string
+	best := order.Quantity
+	limit := func(c types.RejectCode, msg string, q decimal.Decimal) {
+		if q.LessThan(best) {
+			best, code, reason = q, c, msg
+		}
+	}
+
+	oldQty := qty[order.Ticker]
+
+	if r.maxNotionalPerOrder.IsPositive() {
+		limit(RejectMaxNotionalPerOrder,
+			fmt.Sprintf("order notional exceeds %s", r.maxNotionalPerOrder),
+			r.maxNotionalPerOrder.Div(price))
+	}
+
+	if r.maxPositionWeight.IsPositive() {
+		maxAbs := r.maxPositionWeight.Mul(equity).Div(price)
+		limit(RejectMaxPositionWeight,
+			fmt.Sprintf("position weight would exceed %s of equity", r.maxPositionWeight),
+			maxAbs.Sub(sideSign.Mul(oldQty)))
+	}
+
+	otherGross, otherNet := decimal.Zero, decimal.Zero
+	otherType := decimal.Zero
+	for ticker, q := range qty {
+		if ticker == order.Ticker {
+			continue
+		}
+		value := q.Mul(prices[ticker])
+		otherGross = otherGross.Add(value.Abs())
+		otherNet = otherNet.Add(value)
+		if assetType != "" && r.assetType(ticker) == assetType {
+			otherType = otherType.Add(value.Abs())
+		}
+	}
+
+	if r.maxGrossExposure.IsPositive() {
+		maxAbs := r.maxGrossExposure.Mul(equity).Sub(otherGross).Div(price)
+		limit(RejectMaxGrossExposure,
+			fmt.Sprintf("gross exposure would exceed %s times equity", r.maxGrossExposure),
+			maxAbs.Sub(sideSign.Mul(oldQty)))
+	}
+
+	if r.maxNetExposure.IsPositive() {
+		// Bound on the new signed position in the direction of the order
+		bound := r.maxNetExposure.Mul(equity).Mul(sideSign).Sub(otherNet).Div(price)
+		limit(RejectMaxNetExposure,
+			fmt.Sprintf("net exposure would exceed %s times equity", r.maxNetExposure),
+			sideSign.Mul(bound).Sub(sideSign.Mul(oldQty)))
+	}
+
+	if typeCap, ok := r.assetTypeCaps[assetType]; ok && typeCap.IsPositive() {
+		maxAbs := typeCap.Mul(equity).Sub(otherType).Div(price)
+		limit(RejectMaxAssetTypeWeight,
+			fmt.Sprintf("%s exposure would exceed %s of equity", assetType, typeCap),
+			maxAbs.Sub(sideSign.Mul(oldQty)))
+	}
+
+	return code, reason, best
+}
+
+// pricedLimits reports whether a limit that values the order is enabled for the asset type, the others
+// also check orders without a price.
+func (r *RiskManager) pricedLimits(assetType types.AssetType) bool {
+	return r.maxNotionalPerOrder.IsPositive() || r.maxPositionWeight.IsPositive() ||
+		r.maxGrossExposure.IsPositive() || r.maxNetExposure.IsPositive() || r.assetTypeCaps[assetType].IsPositive()
+}
+
+func (r *RiskManager) assetType(ticker string) types.AssetType {
+	asset := r.assets[ticker]
+	if asset == nil {
+		return ""
+	}
+	return asset.Type
+}
+
+// roundQuantity rounds a resized quantity down to the precision of the original order, so whole-share orders stay whole.
+func roundQuantity(qty, original decimal.Decimal) decimal.Decimal {
+	if original.Equal(original.Floor()) {
+		return qty.Floor()
+	}
+	return qty.Truncate(original.Exponent() * -1)
+}
+
+func newRiskRejection(order types.Order, code types.RejectCode, reason string, at time.Time) types.ExecutionReport {
+	report := *types.NewExecutionReport(
+		order.Ticker,
+		order.Side,
+		types.OrderRejected,
+		[]types.Fill{},
+		decimal.Zero,
+		decimal.Zero,
+		decimal.Zero,
+		order.Quantity,
+		order.SignalReason,
+		reason,
+		at,
+	)
+	report.RejectCode = code
+	return report
+}
@@ -0,0 +1,302 @@
+package engine
+
+import (
+	"backtester/types"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	tradingDaysPerYear  = 252
+	calendarDaysPerYear = 365
+)
+
+// AnnualizationPeriods sets how many daily returns make a year for an asset type. Defaults are 252 for
+// stocks and ETFs, which only count weekdays, and 365 for crypto. A run uses the largest value of
+// the asset types it trades, since mixing in a 7 day market makes every day count.
+func (c *ReportingConfig) AnnualizationPeriods(assetType types.AssetType, periods int) *ReportingConfig {
+	if c.annualization == nil {
+		c.annualization = make(map[types.AssetType]int)
+	}
+	c.annualization[assetType] = periods
+	return c
+}
+
+func (c *ReportingConfig) periodsFor(assetType types.AssetType) int {
+	if periods, ok := c.annualization[assetType]; ok && periods > 0 {
+		return periods
+	}
+	if assetType == types.AssetTypeCrypto {
+		return calendarDaysPerYear
+	}
+	return tradingDaysPerYear
+}
+
+// annualizationPeriods picks the periods per year for the traded assets, 252 when there are none.
+func (e *Engine) annualizationPeriods() int {
+	periods := 0
+	for _, asset := range e.assets {
+		if asset == nil {
+			continue
+		}
+		if p := e.reportingConfig.periodsFor(asset.Type); p > periods {
+			periods = p
+		}
+	}
+	if periods == 0 {
+		return e.reportingConfig.periodsFor(types.AssetTypeStock)
+	}
+	return periods
+}
+
+// dailyReturns returns the snapshot to snapshot returns of the time-weighted equity curve, day over
+// day with daily snapshots. With weekdaysOnly weekend snapshots are skipped, so a Friday to Monday
+// move counts as one trading day.
+func dailyReturns(snapshots []types.PortfolioView, weekdaysOnly bool) []float64 {
+	equity := timeWeightedEquity(snapshots)
+	var returns []float64
+	prev := -1
+	for i := range equity {
+		if weekdaysOnly && isWeekend(snapshots[i].Time) {
+			continue
+		}
+		if prev >= 0 && equity[prev].IsPositive() {
+			returns = append(returns, equity[i].Div(equity[prev]).Sub(decimal.NewFromInt(1)).InexactFloat64())
+		}
+		prev = i
+	}
+	return returns
+}
+
+func isWeekend(t time.Time) bool {
+	return t.Weekday() == time.Saturday || t.Weekday() == time.Sunday
+}
+
+// calcSortinoRatio annualises the mean daily excess return over the downside deviation below the risk free rate.
+func calcSortinoRatio(returns []float64, annualRiskFree decimal.Decimal, periodsPerYear int, wg *sync.WaitGroup) decimal.Decimal {
+	defer wg.Done()
+	if len(returns) < 2 {
+		return decimal.Zero
+	}
+
+	rf := math.Pow(1+annualRiskFree.InexactFloat64(), 1/float64(periodsPerYear)) - 1
+	var excessSum, downsideSq float64
+	for _, r := range returns {
+		excess := r - rf
+		excessSum += excess
+		if excess < 0 {
+			downsideSq += excess * excess
+		}
+	}
+	downsideDev := math.Sqrt(downsideSq / float64(len(returns)))
+	if downsideDev == 0 {
+		return decimal.Zero
+	}
+	return decimal.NewFromFloat(excessSum / float64(len(returns)) / downsideDev * math.Sqrt(float64(periodsPerYear)))
+}
+
+// calcProfitFactor divides gross profit by gross loss of the closed trades. It is zero without losing trades.
+func calcProfitFactor(trades []trade, wg *sync.WaitGroup) decimal.Decimal {
+	defer wg.Done()
+	profit, loss := decimal.Zero, decimal.Zero
+	for _, tr := range trades {
+		pnl, ok := tradeNetPnL(tr)
+		if !ok {
+			continue
+		}
+		if pnl.IsPositive() {
+			profit = profit.Add(pnl)
+		} else {
+			loss = loss.Add(pnl.Abs())
+		}
+	}
+	if loss.IsZero() {
+		return decimal.Zero
+	}
+	return profit.Div(loss)
+}
+
+// calcCalmarAndMAR returns CAGR over max drawdown for the trailing 36 months (Calmar) and for the full run (MAR).
+func calcCalmarAndMAR(snapshots []types.PortfolioView, cagr, maxDrawdownPct decimal.Decimal, wg *sync.WaitGroup) (decimal.Decimal, decimal.Decimal) {
+	defer wg.Done()
+	if len(snapshots) == 0 {
+		return decimal.Zero, decimal.Zero
+	}
+
+	mar := decimal.Zero
+	if maxDrawdownPct.IsPositive() {
+		mar = cagr.Div(maxDrawdownPct)
+	}
+
+	from := snapshots[len(snapshots)-1].Time.AddDate(-3, 0, 0)
+	first := sort.Search(len(snapshots), func(i int) bool {
+		return !snapshots[i].Time.Before(from)
+	})
+	trailing := snapshots[first:]
+	trailingCAGR := calcCAGR(trailing, noopWaitGroup())
+	_, trailingDD, _ := calcDrawdownMetrics(trailing, noopWaitGroup())
+
+	calmar := decimal.Zero
+	if trailingDD.IsPositive() {
+		calmar = trailingCAGR.Div(trailingDD)
+	}
+	return calmar, mar
+}
+
+// calcUlcerIndex is the root mean square of the percentage drawdown of every snapshot, as a fraction.
+// The Ulcer Performance Index divides the CAGR in excess of the risk free rate by it.
+func calcUlcerIndex(snapshots []types.PortfolioView, cagr, annualRiskFree decimal.Decimal, wg *sync.WaitGroup) (decimal.Decimal, decimal.Decimal) {
+	defer wg.Done()
+	equity := timeWeightedEquity(snapshots)
+	if len(equity) == 0 {
+		return decimal.Zero, decimal.Zero
+	}
+
+	peak := 0.0
+	sumSq := 0.0
+	for _, e := range equity {
+		v := e.InexactFloat64()
+		if v > peak {
+			peak = v
+		}
+		if peak > 0 {
+			dd := (peak - v) / peak
+			sumSq += dd * dd
+		}
+	}
+	ulcer := math.Sqrt(sumSq / float64(len(equity)))
+	if ulcer == 0 {
+		return decimal.Zero, decimal.Zero
+	}
+	upi := (cagr.InexactFloat64() - annualRiskFree.InexactFloat64()) / ulcer
+	return decimal.NewFromFloat(ulcer), decimal.NewFromFloat(upi)
+}
+
+type returnDistribution struct {
+	omega     decimal.Decimal
+	tailRatio decimal.Decimal
+	skewness  decimal.Decimal
+	kurtosis  decimal.Decimal
+	// Historical VaR and CVaR at 95% over one snapshot period, as positive loss fractions
+	valueAtRisk    decimal.Decimal
+	conditionalVaR decimal.Decimal
+}
+
+// calcReturnDistribution describes the daily returns: Omega ratio at a zero threshold, tail ratio
+// (95th percentile over the absolute 5th percentile), skewness, excess kurtosis and historical VaR/CVaR at 95%.
+func calcReturnDistribution(returns []float64, wg *sync.WaitGroup) returnDistribution {
+	defer wg.Done()
+	var out returnDistribution
+	if len(returns) < 2 {
+		return out
+	}
+
+	var gains, losses float64
+	for _, r := range returns {
+		if r > 0 {
+			gains += r
+		} else {
+			losses -= r
+		}
+	}
+	if losses > 0 {
+		out.omega = decimal.NewFromFloat(gains / losses)
+	}
+
+	sorted := append([]float64(nil), returns...)
+	sort.Float64s(sorted)
+	p5, p95 := percentile(sorted, 0.05), percentile(sorted, 0.95)
+	if p5 != 0 {
+		out.tailRatio = decimal.NewFromFloat(math.Abs(p95) / math.Abs(p5))
+	}
+
+	m := mean(returns)
+	var m2, m3, m4 float64
+	for _, r := range returns {
+		d := r - m
+		m2 += d * d
+		m3 += d * d * d
+		m4 += d * d * d * d
+	}
+	n := float64(len(returns))
+	m2, m3, m4 = m2/n, m3/n, m4/n
+	if m2 > 0 {
+		out.skewness = decimal.NewFromFloat(m3 / math.Pow(m2, 1.5))
+		out.kurtosis = decimal.NewFromFloat(m4/(m2*m2) - 3)
+	}
+
+	if p5 < 0 {
+		out.valueAtRisk = decimal.NewFromFloat(-p5)
+	}
+	tailSum, tailCount := 0.0, 0
+	for _, r := range sorted {
+		if r > p5 {
+			break
+		}
+		tailSum += r
+		tailCount++
+	}
+	if tailCount > 0 && tailSum < 0 {
+		out.conditionalVaR = decimal.NewFromFloat(-tailSum / float64(tailCount))
+	}
+	return out
+}
+
+// calcExpectancyAndSQN measures closed trades in R, with 1R the average losing trade. Expectancy is the
+// average R per trade and SQN is sqrt(trades) * mean(R) / stddev(R).
+func calcExpectancyAndSQN(trades []trade, wg *sync.WaitGroup) (decimal.Decimal, decimal.Decimal) {
+	defer wg.Done()
+	var pnls []float64
+	lossSum, losses := 0.0, 0
+	for _, tr := range trades {
+		pnl, ok := tradeNetPnL(tr)
+		if !ok {
+			continue
+		}
+		v := pnl.InexactFloat64()
+		pnls = append(pnls, v)
+		if v < 0 {
+			lossSum -= v
+			losses++
+		}
+	}
+	if len(pnls) == 0 || losses == 0 {
+		return decimal.Zero, decimal.Zero
+	}
+
+	oneR := lossSum / float64(losses)
+	rs := make([]float64, len(pnls))
+	for i, v := range pnls {
+		rs[i] = v / oneR
+	}
+
+	expectancy := mean(rs)
+	sqn := 0.0
+	if sd := stdDev(rs); sd > 0 {
+		sqn = math.Sqrt(float64(len(rs))) * expectancy / sd
+	}
+	return decimal.NewFromFloat(expectancy), decimal.NewFromFloat(sqn)
+}
+
+func printRiskMetrics(report *Report) {
+	pct := func(d decimal.Decimal) float64 { return d.Mul(decimal.NewFromInt(100)).InexactFloat64() }
+
+	fmt.Printf("Calmar Ratio (36m):    %.2f\n", report.CalmarRatio.InexactFloat64())
+	fmt.Printf("MAR Ratio:             %.2f\n", report.MARRatio.InexactFloat64())
+	fmt.Printf("Ulcer Index:           %.2f%%\n", pct(report.UlcerIndex))
+	fmt.Printf("Ulcer Perf. Index:     %.2f\n", report.UlcerPerformanceIndex.InexactFloat64())
+	fmt.Printf("Omega Ratio:           %.2f\n", report.OmegaRatio.InexactFloat64())
+	fmt.Printf("Tail Ratio:            %.2f\n", report.TailRatio.InexactFloat64())
+	fmt.Printf("Skewness:              %.2f\n", report.Skewness.InexactFloat64())
+	fmt.Printf("Excess Kurtosis:       %.2f\n", report.Kurtosis.InexactFloat64())
+	fmt.Printf("%-23s%.2f%%\n", report.ReturnPeriod+" VaR 95%:", pct(report.ValueAtRisk95))
+	fmt.Printf("%-23s%.2f%%\n", report.ReturnPeriod+" CVaR 95%:", pct(report.ConditionalVaR95))
+	fmt.Printf("Expectancy (R):        %.2f\n", report.Expectancy.InexactFloat64())
+	fmt.Printf("SQN:                   %.2f\n", report.SQN.InexactFloat64())
+	fmt.Printf("Periods per Year:      %d\n", report.AnnualizationPeriods)
+}
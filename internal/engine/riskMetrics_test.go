@@ -0,0 +1,145 @@
+package engine
+
+import (
+	"backtester/types"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestPeriodsFor(t *testing.T) {
+	tests := []struct {
+		name      string
+		config    *ReportingConfig
+		assetType types.AssetType
+		want      int
+	}{
+		{name: "stocks default to trading days", config: &ReportingConfig{}, assetType: types.AssetTypeStock, want: 252},
+		{name: "etfs default to trading days", config: &ReportingConfig{}, assetType: types.AssetTypeEtf, want: 252},
+		{name: "crypto defaults to calendar days", config: &ReportingConfig{}, assetType: types.AssetTypeCrypto, want: 365},
+		{name: "override", config: (&ReportingConfig{}).AnnualizationPeriods(types.AssetTypeStock, 260), assetType: types.AssetTypeStock, want: 260},
+		{name: "override of another type", config: (&ReportingConfig{}).AnnualizationPeriods(types.AssetTypeStock, 260), assetType: types.AssetTypeCrypto, want: 365},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.config.periodsFor(tt.assetType); got != tt.want {
+				t.Errorf("got=%d, want=%d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDailyReturnsWeekdaysOnly(t *testing.T) {
+	friday := time.Date(2020, 1, 3, 0, 0, 0, 0, time.UTC)
+	snapshots := []types.PortfolioView{
+		newPv(friday, "1000"),
+		newPv(friday.AddDate(0, 0, 1), "1100"),
+		newPv(friday.AddDate(0, 0, 2), "1200"),
+		newPv(friday.AddDate(0, 0, 3), "1300"),
+	}
+
+	if got := dailyReturns(snapshots, false); len(got) != 3 {
+		t.Errorf("calendar days: got %d returns, want 3", len(got))
+	}
+	got := dailyReturns(snapshots, true)
+	if len(got) != 1 || math.Abs(got[0]-0.3) > 1e-9 {
+		t.Errorf("weekdays only: got=%v, want [0.3]", got)
+	}
+}
+
+func TestCalcSortinoRatio(t *testing.T) {
+	returns := []float64{0.02, -0.01, 0.03, -0.02}
+
+	// mean 0.005, downside deviation sqrt((0.01² + 0.02²) / 4)
+	want := 0.005 / math.Sqrt(0.0005/4) * math.Sqrt(252)
+	got := calcSortinoRatio(returns, decimal.Zero, tradingDaysPerYear, noopWaitGroup())
+	if math.Abs(got.InexactFloat64()-want) > 1e-6 {
+		t.Errorf("got=%s, want=%f", got, want)
+	}
+
+	if got := calcSortinoRatio([]float64{0.01, 0.02}, decimal.Zero, tradingDaysPerYear, noopWaitGroup()); !got.IsZero() {
+		t.Errorf("no downside: got=%s, want 0", got)
+	}
+}
+
+func TestTradeBasedRiskMetrics(t *testing.T) {
+	at := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	closedTrade := func(entry, exit string) trade {
+		buy := newExecutionReport("AAA", types.SideTypeBuy, newFill(at, entry, "1", "0"))
+		sell := newExecutionReport("AAA", types.SideTypeSell, newFill(at.Add(time.Hour), exit, "1", "0"))
+		return trade{buy: &buy, sell: &sell, qty: decimal.NewFromInt(1)}
+	}
+	open := newExecutionReport("AAA", types.SideTypeBuy, newFill(at, "100", "1", "0"))
+	trades := []trade{
+		closedTrade("100", "400"),
+		closedTrade("100", "0"),
+		closedTrade("100", "50"),
+		closedTrade("100", "150"),
+		{buy: &open, qty: decimal.NewFromInt(1)},
+	}
+
+	// 350 gross profit over 150 gross loss
+	if got := calcProfitFactor(trades, noopWaitGroup()); !got.Round(4).Equal(decimal.RequireFromString("2.3333")) {
+		t.Errorf("profit factor: got=%s, want 2.3333", got.Round(4))
+	}
+
+	// 1R = 75, so the trades are 4R, -4/3R, -2/3R and 2/3R
+	expectancy, sqn := calcExpectancyAndSQN(trades, noopWaitGroup())
+	if !expectancy.Round(4).Equal(decimal.RequireFromString("0.6667")) {
+		t.Errorf("expectancy: got=%s, want 0.6667", expectancy.Round(4))
+	}
+	if !sqn.Round(4).Equal(decimal.RequireFromString("0.5620")) {
+		t.Errorf("sqn: got=%s, want 0.5620", sqn.Round(4))
+	}
+
+	if got := calcProfitFactor(trades[:1], noopWaitGroup()); !got.IsZero() {
+		t.Errorf("no losers: got=%s, want 0", got)
+	}
+}
+
+func TestCalcReturnDistribution(t *testing.T) {
+	// -0.05 to 0.14 in steps of 0.01
+	var returns []float64
+	for i := -5; i < 15; i++ {
+		returns = append(returns, float64(i)/100)
+	}
+
+	got := calcReturnDistribution(returns, noopWaitGroup())
+	for name, tt := range map[string]struct {
+		got  decimal.Decimal
+		want float64
+	}{
+		"omega":    {got.omega, 1.05 / 0.15},
+		"skewness": {got.skewness, 0},
+		"var":      {got.valueAtRisk, 0.0405},
+		"cvar":     {got.conditionalVaR, 0.05},
+		"tail":     {got.tailRatio, 0.1305 / 0.0405},
+		// Discrete uniform: -6(n²+1) / 5(n²-1)
+		"kurtosis": {got.kurtosis, -6.0 * 401 / (5 * 399)},
+	} {
+		if math.Abs(tt.got.InexactFloat64()-tt.want) > 1e-4 {
+			t.Errorf("%s: got=%s, want=%f", name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestCalcUlcerIndex(t *testing.T) {
+	base := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	snapshots := []types.PortfolioView{
+		newPv(base, "100"),
+		newPv(base.AddDate(0, 0, 1), "80"),
+		newPv(base.AddDate(0, 0, 2), "100"),
+	}
+
+	ulcer, upi := calcUlcerIndex(snapshots, decimal.RequireFromString("0.10"), decimal.Zero, noopWaitGroup())
+	wantUlcer := math.Sqrt(0.04 / 3)
+	if math.Abs(ulcer.InexactFloat64()-wantUlcer) > 1e-9 {
+		t.Errorf("ulcer: got=%s, want=%f", ulcer, wantUlcer)
+	}
+	if math.Abs(upi.InexactFloat64()-0.10/wantUlcer) > 1e-6 {
+		t.Errorf("upi: got=%s, want=%f", upi, 0.10/wantUlcer)
+	}
+}
@@ -0,0 +1,268 @@
+package engine
+
+import (
+	"backtester/types"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestRiskManager_Check(t *testing.T) {
+	at := time.Date(2020, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	buy := func(ticker, price, qty string) types.Order {
+		return types.NewOrder(ticker, decimal.RequireFromString(price), decimal.RequireFromString(qty), types.TypeLimit, types.SideTypeBuy, "test", at)
+	}
+	sell := func(ticker, price, qty string) types.Order {
+		return types.NewOrder(ticker, decimal.RequireFromString(price), decimal.RequireFromString(qty), types.TypeLimit, types.SideTypeSell, "test", at)
+	}
+
+	tests := []struct {
+		name      string
+		rm        *RiskManager
+		view      types.PortfolioView
+		orders    []types.Order
+		wantQty   []string
+		wantCodes []types.RejectCode
+	}{
+		{
+			name:    "no limits passes orders unchanged",
+			rm:      NewRiskManager(),
+			view:    newPv(at, "10000"),
+			orders:  []types.Order{buy("AAA", "100", "50")},
+			wantQty: []string{"50"},
+		},
+		{
+			name:    "max notional resizes the order",
+			rm:      NewRiskManager().MaxNotionalPerOrder(decimal.NewFromInt(1050)),
+			view:    newPv(at, "10000"),
+			orders:  []types.Order{buy("AAA", "100", "50")},
+			wantQty: []string{"10"},
+		},
+		{
+			name:    "max position weight accounts for the existing position",
+			rm:      NewRiskManager().MaxPositionWeight(decimal.RequireFromString("0.2")),
+			view:    pvWithPosition(at, "9000", "AAA", "10", "100"),
+			orders:  []types.Order{buy("AAA", "100", "50")},
+			wantQty: []string{"10"},
+		},
+		{
+			name:      "max position weight rejects when already at the cap",
+			rm:        NewRiskManager().MaxPositionWeight(decimal.RequireFromString("0.1")),
+			view:      pvWithPosition(at, "9000", "AAA", "10", "100"),
+			orders:    []types.Order{buy("AAA", "100", "1")},
+			wantCodes: []types.RejectCode{RejectMaxPositionWeight},
+		},
+		{
+			name:    "reducing orders always pass",
+			rm:      NewRiskManager().MaxPositionWeight(decimal.RequireFromString("0.01")).MaxOrdersPerDay(0),
+			view:    pvWithPosition(at, "9000", "AAA", "10", "100"),
+			orders:  []types.Order{sell("AAA", "100", "10")},
+			wantQty: []string{"10"},
+		},
+		{
+			name:    "gross exposure applies to the batch as a whole",
+			rm:      NewRiskManager().MaxGrossExposure(decimal.RequireFromString("0.5")),
+			view:    newPv(at, "10000"),
+			orders:  []types.Order{buy("AAA", "100", "30"), buy("BBB", "100", "30")},
+			wantQty: []string{"30", "20"},
+		},
+		{
+			name: "net exposure allows a short to offset a long",
+			// equity 11000, cap 1100: long 1000 - short 2000 = -1000 fits
+			rm:      NewRiskManager().MaxNetExposure(decimal.RequireFromString("0.1")),
+			view:    pvWithPosition(at, "10000", "AAA", "10", "100"),
+			orders:  []types.Order{sell("BBB", "100", "20")},
+			wantQty: []string{"20"},
+		},
+		{
+			name: "net exposure resizes a short that overshoots",
+			// long 1000 - short 3000 = -2000 breaches; max short is (1100 + 1000) / 100 = 21
+			rm:      NewRiskManager().MaxNetExposure(decimal.RequireFromString("0.1")),
+			view:    pvWithPosition(at, "10000", "AAA", "10", "100"),
+			orders:  []types.Order{sell("BBB", "100", "30")},
+			wantQty: []string{"21"},
+		},
+		{
+			name:      "max orders per day",
+			rm:        NewRiskManager().MaxOrdersPerDay(1),
+			view:      newPv(at, "10000"),
+			orders:    []types.Order{buy("AAA", "10", "1"), buy("BBB", "10", "1")},
+			wantQty:   []string{"1"},
+			wantCodes: []types.RejectCode{RejectMaxOrdersPerDay},
+		},
+		{
+			name:      "missing price is rejected",
+			rm:        NewRiskManager().MaxNotionalPerOrder(decimal.NewFromInt(100)),
+			view:      newPv(at, "10000"),
+			orders:    []types.Order{buy("AAA", "0", "1")},
+			wantCodes: []types.RejectCode{RejectNoReferencePrice},
+		},
+		{
+			name:    "missing price passes limits that do not value the order",
+			rm:      NewRiskManager().MaxOrdersPerDay(5).MaxDrawdown(decimal.RequireFromString("0.2")),
+			view:    newPv(at, "10000"),
+			orders:  []types.Order{buy("AAA", "0", "1")},
+			wantQty: []string{"1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_ = tt.rm.Init(nil, nil)
+			accepted, rejected := tt.rm.Check(tt.orders, tt.view)
+
+			if len(accepted) != len(tt.wantQty) {
+				t.Fatalf("accepted %d orders, want %d (%v)", len(accepted), len(tt.wantQty), accepted)
+			}
+			for i, want := range tt.wantQty {
+				if !accepted[i].Quantity.Equal(decimal.RequireFromString(want)) {
+					t.Errorf("order %d qty = %s, want %s", i, accepted[i].Quantity, want)
+				}
+			}
+			if len(rejected) != len(tt.wantCodes) {
+				t.Fatalf("rejected %d orders, want %d (%v)", len(rejected), len(tt.wantCodes), rejected)
+			}
+			for i, want := range tt.wantCodes {
+				if rejected[i].Status != types.OrderRejected || rejected[i].RejectCode != want {
+					t.Errorf("rejection %d = %s/%s, want %s/%s", i, rejected[i].Status, rejected[i].RejectCode, types.OrderRejected, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRiskManager_AssetTypeCap(t *testing.T) {
+	at := time.Date(2020, 1, 1, 10, 0, 0, 0, time.UTC)
+	rm := NewRiskManager().MaxAssetTypeWeight(types.AssetTypeCrypto, decimal.RequireFromString("0.1"))
+	_ = rm.Init(nil, map[string]*types.Asset{
+		"BTC": {Ticker: "BTC", Type: types.AssetTypeCrypto},
+		"ETH": {Ticker: "ETH", Type: types.AssetTypeCrypto},
+		"AAA": {Ticker: "AAA", Type: types.AssetTypeStock},
+	})
+
+	view := pvWithPosition(at, "9500", "BTC", "1", "500")
+	orders := []types.Order{
+		types.NewOrder("ETH", decimal.NewFromInt(100), decimal.NewFromInt(10), types.TypeLimit, types.SideTypeBuy, "", at),
+		types.NewOrder("AAA", decimal.NewFromInt(100), decimal.NewFromInt(10), types.TypeLimit, types.SideTypeBuy, "", at),
+	}
+
+	accepted, rejected := rm.Check(orders, view)
+	if len(rejected) != 0 {
+		t.Fatalf("unexpected rejections: %v", rejected)
+	}
+	if !accepted[0].Quantity.Equal(decimal.NewFromInt(5)) {
+		t.Errorf("ETH qty = %s, want 5", accepted[0].Quantity)
+	}
+	if !accepted[1].Quantity.Equal(decimal.NewFromInt(10)) {
+		t.Errorf("AAA qty = %s, want 10", accepted[1].Quantity)
+	}
+
+	resized, halt := rm.lastDecisions()
+	if len(resized) != 1 || resized[0].Order.Ticker != "ETH" || !resized[0].OriginalQuantity.Equal(decimal.NewFromInt(10)) ||
+		!resized[0].Order.Quantity.Equal(decimal.NewFromInt(5)) || resized[0].Code != RejectMaxAssetTypeWeight {
+		t.Errorf("resized = %+v, want ETH from 10 to 5 by %s", resized, RejectMaxAssetTypeWeight)
+	}
+	if halt != nil {
+		t.Errorf("halt = %+v, want none", halt)
+	}
+}
+
+func TestRiskManager_MaxDrawdownKillSwitch(t *testing.T) {
+	at := time.Date(2020, 1, 1, 10, 0, 0, 0, time.UTC)
+	rm := NewRiskManager().MaxDrawdown(decimal.RequireFromString("0.2"))
+	order := types.NewOrder("AAA", decimal.NewFromInt(10), decimal.NewFromInt(1), types.TypeLimit, types.SideTypeBuy, "", at)
+
+	if _, rejected := rm.Check([]types.Order{order}, newPv(at, "1000")); len(rejected) != 0 {
+		t.Fatalf("expected order to pass at peak, got %v", rejected)
+	}
+	// 25% below the peak trips the switch
+	rm.Check(nil, newPv(at.Add(time.Hour), "750"))
+	if _, halt := rm.lastDecisions(); halt == nil || !halt.Equity.Equal(decimal.NewFromInt(750)) || !halt.PeakEquity.Equal(decimal.NewFromInt(1000)) {
+		t.Fatalf("halt = %+v, want equity 750 below a peak of 1000", halt)
+	}
+
+	// Recovering does not reset it
+	accepted, rejected := rm.Check([]types.Order{order}, newPv(at.Add(2*time.Hour), "1000"))
+	if len(accepted) != 0 || len(rejected) != 1 || rejected[0].RejectCode != RejectMaxDrawdown {
+		t.Fatalf("expected kill switch rejection, got accepted=%v rejected=%v", accepted, rejected)
+	}
+	if _, halt := rm.lastDecisions(); halt != nil {
+		t.Errorf("halt reported again: %+v", halt)
+	}
+}
+
+func TestRiskManager_MaxDrawdownIgnoresCashFlows(t *testing.T) {
+	at := time.Date(2020, 1, 1, 10, 0, 0, 0, time.UTC)
+	rm := NewRiskManager().MaxDrawdown(decimal.RequireFromString("0.2"))
+	order := types.NewOrder("AAA", decimal.NewFromInt(10), decimal.NewFromInt(1), types.TypeLimit, types.SideTypeBuy, "", at)
+	withContributions := func(pv types.PortfolioView, contributions string) types.PortfolioView {
+		pv.NetContributions = decimal.RequireFromString(contributions)
+		return pv
+	}
+
+	rm.Check(nil, withContributions(newPv(at, "1000"), "1000"))
+	// Withdrawing half the equity is no loss
+	rm.Check(nil, withContributions(newPv(at.Add(time.Hour), "500"), "500"))
+	// A deposit is no gain, so the following 10% loss stays 10% below the peak
+	rm.Check(nil, withContributions(newPv(at.Add(2*time.Hour), "2500"), "2500"))
+	accepted, rejected := rm.Check([]types.Order{order}, withContributions(newPv(at.Add(3*time.Hour), "2250"), "2500"))
+	if len(accepted) != 1 || len(rejected) != 0 {
+		t.Fatalf("expected the order to pass, got accepted=%v rejected=%v", accepted, rejected)
+	}
+
+	// A real 25% loss still trips the switch
+	rm.Check(nil, withContributions(newPv(at.Add(4*time.Hour), "1875"), "2500"))
+	if _, halt := rm.lastDecisions(); halt == nil || !halt.PeakEquity.Equal(decimal.NewFromInt(1000)) || !halt.Equity.Equal(decimal.NewFromInt(750)) {
+		t.Fatalf("halt = %+v, want equity 750 below a peak of 1000", halt)
+	}
+}
+
+func TestBacktest_RiskRejectionsAreRecorded(t *testing.T) {
+	testStrat := allocatorStrategy{callAllocator: 1}
+	testAllocator := &orderAllocator{
+		orders: []types.Order{types.NewOrder("AAPL", decimal.NewFromInt(100), decimal.NewFromInt(1), types.TypeLimit, types.SideTypeBuy, "", time.UnixMilli(0))},
+	}
+	testBroker := &mockBroker{}
+
+	engine := mockEngine(&testStrat, mockInstrument(), testAllocator, testBroker).
+		WithRiskManager(NewRiskManager().MaxNotionalPerOrder(decimal.NewFromInt(10)))
+
+	if _, err := engine.Run(); err != nil {
+		t.Fatalf("Error running engine: %v", err)
+	}
+	if len(engine.portfolio.rejections) == 0 {
+		t.Fatalf("expected risk rejections to be recorded")
+	}
+	for _, r := range engine.portfolio.rejections {
+		if r.RejectCode != RejectMaxNotionalPerOrder {
+			t.Errorf("reject code = %s, want %s", r.RejectCode, RejectMaxNotionalPerOrder)
+		}
+	}
+}
+
+// Helper functions
+func pvWithPosition(t time.Time, cash, ticker, qty, price string) types.PortfolioView {
+	pv := newPv(t, cash)
+	pv.Positions[ticker] = types.PositionSnapshot{
+		Ticker:          ticker,
+		Quantity:        decimal.RequireFromString(qty),
+		AvgEntryPrice:   decimal.RequireFromString(price),
+		LastMarketPrice: decimal.RequireFromString(price),
+	}
+	return pv
+}
+
+// orderAllocator returns the same orders on every call.
+type orderAllocator struct {
+	orders []types.Order
+}
+
+func (a *orderAllocator) Init(api PortfolioApi) error {
+	return nil
+}
+
+func (a *orderAllocator) Allocate(signals map[string][]types.Signal, view types.PortfolioView) []types.Order {
+	return a.orders
+}
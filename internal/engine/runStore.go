@@ -0,0 +1,115 @@
+package engine
+
+import (
+	"backtester/types"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// runStore keeps finished runs so their results can be compared across code changes.
+type runStore interface {
+	SaveRun(run types.Run, ctx context.Context) error
+}
+
+// WithRunStore saves the run with its parameters, metrics, trades and equity curve to store after
+// the report is generated. The report name names the run and tags group it with others.
+func (e *Engine) WithRunStore(store runStore, tags ...string) *Engine {
+	e.runStore = store
+	e.runTags = tags
+	return e
+}
+
+// WithParameters records the parameters the engine was built with, for the stored run. The optimizer
+// sets them for every combination.
+func (e *Engine) WithParameters(params Params) *Engine {
+	e.params = params
+	return e
+}
+
+func (e *Engine) saveRun(report *Report, start, end time.Time) error {
+	run := types.Run{
+		RunID:      e.runID,
+		Name:       e.reportingConfig.reportName,
+		Tags:       e.runTags,
+		Start:      start,
+		End:        end,
+		CreatedAt:  time.Now().UTC(),
+		Parameters: make(map[string]string, len(e.params)),
+		Metrics:    reportMetricValues(report),
+	}
+	if e.strategy != nil {
+		run.Strategy = fmt.Sprintf("%T", e.strategy)
+	}
+	for name, value := range e.params {
+		run.Parameters[name] = fmt.Sprint(value)
+	}
+	for _, record := range report.Trades {
+		run.Trades = append(run.Trades, runTrade(record, end))
+	}
+	for _, snap := range e.portfolio.snapshots {
+		run.Equity = append(run.Equity, types.EquityPoint{
+			Time:             snap.Time,
+			Equity:           portfolioValue(snap),
+			Cash:             snap.Cash,
+			NetContributions: snap.NetContributions,
+		})
+	}
+	return e.runStore.SaveRun(run, context.Background())
+}
+
+// runTrade converts a trade record, letting open trades exit at end.
+func runTrade(record TradeRecord, end time.Time) types.RunTrade {
+	exit := record.ExitTime
+	if record.Open {
+		exit = end
+	}
+	return types.RunTrade{
+		Ticker:     record.Ticker,
+		Side:       record.Side,
+		Open:       record.Open,
+		EntryTime:  record.EntryTime,
+		ExitTime:   exit,
+		Quantity:   record.Quantity,
+		EntryPrice: record.EntryPrice,
+		ExitPrice:  record.ExitPrice,
+		Fees:       record.Fees,
+		NetPnL:     record.NetPnL,
+		Return:     record.Return,
+		Reason:     record.Reason,
+		ExitReason: record.ExitReason,
+	}
+}
+
+// reportMetricValues returns the numeric metrics of the report under their JSON report names.
+// Durations are stored in days.
+func reportMetricValues(report *Report) map[string]decimal.Decimal {
+	raw, err := json.Marshal(newJSONMetrics(report))
+	if err != nil {
+		return make(map[string]decimal.Decimal)
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return make(map[string]decimal.Decimal)
+	}
+	return metricValues(fields)
+}
+
+// metricValues keeps the numeric fields of the metrics object of a JSON report.
+func metricValues(fields map[string]json.RawMessage) map[string]decimal.Decimal {
+	metrics := make(map[string]decimal.Decimal)
+	for name, field := range fields {
+		if name == "run_id" || name == "benchmark_name" || string(field) == "null" {
+			continue
+		}
+		// Decimals are quoted and numbers are not, anything else is not a metric
+		var value decimal.Decimal
+		if err := json.Unmarshal(field, &value); err == nil {
+			metrics[name] = value
+		}
+	}
+	return metrics
+}
@@ -0,0 +1,80 @@
+package engine
+
+import (
+	"backtester/types"
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+type mockRunStore struct {
+	runs []types.Run
+	err  error
+}
+
+func (s *mockRunStore) SaveRun(run types.Run, ctx context.Context) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.runs = append(s.runs, run)
+	return nil
+}
+
+var storeErr = errors.New("connection refused")
+
+func TestEngine_WithRunStore(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		wantErr error
+	}{
+		{"should save run", nil, nil},
+		{"should fail run on store error", storeErr, storeErr},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &mockRunStore{err: tt.err}
+			engine := mockEngine(&alternatingStrategy{}, mockInstrument(), &signalAllocator{}, &fillingBroker{}).
+				WithRunStore(store, "baseline", "v2").
+				WithParameters(Params{"lookback": 4}).
+				WithRunID("run-1").
+				Quiet()
+
+			report, err := engine.Run()
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Run() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if len(store.runs) != 1 {
+				t.Fatalf("saved %d runs, want 1", len(store.runs))
+			}
+
+			run := store.runs[0]
+			if run.RunID != "run-1" || run.Strategy != "*engine.alternatingStrategy" || !slices.Equal(run.Tags, []string{"baseline", "v2"}) {
+				t.Errorf("run = %s %s %v, want run-1 *engine.alternatingStrategy [baseline v2]", run.RunID, run.Strategy, run.Tags)
+			}
+			if run.Parameters["lookback"] != "4" {
+				t.Errorf("parameters = %v, want lookback=4", run.Parameters)
+			}
+			if !run.Metrics["net_profit"].Equal(report.NetProfit) || !run.Metrics["total_trades"].Equal(decimal.NewFromInt(int64(report.TotalTrades))) {
+				t.Errorf("metrics net_profit = %s total_trades = %s, want %s and %d", run.Metrics["net_profit"], run.Metrics["total_trades"], report.NetProfit, report.TotalTrades)
+			}
+			if _, ok := run.Metrics["run_id"]; ok {
+				t.Error("metrics contain run_id")
+			}
+			if len(run.Trades) != len(report.Trades) || len(run.Equity) != len(engine.portfolio.snapshots) {
+				t.Errorf("got %d trades and %d equity points, want %d and %d", len(run.Trades), len(run.Equity), len(report.Trades), len(engine.portfolio.snapshots))
+			}
+			for _, trade := range run.Trades {
+				if trade.ExitTime.IsZero() {
+					t.Errorf("trade %+v has no exit time", trade)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,202 @@
+package engine
+
+import (
+	"backtester/types"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+type ConflictResolution string
+
+const (
+	// ResolvePassThrough hands every signal to the allocator unchanged.
+	ResolvePassThrough ConflictResolution = "PASS_THROUGH"
+	// ResolveNet sums buy strengths minus sell strengths into a single signal, or drops all on a tie.
+	ResolveNet ConflictResolution = "NET"
+	// ResolveStrongest keeps the signal with the highest strength, the earliest one on a tie.
+	ResolveStrongest ConflictResolution = "STRONGEST"
+	// ResolveFirst keeps the earliest signal.
+	ResolveFirst ConflictResolution = "FIRST"
+	// ResolveIgnore drops all signals of a ticker when they disagree on side and keeps the first when they agree.
+	ResolveIgnore ConflictResolution = "IGNORE"
+)
+
+// DroppedSignal is a signal that never reached the allocator, kept for reporting.
+type DroppedSignal struct {
+	Signal types.Signal
+	Reason string
+	Time   time.Time
+}
+
+// signalResolver sits between the strategy and the allocator. It removes expired signals, carries
+// signals with a future expiry over to later bars and reduces conflicting signals per ticker to one.
+type signalResolver struct {
+	mode    ConflictResolution
+	pending map[string][]types.Signal
+	dropped []DroppedSignal
+}
+
+func newSignalResolver(mode ConflictResolution) *signalResolver {
+	return &signalResolver{
+		mode:    mode,
+		pending: make(map[string][]types.Signal),
+	}
+}
+
+func (r *signalResolver) resolve(signals map[string][]types.Signal, curTime time.Time) map[string][]types.Signal {
+	out := make(map[string][]types.Signal, len(signals)+len(r.pending))
+
+	for ticker, sigs := range signals {
+		if len(sigs) == 0 {
+			continue
+		}
+		live := make([]types.Signal, 0, len(sigs))
+		for _, sig := range sigs {
+			if isExpired(sig, curTime) {
+				r.drop(sig, "expired before allocation", curTime)
+				continue
+			}
+			live = append(live, sig)
+		}
+
+		resolved := r.resolveTicker(live, curTime)
+		out[ticker] = resolved
+
+		// A newer signal always supersedes the ones carried over for the ticker
+		delete(r.pending, ticker)
+		for _, sig := range resolved {
+			if sig.ExpiresAt.After(curTime) {
+				r.pending[ticker] = append(r.pending[ticker], sig)
+			}
+		}
+	}
+
+	for ticker, carried := range r.pending {
+		if len(signals[ticker]) > 0 {
+			continue
+		}
+		live := carried[:0]
+		for _, sig := range carried {
+			if sig.ExpiresAt.After(curTime) {
+				live = append(live, sig)
+			}
+		}
+		if len(live) == 0 {
+			delete(r.pending, ticker)
+			continue
+		}
+		r.pending[ticker] = live
+		out[ticker] = append([]types.Signal(nil), live...)
+	}
+
+	// Keep tickers that closed a bar without signals, the allocator has always seen those
+	for ticker, sigs := range signals {
+		if _, ok := out[ticker]; !ok {
+			out[ticker] = sigs
+		}
+	}
+
+	return out
+}
+
+func (r *signalResolver) resolveTicker(sigs []types.Signal, curTime time.Time) []types.Signal {
+	if len(sigs) <= 1 || r.mode == ResolvePassThrough || r.mode == "" {
+		return sigs
+	}
+
+	// Keep the emission order as tie breaker
+	sort.SliceStable(sigs, func(i, j int) bool {
+		return sigs[i].CreatedAt.Before(sigs[j].CreatedAt)
+	})
+
+	switch r.mode {
+	case ResolveFirst:
+		r.dropAll(sigs[1:], "conflict: first signal wins", curTime)
+		return sigs[:1]
+
+	case ResolveStrongest:
+		best := 0
+		for i := range sigs {
+			if signalStrength(sigs[i]).GreaterThan(signalStrength(sigs[best])) {
+				best = i
+			}
+		}
+		for i := range sigs {
+			if i != best {
+				r.drop(sigs[i], "conflict: weaker than strongest signal", curTime)
+			}
+		}
+		return []types.Signal{sigs[best]}
+
+	case ResolveIgnore:
+		for _, sig := range sigs[1:] {
+			if sig.Side != sigs[0].Side {
+				r.dropAll(sigs, "conflict: signals disagree on side", curTime)
+				return nil
+			}
+		}
+		r.dropAll(sigs[1:], "conflict: duplicate signal", curTime)
+		return sigs[:1]
+
+	case ResolveNet:
+		net := decimal.Zero
+		for _, sig := range sigs {
+			if sig.Side == types.SideTypeSell {
+				net = net.Sub(signalStrength(sig))
+			} else {
+				net = net.Add(signalStrength(sig))
+			}
+		}
+		if net.IsZero() {
+			r.dropAll(sigs, "conflict: signals net out to zero", curTime)
+			return nil
+		}
+
+		side := types.SideTypeBuy
+		if net.IsNegative() {
+			side = types.SideTypeSell
+		}
+		// The strongest signal on the winning side provides price, reason and expiry
+		best := -1
+		for i, sig := range sigs {
+			if sig.Side == side && (best < 0 || signalStrength(sig).GreaterThan(signalStrength(sigs[best]))) {
+				best = i
+			}
+		}
+		netted := sigs[best]
+		netted.Strength = net.Abs()
+		netted.Reason = fmt.Sprintf("Net of %d signals: %s", len(sigs), netted.Reason)
+		for i := range sigs {
+			if i != best {
+				r.drop(sigs[i], "conflict: netted into combined signal", curTime)
+			}
+		}
+		return []types.Signal{netted}
+	}
+
+	return sigs
+}
+
+func (r *signalResolver) drop(sig types.Signal, reason string, at time.Time) {
+	r.dropped = append(r.dropped, DroppedSignal{Signal: sig, Reason: reason, Time: at})
+}
+
+func (r *signalResolver) dropAll(sigs []types.Signal, reason string, at time.Time) {
+	for _, sig := range sigs {
+		r.drop(sig, reason, at)
+	}
+}
+
+func isExpired(sig types.Signal, curTime time.Time) bool {
+	return !sig.ExpiresAt.IsZero() && !sig.ExpiresAt.After(curTime)
+}
+
+func signalStrength(sig types.Signal) decimal.Decimal {
+	if sig.Strength.IsZero() {
+		return decimal.NewFromInt(1)
+	}
+	return sig.Strength
+}
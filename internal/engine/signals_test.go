@@ -0,0 +1,154 @@
+package engine
+
+import (
+	"backtester/types"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestSignalResolver_ResolveConflicts(t *testing.T) {
+	at := time.UnixMilli(0).UTC()
+	buy := types.NewSignal("AAA", types.SideTypeBuy, decimal.NewFromInt(10), "breakout high", at)
+	sell := types.NewSignal("AAA", types.SideTypeSell, decimal.NewFromInt(9), "breakout low", at)
+
+	tests := []struct {
+		name        string
+		mode        ConflictResolution
+		signals     []types.Signal
+		wantSides   []types.Side
+		wantDropped int
+	}{
+		{
+			name:      "pass through keeps everything",
+			mode:      ResolvePassThrough,
+			signals:   []types.Signal{buy, sell},
+			wantSides: []types.Side{types.SideTypeBuy, types.SideTypeSell},
+		},
+		{
+			name:        "first wins",
+			mode:        ResolveFirst,
+			signals:     []types.Signal{sell, buy},
+			wantSides:   []types.Side{types.SideTypeSell},
+			wantDropped: 1,
+		},
+		{
+			name:        "strongest wins",
+			mode:        ResolveStrongest,
+			signals:     []types.Signal{buy, sell.WithStrength(decimal.NewFromInt(2))},
+			wantSides:   []types.Side{types.SideTypeSell},
+			wantDropped: 1,
+		},
+		{
+			name:        "strongest ties go to the first",
+			mode:        ResolveStrongest,
+			signals:     []types.Signal{buy, sell},
+			wantSides:   []types.Side{types.SideTypeBuy},
+			wantDropped: 1,
+		},
+		{
+			name:        "ignore drops disagreeing signals",
+			mode:        ResolveIgnore,
+			signals:     []types.Signal{buy, sell},
+			wantSides:   nil,
+			wantDropped: 2,
+		},
+		{
+			name:        "ignore collapses agreeing signals",
+			mode:        ResolveIgnore,
+			signals:     []types.Signal{buy, buy},
+			wantSides:   []types.Side{types.SideTypeBuy},
+			wantDropped: 1,
+		},
+		{
+			name:        "net of equal strengths drops all",
+			mode:        ResolveNet,
+			signals:     []types.Signal{buy, sell},
+			wantSides:   nil,
+			wantDropped: 2,
+		},
+		{
+			name:        "net picks the dominant side",
+			mode:        ResolveNet,
+			signals:     []types.Signal{buy.WithStrength(decimal.NewFromInt(3)), sell},
+			wantSides:   []types.Side{types.SideTypeBuy},
+			wantDropped: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newSignalResolver(tt.mode)
+			got := r.resolve(map[string][]types.Signal{"AAA": tt.signals}, at)
+
+			if len(got["AAA"]) != len(tt.wantSides) {
+				t.Fatalf("got %d signals, want %d: %v", len(got["AAA"]), len(tt.wantSides), got["AAA"])
+			}
+			for i, side := range tt.wantSides {
+				if got["AAA"][i].Side != side {
+					t.Errorf("signal %d side = %s, want %s", i, got["AAA"][i].Side, side)
+				}
+			}
+			if len(r.dropped) != tt.wantDropped {
+				t.Errorf("dropped %d signals, want %d", len(r.dropped), tt.wantDropped)
+			}
+		})
+	}
+}
+
+func TestSignalResolver_NetStrength(t *testing.T) {
+	at := time.UnixMilli(0).UTC()
+	r := newSignalResolver(ResolveNet)
+	got := r.resolve(map[string][]types.Signal{"AAA": {
+		types.NewSignal("AAA", types.SideTypeBuy, decimal.NewFromInt(10), "a", at).WithStrength(decimal.NewFromInt(3)),
+		types.NewSignal("AAA", types.SideTypeSell, decimal.NewFromInt(9), "b", at).WithStrength(decimal.NewFromInt(1)),
+	}}, at)
+
+	if !got["AAA"][0].Strength.Equal(decimal.NewFromInt(2)) {
+		t.Errorf("net strength = %s, want 2", got["AAA"][0].Strength)
+	}
+	if !got["AAA"][0].Price.Equal(decimal.NewFromInt(10)) {
+		t.Errorf("net price = %s, want 10", got["AAA"][0].Price)
+	}
+}
+
+func TestSignalResolver_Expiry(t *testing.T) {
+	at := time.UnixMilli(0).UTC()
+	r := newSignalResolver(ResolvePassThrough)
+
+	carried := types.NewSignal("AAA", types.SideTypeBuy, decimal.NewFromInt(10), "", at).WithExpiry(at.Add(2 * time.Minute))
+	stale := types.NewSignal("BBB", types.SideTypeBuy, decimal.NewFromInt(10), "", at).WithExpiry(at)
+
+	got := r.resolve(map[string][]types.Signal{"AAA": {carried}, "BBB": {stale}}, at)
+	if len(got["AAA"]) != 1 || len(got["BBB"]) != 0 {
+		t.Fatalf("unexpected signals at emission: %v", got)
+	}
+	if len(r.dropped) != 1 {
+		t.Fatalf("expected the stale signal to be dropped, got %v", r.dropped)
+	}
+
+	// Carried over on the next bar, even when the ticker closes a bar without signals
+	got = r.resolve(map[string][]types.Signal{"AAA": nil}, at.Add(time.Minute))
+	if len(got["AAA"]) != 1 {
+		t.Fatalf("expected carried signal, got %v", got)
+	}
+
+	// Gone once expired
+	got = r.resolve(map[string][]types.Signal{}, at.Add(2*time.Minute))
+	if len(got["AAA"]) != 0 {
+		t.Fatalf("expected expired signal to be gone, got %v", got)
+	}
+
+	// A newer signal supersedes the carried one
+	r.resolve(map[string][]types.Signal{"AAA": {carried}}, at)
+	newer := types.NewSignal("AAA", types.SideTypeSell, decimal.NewFromInt(9), "", at.Add(time.Minute))
+	got = r.resolve(map[string][]types.Signal{"AAA": {newer}}, at.Add(time.Minute))
+	if len(got["AAA"]) != 1 || got["AAA"][0].Side != types.SideTypeSell {
+		t.Fatalf("expected the newer signal only, got %v", got)
+	}
+	got = r.resolve(map[string][]types.Signal{}, at.Add(90*time.Second))
+	if len(got["AAA"]) != 0 {
+		t.Fatalf("expected superseded signal to be gone, got %v", got)
+	}
+}
@@ -0,0 +1,141 @@
+package engine
+
+import (
+	"backtester/types"
+	"fmt"
+	"time"
+)
+
+type snapshotMode string
+
+const (
+	snapshotDaily        snapshotMode = "DAILY"
+	snapshotInterval     snapshotMode = "INTERVAL"
+	snapshotBar          snapshotMode = "BAR"
+	snapshotSessionClose snapshotMode = "SESSION_CLOSE"
+	snapshotFill         snapshotMode = "FILL"
+)
+
+// SnapshotFrequency decides when the engine records a portfolio snapshot, which makes up the equity
+// curve of the report. The default is SnapshotDaily.
+type SnapshotFrequency struct {
+	mode     snapshotMode
+	every    time.Duration
+	location *time.Location
+	close    time.Duration
+}
+
+// SnapshotDaily records a snapshot at 00:00 every day.
+func SnapshotDaily() SnapshotFrequency {
+	return SnapshotFrequency{mode: snapshotDaily}
+}
+
+// SnapshotEvery records a snapshot every d, aligned to the clock, so every 15 minutes snapshots
+// at :00, :15, :30 and :45.
+func SnapshotEvery(d time.Duration) SnapshotFrequency {
+	return SnapshotFrequency{mode: snapshotInterval, every: max(d.Truncate(time.Minute), time.Minute)}
+}
+
+// SnapshotEveryBar records a snapshot on every minute a primary candle of any instrument closes.
+func SnapshotEveryBar() SnapshotFrequency {
+	return SnapshotFrequency{mode: snapshotBar}
+}
+
+// SnapshotAtSessionClose records a snapshot every day at the session close in the given location,
+// for example 16:00 in America/New_York. Assets carry no time zone, so the caller picks the location
+// and one close applies to every instrument of the run. A nil location is UTC.
+func SnapshotAtSessionClose(location *time.Location, hour, minute int) SnapshotFrequency {
+	if location == nil {
+		location = time.UTC
+	}
+	return SnapshotFrequency{
+		mode:     snapshotSessionClose,
+		location: location,
+		close:    time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute,
+	}
+}
+
+// SnapshotOnFill records a snapshot after every minute with a fill, and at the start and end of the
+// run so the equity curve covers the whole period.
+func SnapshotOnFill() SnapshotFrequency {
+	return SnapshotFrequency{mode: snapshotFill}
+}
+
+// WithSnapshotFrequency sets when portfolio snapshots are taken. Ratios on snapshot returns are
+// annualised with the periods per year of the asset types for daily snapshots and session closes,
+// and with the number of snapshots per year otherwise.
+func (e *Engine) WithSnapshotFrequency(frequency SnapshotFrequency) *Engine {
+	e.backtester.snapshotFrequency = frequency
+	return e
+}
+
+// daily reports whether there is one snapshot per calendar day.
+func (f SnapshotFrequency) daily() bool {
+	return f.mode == "" || f.mode == snapshotDaily || f.mode == snapshotSessionClose
+}
+
+// due reports whether a snapshot is taken at t. newBar is set when a primary candle closed at t and
+// filled when an order was filled at t.
+func (f SnapshotFrequency) due(t, start, end time.Time, newBar, filled bool) bool {
+	switch f.mode {
+	case snapshotInterval:
+		return t.Truncate(f.every).Equal(t)
+	case snapshotBar:
+		return newBar
+	case snapshotSessionClose:
+		// Compare the wall clock, days with a daylight saving change are not 24 hours long
+		local := t.In(f.location)
+		return time.Duration(local.Hour())*time.Hour+time.Duration(local.Minute())*time.Minute == f.close
+	case snapshotFill:
+		return filled || t.Equal(start) || t.Equal(end)
+	default:
+		return t.Hour() == 0 && t.Minute() == 0
+	}
+}
+
+// period names the time between two snapshots, which is the horizon of the return based risk
+// metrics such as VaR.
+func (f SnapshotFrequency) period() string {
+	switch f.mode {
+	case snapshotInterval:
+		if f.every%time.Hour == 0 {
+			return fmt.Sprintf("%dh", f.every/time.Hour)
+		}
+		return fmt.Sprintf("%dm", f.every/time.Minute)
+	case snapshotBar:
+		return "Per Bar"
+	case snapshotFill:
+		return "Per Fill"
+	default:
+		return "Daily"
+	}
+}
+
+func hasFills(executions []types.ExecutionReport) bool {
+	for _, exec := range executions {
+		if len(exec.Fills) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// returnPeriods returns how many snapshot returns make a year and whether weekend snapshots are
+// skipped. Intraday and fill driven snapshots use the number of snapshots per year of the run.
+func (e *Engine) returnPeriods(snapshots []types.PortfolioView) (int, bool) {
+	if e.backtester.snapshotFrequency.daily() {
+		periods := e.annualizationPeriods()
+		return periods, periods < calendarDaysPerYear
+	}
+
+	periods := e.annualizationPeriods()
+	if len(snapshots) < 2 {
+		return periods, false
+	}
+	span := snapshots[len(snapshots)-1].Time.Sub(snapshots[0].Time)
+	if span <= 0 {
+		return periods, false
+	}
+	years := span.Seconds() / secondsPerYear
+	return max(int(float64(len(snapshots)-1)/years+0.5), 1), false
+}
@@ -0,0 +1,106 @@
+package engine
+
+import (
+	"backtester/types"
+	"testing"
+	"time"
+)
+
+func TestSnapshotFrequency_Due(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("no time zone data: %v", err)
+	}
+	start := time.Date(2020, 1, 6, 0, 0, 0, 0, time.UTC)
+	end := start.Add(48 * time.Hour)
+
+	tests := []struct {
+		name      string
+		frequency SnapshotFrequency
+		at        time.Time
+		newBar    bool
+		filled    bool
+		want      bool
+	}{
+		{"default at midnight", SnapshotFrequency{}, start.Add(24 * time.Hour), false, false, true},
+		{"default intraday", SnapshotFrequency{}, start.Add(time.Hour), false, false, false},
+		{"daily", SnapshotDaily(), start, false, false, true},
+		{"interval aligned", SnapshotEvery(15 * time.Minute), start.Add(45 * time.Minute), false, false, true},
+		{"interval between", SnapshotEvery(15 * time.Minute), start.Add(50 * time.Minute), false, false, false},
+		{"interval below a minute", SnapshotEvery(time.Second), start.Add(time.Minute), false, false, true},
+		{"bar closed", SnapshotEveryBar(), start.Add(time.Minute), true, false, true},
+		{"no bar", SnapshotEveryBar(), start.Add(time.Minute), false, false, false},
+		// 16:00 in New York is 21:00 UTC in winter
+		{"session close", SnapshotAtSessionClose(newYork, 16, 0), start.Add(21 * time.Hour), false, false, true},
+		{"session close on daylight saving start", SnapshotAtSessionClose(newYork, 16, 0), time.Date(2024, 3, 10, 20, 0, 0, 0, time.UTC), false, false, true},
+		{"session close on daylight saving end", SnapshotAtSessionClose(newYork, 16, 0), time.Date(2024, 11, 3, 21, 0, 0, 0, time.UTC), false, false, true},
+		{"hour after session close on daylight saving end", SnapshotAtSessionClose(newYork, 16, 0), time.Date(2024, 11, 3, 22, 0, 0, 0, time.UTC), false, false, false},
+		{"session close in utc", SnapshotAtSessionClose(newYork, 16, 0), start.Add(16 * time.Hour), false, false, false},
+		{"fill", SnapshotOnFill(), start.Add(time.Hour), false, true, true},
+		{"no fill", SnapshotOnFill(), start.Add(time.Hour), true, false, false},
+		{"no fill at start", SnapshotOnFill(), start, false, false, true},
+		{"no fill at end", SnapshotOnFill(), end, false, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.frequency.due(tt.at, start, end, tt.newBar, tt.filled); got != tt.want {
+				t.Errorf("due(%s) = %v, want %v", tt.at, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSnapshotFrequency_Period(t *testing.T) {
+	tests := []struct {
+		frequency SnapshotFrequency
+		want      string
+	}{
+		{SnapshotFrequency{}, "Daily"},
+		{SnapshotAtSessionClose(nil, 16, 0), "Daily"},
+		{SnapshotEvery(15 * time.Minute), "15m"},
+		{SnapshotEvery(4 * time.Hour), "4h"},
+		{SnapshotEveryBar(), "Per Bar"},
+		{SnapshotOnFill(), "Per Fill"},
+	}
+	for _, tt := range tests {
+		if got := tt.frequency.period(); got != tt.want {
+			t.Errorf("period(%+v) = %q, want %q", tt.frequency, got, tt.want)
+		}
+	}
+}
+
+func TestBacktest_SnapshotFrequency(t *testing.T) {
+	start := time.UnixMilli(0).UTC()
+	feeds := Instruments(Instrument("AAPL", start, start.Add(3*24*time.Hour), types.Hour))
+
+	daily := mockEngine(&allocatorStrategy{}, feeds, &mockAllocator{}, &mockBroker{}).Quiet()
+	dailyReport, err := daily.Run()
+	if err != nil {
+		t.Fatalf("Error running engine: %v", err)
+	}
+
+	feeds = Instruments(Instrument("AAPL", start, start.Add(3*24*time.Hour), types.Hour))
+	hourly := mockEngine(&allocatorStrategy{}, feeds, &mockAllocator{}, &mockBroker{}).Quiet().
+		WithSnapshotFrequency(SnapshotEvery(6 * time.Hour))
+	hourlyReport, err := hourly.Run()
+	if err != nil {
+		t.Fatalf("Error running engine: %v", err)
+	}
+
+	if got, want := len(hourly.portfolio.snapshots), 4*len(daily.portfolio.snapshots)-3; got != want {
+		t.Errorf("got %d snapshots every 6 hours, want %d", got, want)
+	}
+	for _, snap := range hourly.portfolio.snapshots {
+		if snap.Time.Hour()%6 != 0 || snap.Time.Minute() != 0 {
+			t.Errorf("snapshot at %s is not on a 6 hour boundary", snap.Time)
+		}
+	}
+
+	if dailyReport.AnnualizationPeriods != tradingDaysPerYear {
+		t.Errorf("daily periods = %d, want %d", dailyReport.AnnualizationPeriods, tradingDaysPerYear)
+	}
+	// Four snapshots a day, every day of the year
+	if got := hourlyReport.AnnualizationPeriods; got < 1460 || got > 1462 {
+		t.Errorf("6 hour periods = %d, want about 1461", got)
+	}
+}
@@ -0,0 +1,129 @@
+package engine
+
+import (
+	"backtester/types"
+	"sort"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// TradeRecord is one round trip: the entry leg and the part of the exit leg that closed it.
+// Open trades have no exit, PnL or excursions.
+type TradeRecord struct {
+	Ticker string `json:"ticker"`
+	// Side of the entry, SideTypeBuy for longs and SideTypeSell for shorts
+	Side       types.Side      `json:"side"`
+	Open       bool            `json:"open"`
+	EntryTime  time.Time       `json:"entry_time"`
+	ExitTime   time.Time       `json:"exit_time,omitzero"`
+	Quantity   decimal.Decimal `json:"quantity"`
+	EntryPrice decimal.Decimal `json:"entry_price"`
+	ExitPrice  decimal.Decimal `json:"exit_price"`
+	Fees       decimal.Decimal `json:"fees"`
+	NetPnL     decimal.Decimal `json:"net_pnl"`
+	// Return is the net PnL as a fraction of the entry value
+	Return decimal.Decimal `json:"return"`
+
+	// Bars counts the execution feed candles the trade was open for
+	Bars     int           `json:"bars"`
+	Duration time.Duration `json:"duration_ns"`
+	// MAE and MFE are the largest move against and in favour of the trade while it was open, as
+	// positive fractions of the entry price, measured on the execution feed
+	MAE decimal.Decimal `json:"mae"`
+	MFE decimal.Decimal `json:"mfe"`
+
+	// Stop is the protective stop of the entry order and RMultiple the net PnL in units of the
+	// initial risk to that stop. Both are zero when the strategy did not set a stop.
+	Stop      decimal.Decimal `json:"stop"`
+	RMultiple decimal.Decimal `json:"r_multiple"`
+
+	Reason     string `json:"reason"`
+	ExitReason string `json:"exit_reason,omitempty"`
+}
+
+// tradeRecords turns the matched trades into round trips, ordered like the trades. candles is the
+// execution feed per ticker, used for the bar count and excursions.
+func tradeRecords(trades []trade, candles map[string][]types.Candle) []TradeRecord {
+	records := make([]TradeRecord, 0, len(trades))
+	for _, tr := range trades {
+		entry, exit := tradeLegs(tr)
+		record := TradeRecord{
+			Ticker:     entry.Ticker,
+			Side:       entry.Side,
+			EntryTime:  entry.ReportTime,
+			Quantity:   tr.qty,
+			EntryPrice: entry.AvgFillPrice,
+			Stop:       entry.StopPrice,
+			Reason:     entry.SignalReason,
+		}
+
+		if exit == nil {
+			// Part of the leg may have closed earlier trades, only the remaining quantity is open
+			record.Open = true
+			record.Quantity = tr.open
+			record.Fees = legFees(entry, tr.open)
+			records = append(records, record)
+			continue
+		}
+
+		record.ExitTime = exit.ReportTime
+		record.ExitPrice = exit.AvgFillPrice
+		record.ExitReason = exit.SignalReason
+		record.Duration = record.ExitTime.Sub(record.EntryTime)
+		record.Fees = tradeFees(tr)
+		record.NetPnL, _ = tradeNetPnL(tr)
+
+		if entryValue := record.EntryPrice.Mul(record.Quantity); entryValue.IsPositive() {
+			record.Return = record.NetPnL.Div(entryValue)
+		}
+		if risk := record.EntryPrice.Sub(record.Stop).Abs().Mul(record.Quantity); !record.Stop.IsZero() && risk.IsPositive() {
+			record.RMultiple = record.NetPnL.Div(risk)
+		}
+		record.Bars, record.MAE, record.MFE = excursions(record, candles[record.Ticker])
+		records = append(records, record)
+	}
+	return records
+}
+
+// excursions walks the candles from the entry up to, but not including, the candle the exit filled on.
+func excursions(record TradeRecord, candles []types.Candle) (int, decimal.Decimal, decimal.Decimal) {
+	if !record.EntryPrice.IsPositive() {
+		return 0, decimal.Zero, decimal.Zero
+	}
+
+	first := sort.Search(len(candles), func(i int) bool {
+		return !candles[i].Timestamp.Before(record.EntryTime)
+	})
+	bars := 0
+	high, low := record.EntryPrice, record.EntryPrice
+	for _, c := range candles[first:] {
+		if !c.Timestamp.Before(record.ExitTime) {
+			break
+		}
+		bars++
+		high = decimal.Max(high, c.High)
+		low = decimal.Min(low, c.Low)
+	}
+
+	up := high.Sub(record.EntryPrice).Div(record.EntryPrice)
+	down := record.EntryPrice.Sub(low).Div(record.EntryPrice)
+	if record.Side == types.SideTypeSell {
+		return bars, up, down
+	}
+	return bars, down, up
+}
+
+// tradeLegs returns the entry and exit leg of a trade, exit is nil while the trade is open.
+func tradeLegs(tr trade) (entry, exit *types.ExecutionReport) {
+	switch {
+	case tr.buy == nil:
+		return tr.sell, nil
+	case tr.sell == nil:
+		return tr.buy, nil
+	case tr.sell.ReportTime.Before(tr.buy.ReportTime):
+		return tr.sell, tr.buy
+	default:
+		return tr.buy, tr.sell
+	}
+}
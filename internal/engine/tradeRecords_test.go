@@ -0,0 +1,110 @@
+package engine
+
+import (
+	"backtester/types"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestTradeRecords(t *testing.T) {
+	at := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	leg := func(ticker string, side types.Side, when time.Time, price, qty, fee string) types.ExecutionReport {
+		report := newExecutionReport(ticker, side, newFill(when, price, qty, fee))
+		report.ReportTime = when
+		return report
+	}
+	buy := leg("AAA", types.SideTypeBuy, at, "10", "10", "2")
+	buy.StopPrice = decimal.NewFromInt(9)
+	buy.SignalReason = "breakout"
+	sell := leg("AAA", types.SideTypeSell, at.Add(time.Hour), "12", "5", "1")
+	shortEntry := leg("BBB", types.SideTypeSell, at, "20", "1", "0")
+	shortExit := leg("BBB", types.SideTypeBuy, at.Add(time.Hour), "15", "1", "0")
+	open := leg("CCC", types.SideTypeBuy, at, "5", "3", "0.6")
+
+	candle := func(minutes int, high, low string) types.Candle {
+		return types.Candle{
+			Timestamp: at.Add(time.Duration(minutes) * time.Minute),
+			High:      decimal.RequireFromString(high),
+			Low:       decimal.RequireFromString(low),
+		}
+	}
+	candles := map[string][]types.Candle{"AAA": {
+		candle(-15, "30", "1"),
+		candle(0, "11", "9"),
+		candle(15, "13", "8"),
+		candle(30, "12", "10"),
+		candle(45, "11", "10"),
+		// The exit fills on the open of this candle
+		candle(60, "20", "1"),
+	}}
+
+	records := tradeRecords([]trade{
+		{buy: &buy, sell: &sell, qty: decimal.NewFromInt(5)},
+		{buy: &shortExit, sell: &shortEntry, qty: decimal.NewFromInt(1)},
+		// One of the three bought was sold again
+		{buy: &open, qty: decimal.Zero, open: decimal.NewFromInt(2)},
+	}, candles)
+
+	long := records[0]
+	if long.Side != types.SideTypeBuy || !long.ExitTime.Equal(at.Add(time.Hour)) || long.Open {
+		t.Errorf("long: got side=%s exit=%s open=%v", long.Side, long.ExitTime, long.Open)
+	}
+	// Half of the buy fee belongs to the matched quantity
+	if !long.Fees.Equal(decimal.NewFromInt(2)) || !long.NetPnL.Equal(decimal.NewFromInt(8)) {
+		t.Errorf("long: got fees=%s pnl=%s, want 2 and 8", long.Fees, long.NetPnL)
+	}
+
+	if long.Bars != 4 || long.Duration != time.Hour || long.Reason != "breakout" {
+		t.Errorf("long: got bars=%d duration=%s reason=%q", long.Bars, long.Duration, long.Reason)
+	}
+	for name, tt := range map[string]struct {
+		got, want decimal.Decimal
+	}{
+		"return":     {long.Return, decimal.RequireFromString("0.16")},
+		"mae":        {long.MAE, decimal.RequireFromString("0.2")},
+		"mfe":        {long.MFE, decimal.RequireFromString("0.3")},
+		"r multiple": {long.RMultiple, decimal.RequireFromString("1.6")},
+	} {
+		if !tt.got.Equal(tt.want) {
+			t.Errorf("long %s: got=%s, want=%s", name, tt.got, tt.want)
+		}
+	}
+
+	short := records[1]
+	if short.Side != types.SideTypeSell || !short.EntryPrice.Equal(decimal.NewFromInt(20)) || !short.NetPnL.Equal(decimal.NewFromInt(5)) {
+		t.Errorf("short: got side=%s entry=%s pnl=%s", short.Side, short.EntryPrice, short.NetPnL)
+	}
+	if short.Bars != 0 || !short.RMultiple.IsZero() {
+		t.Errorf("short without candles or stop: got bars=%d r=%s", short.Bars, short.RMultiple)
+	}
+
+	openRecord := records[2]
+	if !openRecord.Open || !openRecord.Quantity.Equal(decimal.NewFromInt(2)) || !openRecord.ExitTime.IsZero() {
+		t.Errorf("open: got open=%v qty=%s exit=%s", openRecord.Open, openRecord.Quantity, openRecord.ExitTime)
+	}
+	if !openRecord.Fees.Equal(decimal.RequireFromString("0.4")) {
+		t.Errorf("open: got fees=%s, want 0.4", openRecord.Fees)
+	}
+}
+
+func TestAttachStops(t *testing.T) {
+	at := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	orders := []types.Order{
+		types.NewOrder("AAA", decimal.NewFromInt(10), decimal.NewFromInt(1), types.TypeMarket, types.SideTypeBuy, "", at).WithStop(decimal.NewFromInt(9)),
+		types.NewOrder("BBB", decimal.NewFromInt(10), decimal.NewFromInt(1), types.TypeMarket, types.SideTypeSell, "", at),
+	}
+	reports := []types.ExecutionReport{
+		{Ticker: "AAA", Side: types.SideTypeBuy},
+		{Ticker: "AAA", Side: types.SideTypeSell},
+		{Ticker: "BBB", Side: types.SideTypeSell, StopPrice: decimal.NewFromInt(11)},
+	}
+
+	attachStops(orders, reports)
+	for i, want := range []int64{9, 0, 11} {
+		if !reports[i].StopPrice.Equal(decimal.NewFromInt(want)) {
+			t.Errorf("report %d: got stop=%s, want %d", i, reports[i].StopPrice, want)
+		}
+	}
+}
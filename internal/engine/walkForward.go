@@ -0,0 +1,249 @@
+package engine
+
+import (
+	"backtester/types"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+var (
+	NoWalkForwardWindowsErr = errors.New("time range is too short for a single in-sample and out-of-sample window")
+	InvalidWindowLengthErr  = errors.New("in-sample and out-of-sample lengths must be positive")
+	NoValidParamsErr        = errors.New("every parameter combination failed in-sample")
+)
+
+// WalkForward optimizes on an in-sample window, trades the winning parameters on the out-of-sample
+// window right after it, then moves both windows forward by the out-of-sample length.
+// Strategies start cold in every out-of-sample window and positions still open at its end are not
+// carried over, they show up as open trades.
+type WalkForward struct {
+	optimizer   *Optimizer
+	inSample    time.Duration
+	outOfSample time.Duration
+	anchored    bool
+}
+
+type WalkForwardWindow struct {
+	InSampleStart    time.Time
+	InSampleEnd      time.Time
+	OutOfSampleStart time.Time
+	OutOfSampleEnd   time.Time
+
+	Params      Params
+	InSample    *Report
+	OutOfSample *Report
+	// Efficiency is the out-of-sample CAGR divided by the in-sample CAGR of the winning parameters
+	Efficiency decimal.Decimal
+}
+
+type WalkForwardResult struct {
+	Windows []WalkForwardWindow
+	// Report covers the stitched out-of-sample periods
+	Report *Report
+	// Equity is the stitched out-of-sample equity curve, every window continuing where the previous one ended
+	Equity []types.PortfolioView
+	// Efficiency is the stitched out-of-sample CAGR divided by the average in-sample CAGR
+	Efficiency decimal.Decimal
+}
+
+// NewWalkForward uses the optimizer's factory, worker limit and ranking metric for every in-sample sweep.
+func NewWalkForward(optimizer *Optimizer, inSample, outOfSample time.Duration) *WalkForward {
+	return &WalkForward{
+		optimizer:   optimizer,
+		inSample:    inSample,
+		outOfSample: outOfSample,
+	}
+}
+
+// Anchored keeps every in-sample window starting at the beginning of the data, so it grows instead of rolling.
+func (w *WalkForward) Anchored() *WalkForward {
+	w.anchored = true
+	return w
+}
+
+type walkForwardSplit struct {
+	isStart, isEnd, oosStart, oosEnd time.Time
+}
+
+// walkForwardWindows splits [start, end] into in-sample/out-of-sample pairs. The last out-of-sample
+// window is cut off at end.
+func walkForwardWindows(start, end time.Time, inSample, outOfSample time.Duration, anchored bool) []walkForwardSplit {
+	var splits []walkForwardSplit
+	for i := 0; ; i++ {
+		isStart := start.Add(time.Duration(i) * outOfSample)
+		if anchored {
+			isStart = start
+		}
+		isEnd := start.Add(time.Duration(i)*outOfSample + inSample)
+		if !isEnd.Before(end) {
+			break
+		}
+		oosEnd := isEnd.Add(outOfSample)
+		if oosEnd.After(end) {
+			oosEnd = end
+		}
+		splits = append(splits, walkForwardSplit{isStart: isStart, isEnd: isEnd, oosStart: isEnd, oosEnd: oosEnd})
+	}
+	return splits
+}
+
+func (w *WalkForward) Run(params ...Parameter) (*WalkForwardResult, error) {
+	if len(params) == 0 {
+		return nil, NoParametersErr
+	}
+	if w.inSample <= 0 || w.outOfSample <= 0 {
+		return nil, InvalidWindowLengthErr
+	}
+	if _, err := w.optimizer.objective.Value(&Report{}); err != nil {
+		return nil, err
+	}
+
+	combinations := gridCombinations(params)
+	store := newSharedStore(w.optimizer.db)
+
+	// The factory decides the feeds, so build one engine just to read the global time range
+	probe, err := w.optimizer.buildEngine(combinations[0], store)
+	if err != nil {
+		return nil, err
+	}
+	start, end := getGlobalTimeRange(probe.feeds)
+
+	splits := walkForwardWindows(start, end, w.inSample, w.outOfSample, w.anchored)
+	if len(splits) == 0 {
+		return nil, NoWalkForwardWindowsErr
+	}
+
+	result := &WalkForwardResult{}
+	var oosEngines []*Engine
+	for _, split := range splits {
+		ranked, err := w.optimizer.within(split.isStart, split.isEnd).runCombinations(combinations, store)
+		if err != nil {
+			return nil, err
+		}
+		best := ranked[0]
+		if best.Err != nil {
+			return nil, fmt.Errorf("%w: window %s - %s: %w", NoValidParamsErr, split.isStart.Format(time.DateOnly), split.isEnd.Format(time.DateOnly), best.Err)
+		}
+
+		oosEngine, err := w.optimizer.within(split.oosStart, split.oosEnd).buildEngine(best.Params, store)
+		if err != nil {
+			return nil, err
+		}
+		oosReport, err := oosEngine.Run()
+		if err != nil {
+			return nil, err
+		}
+		oosEngines = append(oosEngines, oosEngine)
+
+		result.Windows = append(result.Windows, WalkForwardWindow{
+			InSampleStart:    split.isStart,
+			InSampleEnd:      split.isEnd,
+			OutOfSampleStart: split.oosStart,
+			OutOfSampleEnd:   split.oosEnd,
+			Params:           best.Params,
+			InSample:         best.Report,
+			OutOfSample:      oosReport,
+			Efficiency:       efficiency(oosReport.CAGR, best.Report.CAGR),
+		})
+	}
+
+	result.Equity = stitchEquity(oosEngines)
+	result.Report = combinedReport(oosEngines, result.Equity)
+
+	isCAGR := decimal.Zero
+	for _, window := range result.Windows {
+		isCAGR = isCAGR.Add(window.InSample.CAGR)
+	}
+	isCAGR = isCAGR.Div(decimal.NewFromInt(int64(len(result.Windows))))
+	result.Efficiency = efficiency(result.Report.CAGR, isCAGR)
+
+	return result, nil
+}
+
+// efficiency is zero when the in-sample return was not positive, the ratio means nothing then.
+func efficiency(outOfSample, inSample decimal.Decimal) decimal.Decimal {
+	if !inSample.IsPositive() {
+		return decimal.Zero
+	}
+	return outOfSample.Div(inSample)
+}
+
+// stitchEquity chains the time-weighted equity of every out-of-sample run, scaling each window so it
+// starts at the value the previous window ended on. The boundary snapshot shared by two windows is kept once.
+func stitchEquity(engines []*Engine) []types.PortfolioView {
+	var stitched []types.PortfolioView
+	for _, eng := range engines {
+		snapshots := eng.portfolio.snapshots
+		if len(snapshots) == 0 {
+			continue
+		}
+		equity := timeWeightedEquity(snapshots)
+		if !equity[0].IsPositive() {
+			continue
+		}
+
+		scale := decimal.NewFromInt(1)
+		first := 0
+		if len(stitched) > 0 {
+			last := stitched[len(stitched)-1]
+			scale = last.Cash.Div(equity[0])
+			if !snapshots[0].Time.After(last.Time) {
+				first = 1
+			}
+		}
+		for i := first; i < len(snapshots); i++ {
+			stitched = append(stitched, types.PortfolioView{
+				Time:      snapshots[i].Time,
+				Cash:      equity[i].Mul(scale),
+				Positions: make(map[string]types.PositionSnapshot),
+			})
+		}
+	}
+	return stitched
+}
+
+// combinedReport computes the usual report over the stitched equity and every out-of-sample execution.
+// Every window starts flat, so trades are matched within their window and positions still open at its
+// end stay open trades. Exposure is measured on the snapshots of the windows, the stitched curve only
+// holds equity.
+func combinedReport(engines []*Engine, equity []types.PortfolioView) *Report {
+	combined := newPortfolio(decimal.Zero, false)
+	combined.snapshots = equity
+	var (
+		trades  []trade
+		exposed []types.PortfolioView
+	)
+	dropped := 0
+	for _, eng := range engines {
+		combined.executions = append(combined.executions, eng.portfolio.executions...)
+		combined.cashFlows = append(combined.cashFlows, eng.portfolio.cashFlows...)
+		trades = append(trades, executionsToTrades(eng.portfolio)...)
+		for _, snap := range eng.portfolio.snapshots {
+			// The boundary snapshot shared by two windows is kept once
+			if len(exposed) > 0 && !snap.Time.After(exposed[len(exposed)-1].Time) {
+				continue
+			}
+			exposed = append(exposed, snap)
+		}
+		dropped += len(eng.backtester.signalResolver.dropped)
+	}
+
+	last := engines[len(engines)-1]
+	start, end := engines[0].backtester.start, last.backtester.curTime
+	report := last.generateReport(start, end, combined, trades, exposed)
+	report.DroppedSignals = dropped
+
+	// The last engine only loaded its own window, excursions need the execution feed of every window
+	candles := make(map[string][]types.Candle)
+	for _, eng := range engines {
+		for ticker, cs := range eng.backtester.executionConfig.candles {
+			candles[ticker] = append(candles[ticker], cs...)
+		}
+	}
+	report.Trades = tradeRecords(report.trades, candles)
+	last.addBreakdowns(report, start, end)
+	return report
+}
@@ -0,0 +1,168 @@
+package engine
+
+import (
+	"backtester/types"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestWalkForwardWindows(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+
+	tests := []struct {
+		name        string
+		end         time.Time
+		anchored    bool
+		wantISStart []time.Time
+		wantOOSEnd  []time.Time
+	}{
+		{
+			name:        "rolling windows move by the out-of-sample length",
+			end:         start.Add(20 * day),
+			wantISStart: []time.Time{start, start.Add(4 * day), start.Add(8 * day)},
+			wantOOSEnd:  []time.Time{start.Add(12 * day), start.Add(16 * day), start.Add(20 * day)},
+		},
+		{
+			name:        "anchored windows keep the start",
+			end:         start.Add(20 * day),
+			anchored:    true,
+			wantISStart: []time.Time{start, start, start},
+			wantOOSEnd:  []time.Time{start.Add(12 * day), start.Add(16 * day), start.Add(20 * day)},
+		},
+		{
+			name:        "last out-of-sample window is cut off at the end",
+			end:         start.Add(14 * day),
+			wantISStart: []time.Time{start, start.Add(4 * day)},
+			wantOOSEnd:  []time.Time{start.Add(12 * day), start.Add(14 * day)},
+		},
+		{
+			name: "too short for one window",
+			end:  start.Add(8 * day),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := walkForwardWindows(start, tt.end, 8*day, 4*day, tt.anchored)
+			if len(got) != len(tt.wantISStart) {
+				t.Fatalf("len(got)=%d, want %d (%v)", len(got), len(tt.wantISStart), got)
+			}
+			for i := range got {
+				if !got[i].isStart.Equal(tt.wantISStart[i]) || !got[i].oosEnd.Equal(tt.wantOOSEnd[i]) {
+					t.Errorf("window %d = %s..%s, want %s..%s", i, got[i].isStart, got[i].oosEnd, tt.wantISStart[i], tt.wantOOSEnd[i])
+				}
+				if !got[i].oosStart.Equal(got[i].isEnd) {
+					t.Errorf("window %d: out-of-sample does not follow in-sample", i)
+				}
+			}
+		})
+	}
+}
+
+func TestStitchEquity(t *testing.T) {
+	base := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	first := &Engine{portfolio: &portfolio{snapshots: []types.PortfolioView{
+		newPv(base, "1000"),
+		newPv(base.AddDate(0, 0, 1), "1100"),
+	}}}
+	second := &Engine{portfolio: &portfolio{snapshots: []types.PortfolioView{
+		newPv(base.AddDate(0, 0, 1), "1000"),
+		newPv(base.AddDate(0, 0, 2), "900"),
+	}}}
+
+	got := stitchEquity([]*Engine{first, second})
+	want := []string{"1000", "1100", "990"}
+	if len(got) != len(want) {
+		t.Fatalf("len(got)=%d, want %d", len(got), len(want))
+	}
+	for i, w := range want {
+		if !got[i].Cash.Equal(decimal.RequireFromString(w)) {
+			t.Errorf("index %d: got=%s, want=%s", i, got[i].Cash, w)
+		}
+	}
+}
+
+func TestCombinedReport(t *testing.T) {
+	base := time.Date(2020, 1, 6, 0, 0, 0, 0, time.UTC)
+	day := func(n int) time.Time { return base.AddDate(0, 0, n) }
+	window := func(start, end time.Time, executions []types.ExecutionReport, snapshots ...types.PortfolioView) *Engine {
+		eng := mockEngine(&alternatingStrategy{}, mockInstrument(), &signalAllocator{}, &fillingBroker{})
+		eng.backtester.start, eng.backtester.curTime = start, end
+		eng.portfolio.executions, eng.portfolio.snapshots = executions, snapshots
+		return eng
+	}
+	// The first window ends holding the buy at 5, the second starts flat and trades from 10 to 12
+	first := window(day(0), day(2),
+		[]types.ExecutionReport{newExecutionReport("AAPL", types.SideTypeBuy, newFill(day(1), "5", "1", "0"))},
+		newPv(day(0), "1000"),
+		pvWithPosition(day(1), "995", "AAPL", "1", "5"),
+		pvWithPosition(day(2), "995", "AAPL", "1", "5"),
+	)
+	second := window(day(2), day(4),
+		[]types.ExecutionReport{
+			newExecutionReport("AAPL", types.SideTypeBuy, newFill(day(2), "10", "1", "0")),
+			newExecutionReport("AAPL", types.SideTypeSell, newFill(day(3), "12", "1", "0")),
+		},
+		pvWithPosition(day(2), "990", "AAPL", "1", "10"),
+		newPv(day(3), "1002"),
+		newPv(day(4), "1002"),
+	)
+	engines := []*Engine{first, second}
+
+	report := combinedReport(engines, stitchEquity(engines))
+	if !report.NetProfit.Equal(decimal.NewFromInt(2)) {
+		t.Errorf("NetProfit = %s, want 2 from the trade of the second window", report.NetProfit)
+	}
+	open := 0
+	for _, record := range report.Trades {
+		if record.Open {
+			open++
+		}
+	}
+	if len(report.Trades) != 2 || open != 1 {
+		t.Errorf("trades = %+v, want one closed trade and the buy of the first window open", report.Trades)
+	}
+	if !report.TimeInMarket.IsPositive() || !report.MaxGrossExposure.IsPositive() {
+		t.Errorf("TimeInMarket = %s, MaxGrossExposure = %s, want both positive", report.TimeInMarket, report.MaxGrossExposure)
+	}
+}
+
+func TestWalkForward_Run(t *testing.T) {
+	start := time.UnixMilli(0).UTC()
+	factory := func(params Params) (*Engine, error) {
+		cash, err := params.Decimal("cash")
+		if err != nil {
+			return nil, err
+		}
+		feeds := Instruments(Instrument("AAPL", start, start.Add(20*time.Minute), testInterval))
+		eng := mockEngine(&allocatorStrategy{}, feeds, &mockAllocator{}, &mockBroker{})
+		eng.portfolioConfig.initialCash = cash
+		eng.portfolio.cash = cash
+		return eng, nil
+	}
+	db := mockDb{assets: map[string]*types.Asset{"AAPL": {Id: 1, Ticker: "AAPL", Type: types.AssetTypeStock}}}
+
+	cash := Values("cash", decimal.NewFromInt(1000), decimal.NewFromInt(2000))
+	result, err := NewWalkForward(NewOptimizer(db, factory, 2), 8*time.Minute, 4*time.Minute).Run(cash)
+	if err != nil {
+		t.Fatalf("Error running walk-forward: %v", err)
+	}
+
+	if len(result.Windows) != 3 {
+		t.Fatalf("windows=%d, want 3", len(result.Windows))
+	}
+	for i, w := range result.Windows {
+		if w.InSample == nil || w.OutOfSample == nil {
+			t.Fatalf("window %d misses a report", i)
+		}
+		if !w.Params["cash"].(decimal.Decimal).Equal(decimal.NewFromInt(1000)) {
+			t.Errorf("window %d: ties should keep the first combination, got %s", i, w.Params)
+		}
+	}
+	if result.Report == nil {
+		t.Fatalf("missing combined report")
+	}
+}
@@ -1,11 +1,13 @@
 package repository
 
 import (
+	sqlc "backtester/internal/repository/sqlc/generated"
 	"backtester/types"
 	"context"
 	"database/sql"
 	"errors"
 	"fmt"
+	"time"
 )
 
 // GetAssetByTicker retrieves a types.Asset by its ticker.
@@ -17,6 +19,45 @@ func (db *Database) GetAssetByTicker(ticker string, ctx context.Context) (*types
 		}
 		return nil, err
 	}
+	return convertAsset(asset), nil
+}
+
+// GetAssets retrieves all assets of a type, or every asset when assetType is empty.
+func (db *Database) GetAssets(assetType types.AssetType, ctx context.Context) ([]types.Asset, error) {
+	var daos []sqlc.Asset
+	var err error
+	if assetType == "" {
+		daos, err = db.assets.GetAssets(ctx)
+	} else {
+		daos, err = db.assets.GetAssetsByType(ctx, sqlc.Assettype(assetType))
+	}
+	if err != nil {
+		return nil, err
+	}
+	assets := make([]types.Asset, len(daos))
+	for i, dao := range daos {
+		assets[i] = *convertAsset(dao)
+	}
+	return assets, nil
+}
+
+// SaveAsset creates the asset of a ticker, or updates its name and type when it exists.
+func (db *Database) SaveAsset(ticker, name string, assetType types.AssetType, ctx context.Context) (*types.Asset, error) {
+	now := time.Now().UTC()
+	asset, err := db.assets.UpsertAsset(ctx, sqlc.UpsertAssetParams{
+		Ticker:     ticker,
+		Name:       name,
+		Type:       sqlc.Assettype(assetType),
+		CreatedAt:  &now,
+		ModifiedAt: &now,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("save asset %s: %w", ticker, err)
+	}
+	return convertAsset(asset), nil
+}
+
+func convertAsset(asset sqlc.Asset) *types.Asset {
 	return &types.Asset{
 		Id:         int(asset.ID),
 		Ticker:     asset.Ticker,
@@ -24,5 +65,5 @@ func (db *Database) GetAssetByTicker(ticker string, ctx context.Context) (*types
 		Type:       types.AssetType(asset.Type),
 		CreatedAt:  *asset.CreatedAt,
 		ModifiedAt: *asset.ModifiedAt,
-	}, nil
+	}
 }
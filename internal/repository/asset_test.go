@@ -52,6 +52,50 @@ func TestDatabase_GetAssetByTicker(t *testing.T) {
 	}
 }
 
+func TestDatabase_GetAssets(t *testing.T) {
+	tests := []struct {
+		name      string
+		assetType types.AssetType
+		want      int
+	}{
+		{"should return all assets", "", 2},
+		{"should return assets by type", types.AssetTypeEtf, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := &Database{assets: mockAssetsRepository{}}
+			got, err := db.GetAssets(tt.assetType, context.Background())
+			if err != nil {
+				t.Fatalf("GetAssets() error = %v", err)
+			}
+			if len(got) != tt.want {
+				t.Errorf("GetAssets() = %v, want %d assets", got, tt.want)
+			}
+			for _, asset := range got {
+				if tt.assetType != "" && asset.Type != tt.assetType {
+					t.Errorf("GetAssets() type = %v, want %v", asset.Type, tt.assetType)
+				}
+			}
+		})
+	}
+}
+
+func TestDatabase_SaveAsset(t *testing.T) {
+	db := &Database{assets: mockAssetsRepository{}}
+	got, err := db.SaveAsset("SPY", "SPDR S&P 500", types.AssetTypeEtf, context.Background())
+	if err != nil {
+		t.Fatalf("SaveAsset() error = %v", err)
+	}
+	if got.Ticker != "SPY" || got.Type != types.AssetTypeEtf || got.CreatedAt.IsZero() {
+		t.Errorf("SaveAsset() = %+v, want SPY ETF", got)
+	}
+
+	db = &Database{assets: mockAssetsRepository{sqlError: sql.ErrConnDone}}
+	if _, err := db.SaveAsset("SPY", "SPDR S&P 500", types.AssetTypeEtf, context.Background()); !errors.Is(err, sql.ErrConnDone) {
+		t.Errorf("SaveAsset() error = %v, want %v", err, sql.ErrConnDone)
+	}
+}
+
 func (m mockAssetsRepository) GetAssetByTicker(_ context.Context, ticker string) (sqlc.Asset, error) {
 	if m.sqlError != nil {
 		return sqlc.Asset{}, m.sqlError
@@ -66,3 +110,35 @@ func (m mockAssetsRepository) GetAssetByTicker(_ context.Context, ticker string)
 		ModifiedAt: &curTime,
 	}, nil
 }
+
+func (m mockAssetsRepository) GetAssets(ctx context.Context) ([]sqlc.Asset, error) {
+	apple, _ := m.GetAssetByTicker(ctx, "AAPL")
+	spy, _ := m.GetAssetByTicker(ctx, "SPY")
+	spy.Type = sqlc.AssettypeETF
+	return []sqlc.Asset{apple, spy}, nil
+}
+
+func (m mockAssetsRepository) GetAssetsByType(ctx context.Context, assetType sqlc.Assettype) ([]sqlc.Asset, error) {
+	all, _ := m.GetAssets(ctx)
+	var assets []sqlc.Asset
+	for _, asset := range all {
+		if asset.Type == assetType {
+			assets = append(assets, asset)
+		}
+	}
+	return assets, nil
+}
+
+func (m mockAssetsRepository) UpsertAsset(_ context.Context, arg sqlc.UpsertAssetParams) (sqlc.Asset, error) {
+	if m.sqlError != nil {
+		return sqlc.Asset{}, m.sqlError
+	}
+	return sqlc.Asset{
+		ID:         1,
+		Ticker:     arg.Ticker,
+		Name:       arg.Name,
+		Type:       arg.Type,
+		CreatedAt:  arg.CreatedAt,
+		ModifiedAt: arg.ModifiedAt,
+	}, nil
+}
@@ -6,6 +6,7 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 	"time"
 )
 
@@ -43,6 +44,47 @@ func (db *Database) GetAggregates(assetId int, ticker string, interval types.Int
 	return convertCandles(candles, interval, ticker), nil
 }
 
+// ImportCandles stores candles of an asset for GetAggregates to bucket. Stored candles between the
+// first and the last imported candle are replaced, so overlapping imports do not duplicate candles.
+func (db *Database) ImportCandles(assetId int, candles []types.Candle, ctx context.Context) (int64, error) {
+	if len(candles) == 0 {
+		return 0, ErrNoCandles
+	}
+	first, last := candles[0].Timestamp, candles[0].Timestamp
+	rows := make([]sqlc.CreateCandlesParams, len(candles))
+	for i, candle := range candles {
+		if candle.Timestamp.Before(first) {
+			first = candle.Timestamp
+		}
+		if candle.Timestamp.After(last) {
+			last = candle.Timestamp
+		}
+		rows[i] = sqlc.CreateCandlesParams{
+			Timestamp: &candles[i].Timestamp,
+			AssetID:   int32(assetId),
+			Open:      candle.Open,
+			High:      candle.High,
+			Low:       candle.Low,
+			Close:     candle.Close,
+			Volume:    candle.Volume,
+		}
+	}
+
+	var imported int64
+	err := db.importTx(ctx, func(imports importRepository) error {
+		if err := imports.DeleteCandles(ctx, sqlc.DeleteCandlesParams{AssetID: int32(assetId), StartTime: &first, EndTime: &last}); err != nil {
+			return fmt.Errorf("delete candles: %w", err)
+		}
+		n, err := imports.CreateCandles(ctx, rows)
+		if err != nil {
+			return fmt.Errorf("create candles: %w", err)
+		}
+		imported = n
+		return nil
+	})
+	return imported, err
+}
+
 func convertCandles(candleDAOs []sqlc.GetAggregatesRow, interval types.Interval, ticker string) []types.Candle {
 	var candles []types.Candle
 	for _, dao := range candleDAOs {
@@ -112,3 +112,45 @@ func mockCandles(assetId int, start, end time.Time) []types.Candle {
 	}
 	return candles
 }
+
+type mockImportRepository struct {
+	deleted sqlc.DeleteCandlesParams
+	created []sqlc.CreateCandlesParams
+}
+
+func (m *mockImportRepository) DeleteCandles(_ context.Context, arg sqlc.DeleteCandlesParams) error {
+	m.deleted = arg
+	return nil
+}
+
+func (m *mockImportRepository) CreateCandles(_ context.Context, arg []sqlc.CreateCandlesParams) (int64, error) {
+	m.created = arg
+	return int64(len(arg)), nil
+}
+
+func TestDatabase_ImportCandles(t *testing.T) {
+	imports := &mockImportRepository{}
+	db := &Database{
+		importTx: func(_ context.Context, fn func(imports importRepository) error) error {
+			return fn(imports)
+		},
+	}
+	if _, err := db.ImportCandles(7, nil, context.Background()); !errors.Is(err, ErrNoCandles) {
+		t.Errorf("ImportCandles() error = %v, want %v", err, ErrNoCandles)
+	}
+
+	candles := []types.Candle{
+		{Timestamp: endTime, Close: decimal.NewFromInt(2)},
+		{Timestamp: startTime, Close: decimal.NewFromInt(1)},
+	}
+	got, err := db.ImportCandles(7, candles, context.Background())
+	if err != nil {
+		t.Fatalf("ImportCandles() error = %v", err)
+	}
+	if got != 2 || len(imports.created) != 2 || imports.created[0].AssetID != 7 || !imports.created[1].Close.Equal(decimal.NewFromInt(1)) {
+		t.Errorf("ImportCandles() imported %d rows %+v, want both candles of asset 7", got, imports.created)
+	}
+	if imports.deleted.AssetID != 7 || !imports.deleted.StartTime.Equal(startTime) || !imports.deleted.EndTime.Equal(endTime) {
+		t.Errorf("ImportCandles() deleted %+v, want asset 7 from %v to %v", imports.deleted, startTime, endTime)
+	}
+}
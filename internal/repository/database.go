@@ -16,20 +16,48 @@ var (
 	ErrIntervalNotSupported = errors.New("timeframe not supported")
 	ErrAssetNotFound        = errors.New("not found in datasource")
 	ErrNoCandles            = errors.New("no candles found in datasource")
+	ErrRunNotFound          = errors.New("not found in runs")
 )
 
 type assetsRepository interface {
 	GetAssetByTicker(ctx context.Context, ticker string) (sqlc.Asset, error)
+	GetAssets(ctx context.Context) ([]sqlc.Asset, error)
+	GetAssetsByType(ctx context.Context, type_ sqlc.Assettype) ([]sqlc.Asset, error)
+	UpsertAsset(ctx context.Context, arg sqlc.UpsertAssetParams) (sqlc.Asset, error)
 }
 type candlesRepository interface {
 	GetAggregates(ctx context.Context, arg sqlc.GetAggregatesParams) ([]sqlc.GetAggregatesRow, error)
 }
+type importRepository interface {
+	DeleteCandles(ctx context.Context, arg sqlc.DeleteCandlesParams) error
+	CreateCandles(ctx context.Context, arg []sqlc.CreateCandlesParams) (int64, error)
+}
+type runsRepository interface {
+	CreateRun(ctx context.Context, arg sqlc.CreateRunParams) error
+	CreateRunParameters(ctx context.Context, arg []sqlc.CreateRunParametersParams) (int64, error)
+	CreateRunMetrics(ctx context.Context, arg []sqlc.CreateRunMetricsParams) (int64, error)
+	CreateRunTrades(ctx context.Context, arg []sqlc.CreateRunTradesParams) (int64, error)
+	CreateRunEquity(ctx context.Context, arg []sqlc.CreateRunEquityParams) (int64, error)
+	GetRun(ctx context.Context, id string) (sqlc.Run, error)
+	ListRuns(ctx context.Context) ([]sqlc.Run, error)
+	ListRunsByTag(ctx context.Context, tag string) ([]sqlc.Run, error)
+	GetRunParameters(ctx context.Context, runID string) ([]sqlc.RunParameter, error)
+	GetRunMetrics(ctx context.Context, runID string) ([]sqlc.RunMetric, error)
+	GetRunTrades(ctx context.Context, runID string) ([]sqlc.RunTrade, error)
+	GetRunEquity(ctx context.Context, runID string) ([]sqlc.RunEquity, error)
+	GetMetricHistory(ctx context.Context, arg sqlc.GetMetricHistoryParams) ([]sqlc.GetMetricHistoryRow, error)
+	DeleteRun(ctx context.Context, id string) error
+}
 
 // Database struct that holds the database connection and queries.
 type Database struct {
 	assets  assetsRepository
 	candles candlesRepository
-	conn    *pgxpool.Pool
+	runs    runsRepository
+	// runTx and importTx run fn with the queries of a transaction that commits when fn returns nil
+	runTx    func(ctx context.Context, fn func(runs runsRepository) error) error
+	importTx func(ctx context.Context, fn func(imports importRepository) error) error
+	conn     *pgxpool.Pool
 }
 
 // NewDatabase creates a new Database instance and verifies connectivity.
@@ -57,5 +85,16 @@ func NewDatabase(dbURL string) (Database, error) {
 	return Database{
 		assets:  queries,
 		candles: queries,
-		conn:    conn}, nil
+		runs:    queries,
+		runTx: func(ctx context.Context, fn func(runs runsRepository) error) error {
+			return pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+				return fn(queries.WithTx(tx))
+			})
+		},
+		importTx: func(ctx context.Context, fn func(imports importRepository) error) error {
+			return pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+				return fn(queries.WithTx(tx))
+			})
+		},
+		conn: conn}, nil
 }
@@ -0,0 +1,206 @@
+package repository
+
+import (
+	sqlc "backtester/internal/repository/sqlc/generated"
+	"backtester/types"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/shopspring/decimal"
+)
+
+// SaveRun stores the run with its parameters, metrics, trades and equity curve in one transaction.
+func (db *Database) SaveRun(run types.Run, ctx context.Context) error {
+	return db.runTx(ctx, func(runs runsRepository) error {
+		tags := run.Tags
+		if tags == nil {
+			tags = []string{}
+		}
+		createdAt := run.CreatedAt
+		if err := runs.CreateRun(ctx, sqlc.CreateRunParams{
+			ID:        run.RunID,
+			Name:      run.Name,
+			Strategy:  run.Strategy,
+			Tags:      tags,
+			StartTime: &run.Start,
+			EndTime:   &run.End,
+			CreatedAt: &createdAt,
+		}); err != nil {
+			return fmt.Errorf("create run %s: %w", run.RunID, err)
+		}
+
+		parameters := make([]sqlc.CreateRunParametersParams, 0, len(run.Parameters))
+		for name, value := range run.Parameters {
+			parameters = append(parameters, sqlc.CreateRunParametersParams{RunID: run.RunID, Name: name, Value: value})
+		}
+		if _, err := runs.CreateRunParameters(ctx, parameters); err != nil {
+			return fmt.Errorf("create run parameters: %w", err)
+		}
+
+		metrics := make([]sqlc.CreateRunMetricsParams, 0, len(run.Metrics))
+		for name, value := range run.Metrics {
+			metrics = append(metrics, sqlc.CreateRunMetricsParams{RunID: run.RunID, Name: name, Value: value})
+		}
+		if _, err := runs.CreateRunMetrics(ctx, metrics); err != nil {
+			return fmt.Errorf("create run metrics: %w", err)
+		}
+
+		trades := make([]sqlc.CreateRunTradesParams, len(run.Trades))
+		for i, trade := range run.Trades {
+			trades[i] = sqlc.CreateRunTradesParams{
+				RunID:       run.RunID,
+				TradeID:     int32(i + 1),
+				Ticker:      trade.Ticker,
+				Side:        string(trade.Side),
+				Open:        trade.Open,
+				EntryTime:   &trade.EntryTime,
+				ExitTime:    &trade.ExitTime,
+				Quantity:    trade.Quantity,
+				EntryPrice:  trade.EntryPrice,
+				ExitPrice:   trade.ExitPrice,
+				Fees:        trade.Fees,
+				NetPnl:      trade.NetPnL,
+				TradeReturn: trade.Return,
+				Reason:      trade.Reason,
+				ExitReason:  trade.ExitReason,
+			}
+		}
+		if _, err := runs.CreateRunTrades(ctx, trades); err != nil {
+			return fmt.Errorf("create run trades: %w", err)
+		}
+
+		equity := make([]sqlc.CreateRunEquityParams, len(run.Equity))
+		for i, point := range run.Equity {
+			equity[i] = sqlc.CreateRunEquityParams{
+				RunID:            run.RunID,
+				Timestamp:        &point.Time,
+				Equity:           point.Equity,
+				Cash:             point.Cash,
+				NetContributions: point.NetContributions,
+			}
+		}
+		if _, err := runs.CreateRunEquity(ctx, equity); err != nil {
+			return fmt.Errorf("create run equity: %w", err)
+		}
+		return nil
+	})
+}
+
+// GetRun retrieves a run with its parameters, metrics, trades and equity curve.
+func (db *Database) GetRun(runID string, ctx context.Context) (*types.Run, error) {
+	dao, err := db.runs.GetRun(ctx, runID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("run %s %w", runID, ErrRunNotFound)
+		}
+		return nil, err
+	}
+	run := convertRun(dao)
+
+	parameters, err := db.runs.GetRunParameters(ctx, runID)
+	if err != nil {
+		return nil, err
+	}
+	run.Parameters = make(map[string]string, len(parameters))
+	for _, parameter := range parameters {
+		run.Parameters[parameter.Name] = parameter.Value
+	}
+
+	metrics, err := db.runs.GetRunMetrics(ctx, runID)
+	if err != nil {
+		return nil, err
+	}
+	run.Metrics = make(map[string]decimal.Decimal, len(metrics))
+	for _, metric := range metrics {
+		run.Metrics[metric.Name] = metric.Value
+	}
+
+	trades, err := db.runs.GetRunTrades(ctx, runID)
+	if err != nil {
+		return nil, err
+	}
+	for _, trade := range trades {
+		run.Trades = append(run.Trades, types.RunTrade{
+			Ticker:     trade.Ticker,
+			Side:       types.Side(trade.Side),
+			Open:       trade.Open,
+			EntryTime:  *trade.EntryTime,
+			ExitTime:   *trade.ExitTime,
+			Quantity:   trade.Quantity,
+			EntryPrice: trade.EntryPrice,
+			ExitPrice:  trade.ExitPrice,
+			Fees:       trade.Fees,
+			NetPnL:     trade.NetPnl,
+			Return:     trade.TradeReturn,
+			Reason:     trade.Reason,
+			ExitReason: trade.ExitReason,
+		})
+	}
+
+	equity, err := db.runs.GetRunEquity(ctx, runID)
+	if err != nil {
+		return nil, err
+	}
+	for _, point := range equity {
+		run.Equity = append(run.Equity, types.EquityPoint{
+			Time:             *point.Timestamp,
+			Equity:           point.Equity,
+			Cash:             point.Cash,
+			NetContributions: point.NetContributions,
+		})
+	}
+	return &run, nil
+}
+
+// ListRuns retrieves the runs with the given tag, or all runs when tag is empty, newest first. Only
+// the run itself is loaded, see GetRun for its results.
+func (db *Database) ListRuns(tag string, ctx context.Context) ([]types.Run, error) {
+	var daos []sqlc.Run
+	var err error
+	if tag == "" {
+		daos, err = db.runs.ListRuns(ctx)
+	} else {
+		daos, err = db.runs.ListRunsByTag(ctx, tag)
+	}
+	if err != nil {
+		return nil, err
+	}
+	runs := make([]types.Run, len(daos))
+	for i, dao := range daos {
+		runs[i] = convertRun(dao)
+	}
+	return runs, nil
+}
+
+// MetricHistory retrieves one metric of every run with the given name, oldest first, to follow how
+// the performance of a setup changes over time.
+func (db *Database) MetricHistory(name, metric string, ctx context.Context) ([]types.MetricPoint, error) {
+	rows, err := db.runs.GetMetricHistory(ctx, sqlc.GetMetricHistoryParams{RunName: name, Metric: metric})
+	if err != nil {
+		return nil, err
+	}
+	points := make([]types.MetricPoint, len(rows))
+	for i, row := range rows {
+		points[i] = types.MetricPoint{RunID: row.ID, CreatedAt: *row.CreatedAt, Value: row.Value}
+	}
+	return points, nil
+}
+
+// DeleteRun removes a run and all of its results.
+func (db *Database) DeleteRun(runID string, ctx context.Context) error {
+	return db.runs.DeleteRun(ctx, runID)
+}
+
+func convertRun(dao sqlc.Run) types.Run {
+	return types.Run{
+		RunID:     dao.ID,
+		Name:      dao.Name,
+		Strategy:  dao.Strategy,
+		Tags:      dao.Tags,
+		Start:     *dao.StartTime,
+		End:       *dao.EndTime,
+		CreatedAt: *dao.CreatedAt,
+	}
+}
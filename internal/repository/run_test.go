@@ -0,0 +1,239 @@
+package repository
+
+import (
+	sqlc "backtester/internal/repository/sqlc/generated"
+	"backtester/types"
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+var errCopy = errors.New("copy failed")
+
+// mockRunsRepository keeps the rows of one run in memory, like a table per query.
+type mockRunsRepository struct {
+	copyErr    error
+	run        *sqlc.Run
+	parameters []sqlc.CreateRunParametersParams
+	metrics    []sqlc.CreateRunMetricsParams
+	trades     []sqlc.CreateRunTradesParams
+	equity     []sqlc.CreateRunEquityParams
+	byTag      bool
+}
+
+func newMockDatabase(runs *mockRunsRepository) *Database {
+	return &Database{
+		runs: runs,
+		runTx: func(_ context.Context, fn func(runs runsRepository) error) error {
+			return fn(runs)
+		},
+	}
+}
+
+func testRun() types.Run {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+	return types.Run{
+		RunID:      "abc",
+		Name:       "donchian",
+		Strategy:   "*donchian.Strategy",
+		Tags:       []string{"baseline"},
+		Start:      start,
+		End:        end,
+		CreatedAt:  end.Add(time.Hour),
+		Parameters: map[string]string{"lookback_weeks": "4"},
+		Metrics:    map[string]decimal.Decimal{"sharpe_ratio": decimal.NewFromFloat(1.25)},
+		Trades: []types.RunTrade{
+			{Ticker: "AAPL", Side: types.SideTypeBuy, EntryTime: start, ExitTime: start.AddDate(0, 0, 5), NetPnL: decimal.NewFromInt(10)},
+			{Ticker: "MSFT", Side: types.SideTypeSell, Open: true, EntryTime: start.AddDate(0, 0, 20), ExitTime: end},
+		},
+		Equity: []types.EquityPoint{
+			{Time: start, Equity: decimal.NewFromInt(1000), Cash: decimal.NewFromInt(1000)},
+			{Time: end, Equity: decimal.NewFromInt(1010), Cash: decimal.NewFromInt(500)},
+		},
+	}
+}
+
+func TestDatabase_SaveRun(t *testing.T) {
+	tests := []struct {
+		name    string
+		copyErr error
+		wantErr error
+	}{
+		{"should save and load run", nil, nil},
+		{"should return copy error", errCopy, errCopy},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runs := &mockRunsRepository{copyErr: tt.copyErr}
+			db := newMockDatabase(runs)
+			want := testRun()
+
+			err := db.SaveRun(want, context.Background())
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("SaveRun() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			got, err := db.GetRun(want.RunID, context.Background())
+			if err != nil {
+				t.Fatalf("GetRun() error = %v", err)
+			}
+			if got.Name != want.Name || !slices.Equal(got.Tags, want.Tags) || !got.End.Equal(want.End) {
+				t.Errorf("GetRun() run = %+v, want %+v", got, want)
+			}
+			if got.Parameters["lookback_weeks"] != "4" {
+				t.Errorf("GetRun() parameters = %v, want %v", got.Parameters, want.Parameters)
+			}
+			if !got.Metrics["sharpe_ratio"].Equal(want.Metrics["sharpe_ratio"]) {
+				t.Errorf("GetRun() metrics = %v, want %v", got.Metrics, want.Metrics)
+			}
+			if len(got.Trades) != 2 || got.Trades[1].Side != types.SideTypeSell || !got.Trades[1].Open || !got.Trades[0].NetPnL.Equal(decimal.NewFromInt(10)) {
+				t.Errorf("GetRun() trades = %+v, want %+v", got.Trades, want.Trades)
+			}
+			if len(got.Equity) != 2 || !got.Equity[1].Cash.Equal(decimal.NewFromInt(500)) {
+				t.Errorf("GetRun() equity = %+v, want %+v", got.Equity, want.Equity)
+			}
+		})
+	}
+}
+
+func TestDatabase_GetRun(t *testing.T) {
+	db := newMockDatabase(&mockRunsRepository{})
+	if _, err := db.GetRun("missing", context.Background()); !errors.Is(err, ErrRunNotFound) {
+		t.Errorf("GetRun() error = %v, wantErr %v", err, ErrRunNotFound)
+	}
+}
+
+func TestDatabase_ListRuns(t *testing.T) {
+	tests := []struct {
+		name      string
+		tag       string
+		wantByTag bool
+	}{
+		{"should list all runs", "", false},
+		{"should list runs by tag", "baseline", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runs := &mockRunsRepository{}
+			db := newMockDatabase(runs)
+			if err := db.SaveRun(testRun(), context.Background()); err != nil {
+				t.Fatalf("SaveRun() error = %v", err)
+			}
+			got, err := db.ListRuns(tt.tag, context.Background())
+			if err != nil {
+				t.Fatalf("ListRuns() error = %v", err)
+			}
+			if len(got) != 1 || got[0].RunID != "abc" {
+				t.Errorf("ListRuns() = %+v, want run abc", got)
+			}
+			if runs.byTag != tt.wantByTag {
+				t.Errorf("ListRuns() by tag = %v, want %v", runs.byTag, tt.wantByTag)
+			}
+		})
+	}
+}
+
+func (m *mockRunsRepository) CreateRun(_ context.Context, arg sqlc.CreateRunParams) error {
+	m.run = &sqlc.Run{
+		ID:        arg.ID,
+		Name:      arg.Name,
+		Strategy:  arg.Strategy,
+		Tags:      arg.Tags,
+		StartTime: arg.StartTime,
+		EndTime:   arg.EndTime,
+		CreatedAt: arg.CreatedAt,
+	}
+	return nil
+}
+
+func (m *mockRunsRepository) CreateRunParameters(_ context.Context, arg []sqlc.CreateRunParametersParams) (int64, error) {
+	m.parameters = arg
+	return int64(len(arg)), nil
+}
+
+func (m *mockRunsRepository) CreateRunMetrics(_ context.Context, arg []sqlc.CreateRunMetricsParams) (int64, error) {
+	m.metrics = arg
+	return int64(len(arg)), nil
+}
+
+func (m *mockRunsRepository) CreateRunTrades(_ context.Context, arg []sqlc.CreateRunTradesParams) (int64, error) {
+	if m.copyErr != nil {
+		return 0, m.copyErr
+	}
+	m.trades = arg
+	return int64(len(arg)), nil
+}
+
+func (m *mockRunsRepository) CreateRunEquity(_ context.Context, arg []sqlc.CreateRunEquityParams) (int64, error) {
+	m.equity = arg
+	return int64(len(arg)), nil
+}
+
+func (m *mockRunsRepository) GetRun(_ context.Context, id string) (sqlc.Run, error) {
+	if m.run == nil || m.run.ID != id {
+		return sqlc.Run{}, sql.ErrNoRows
+	}
+	return *m.run, nil
+}
+
+func (m *mockRunsRepository) ListRuns(_ context.Context) ([]sqlc.Run, error) {
+	return []sqlc.Run{*m.run}, nil
+}
+
+func (m *mockRunsRepository) ListRunsByTag(_ context.Context, tag string) ([]sqlc.Run, error) {
+	m.byTag = true
+	if !slices.Contains(m.run.Tags, tag) {
+		return nil, nil
+	}
+	return []sqlc.Run{*m.run}, nil
+}
+
+func (m *mockRunsRepository) GetRunParameters(_ context.Context, runID string) ([]sqlc.RunParameter, error) {
+	var rows []sqlc.RunParameter
+	for _, p := range m.parameters {
+		rows = append(rows, sqlc.RunParameter{RunID: p.RunID, Name: p.Name, Value: p.Value})
+	}
+	return rows, nil
+}
+
+func (m *mockRunsRepository) GetRunMetrics(_ context.Context, runID string) ([]sqlc.RunMetric, error) {
+	var rows []sqlc.RunMetric
+	for _, p := range m.metrics {
+		rows = append(rows, sqlc.RunMetric{RunID: p.RunID, Name: p.Name, Value: p.Value})
+	}
+	return rows, nil
+}
+
+func (m *mockRunsRepository) GetRunTrades(_ context.Context, runID string) ([]sqlc.RunTrade, error) {
+	var rows []sqlc.RunTrade
+	for _, p := range m.trades {
+		rows = append(rows, sqlc.RunTrade(p))
+	}
+	return rows, nil
+}
+
+func (m *mockRunsRepository) GetRunEquity(_ context.Context, runID string) ([]sqlc.RunEquity, error) {
+	var rows []sqlc.RunEquity
+	for _, p := range m.equity {
+		rows = append(rows, sqlc.RunEquity(p))
+	}
+	return rows, nil
+}
+
+func (m *mockRunsRepository) GetMetricHistory(_ context.Context, arg sqlc.GetMetricHistoryParams) ([]sqlc.GetMetricHistoryRow, error) {
+	return nil, nil
+}
+
+func (m *mockRunsRepository) DeleteRun(_ context.Context, id string) error {
+	m.run = nil
+	return nil
+}
@@ -83,3 +83,51 @@ type Candle struct {
 	Close     decimal.Decimal
 	Volume    decimal.Decimal
 }
+
+type Run struct {
+	ID        string
+	Name      string
+	Strategy  string
+	Tags      []string
+	StartTime *time.Time
+	EndTime   *time.Time
+	CreatedAt *time.Time
+}
+
+type RunEquity struct {
+	RunID            string
+	Timestamp        *time.Time
+	Equity           decimal.Decimal
+	Cash             decimal.Decimal
+	NetContributions decimal.Decimal
+}
+
+type RunMetric struct {
+	RunID string
+	Name  string
+	Value decimal.Decimal
+}
+
+type RunParameter struct {
+	RunID string
+	Name  string
+	Value string
+}
+
+type RunTrade struct {
+	RunID       string
+	TradeID     int32
+	Ticker      string
+	Side        string
+	Open        bool
+	EntryTime   *time.Time
+	ExitTime    *time.Time
+	Quantity    decimal.Decimal
+	EntryPrice  decimal.Decimal
+	ExitPrice   decimal.Decimal
+	Fees        decimal.Decimal
+	NetPnl      decimal.Decimal
+	TradeReturn decimal.Decimal
+	Reason      string
+	ExitReason  string
+}
@@ -9,6 +9,18 @@ import (
 )
 
 type Querier interface {
+	CreateCandles(ctx context.Context, arg []CreateCandlesParams) (int64, error)
+	//------------------ RUNS ---------------------
+	// Create run
+	CreateRun(ctx context.Context, arg CreateRunParams) error
+	CreateRunEquity(ctx context.Context, arg []CreateRunEquityParams) (int64, error)
+	CreateRunMetrics(ctx context.Context, arg []CreateRunMetricsParams) (int64, error)
+	CreateRunParameters(ctx context.Context, arg []CreateRunParametersParams) (int64, error)
+	CreateRunTrades(ctx context.Context, arg []CreateRunTradesParams) (int64, error)
+	// Delete candles of an asset in a time range
+	DeleteCandles(ctx context.Context, arg DeleteCandlesParams) error
+	// Delete run with its parameters, metrics, trades and equity
+	DeleteRun(ctx context.Context, id string) error
 	//------------------ AGGREGATES ---------------------
 	// Get aggregate
 	GetAggregates(ctx context.Context, arg GetAggregatesParams) ([]GetAggregatesRow, error)
@@ -24,6 +36,20 @@ type Querier interface {
 	//------------------ CANDLES ---------------------
 	// Get min/max candle by assetId
 	GetCandleRangeByTicker(ctx context.Context, ticker string) (AssetCandleRange, error)
+	// One metric over all runs of a name, oldest first
+	GetMetricHistory(ctx context.Context, arg GetMetricHistoryParams) ([]GetMetricHistoryRow, error)
+	// Get run by id
+	GetRun(ctx context.Context, id string) (Run, error)
+	GetRunEquity(ctx context.Context, runID string) ([]RunEquity, error)
+	GetRunMetrics(ctx context.Context, runID string) ([]RunMetric, error)
+	GetRunParameters(ctx context.Context, runID string) ([]RunParameter, error)
+	GetRunTrades(ctx context.Context, runID string) ([]RunTrade, error)
+	// List runs, newest first
+	ListRuns(ctx context.Context) ([]Run, error)
+	// List runs with a tag, newest first
+	ListRunsByTag(ctx context.Context, tag string) ([]Run, error)
+	// Create asset, or update name and type of an existing ticker
+	UpsertAsset(ctx context.Context, arg UpsertAssetParams) (Asset, error)
 }
 
 var _ Querier = (*Queries)(nil)
@@ -12,6 +12,115 @@ import (
 	"github.com/shopspring/decimal"
 )
 
+type CreateCandlesParams struct {
+	Timestamp *time.Time
+	AssetID   int32
+	Open      decimal.Decimal
+	High      decimal.Decimal
+	Low       decimal.Decimal
+	Close     decimal.Decimal
+	Volume    decimal.Decimal
+}
+
+const createRun = `-- name: CreateRun :exec
+INSERT INTO runs (id, name, strategy, tags, start_time, end_time, created_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+`
+
+type CreateRunParams struct {
+	ID        string
+	Name      string
+	Strategy  string
+	Tags      []string
+	StartTime *time.Time
+	EndTime   *time.Time
+	CreatedAt *time.Time
+}
+
+// ------------------ RUNS ---------------------
+// Create run
+func (q *Queries) CreateRun(ctx context.Context, arg CreateRunParams) error {
+	_, err := q.db.Exec(ctx, createRun,
+		arg.ID,
+		arg.Name,
+		arg.Strategy,
+		arg.Tags,
+		arg.StartTime,
+		arg.EndTime,
+		arg.CreatedAt,
+	)
+	return err
+}
+
+type CreateRunEquityParams struct {
+	RunID            string
+	Timestamp        *time.Time
+	Equity           decimal.Decimal
+	Cash             decimal.Decimal
+	NetContributions decimal.Decimal
+}
+
+type CreateRunMetricsParams struct {
+	RunID string
+	Name  string
+	Value decimal.Decimal
+}
+
+type CreateRunParametersParams struct {
+	RunID string
+	Name  string
+	Value string
+}
+
+type CreateRunTradesParams struct {
+	RunID       string
+	TradeID     int32
+	Ticker      string
+	Side        string
+	Open        bool
+	EntryTime   *time.Time
+	ExitTime    *time.Time
+	Quantity    decimal.Decimal
+	EntryPrice  decimal.Decimal
+	ExitPrice   decimal.Decimal
+	Fees        decimal.Decimal
+	NetPnl      decimal.Decimal
+	TradeReturn decimal.Decimal
+	Reason      string
+	ExitReason  string
+}
+
+const deleteCandles = `-- name: DeleteCandles :exec
+DELETE
+FROM candles
+WHERE asset_id = $1
+  AND timestamp BETWEEN $2 AND $3
+`
+
+type DeleteCandlesParams struct {
+	AssetID   int32
+	StartTime *time.Time
+	EndTime   *time.Time
+}
+
+// Delete candles of an asset in a time range
+func (q *Queries) DeleteCandles(ctx context.Context, arg DeleteCandlesParams) error {
+	_, err := q.db.Exec(ctx, deleteCandles, arg.AssetID, arg.StartTime, arg.EndTime)
+	return err
+}
+
+const deleteRun = `-- name: DeleteRun :exec
+DELETE
+FROM runs
+WHERE id = $1
+`
+
+// Delete run with its parameters, metrics, trades and equity
+func (q *Queries) DeleteRun(ctx context.Context, id string) error {
+	_, err := q.db.Exec(ctx, deleteRun, id)
+	return err
+}
+
 const getAggregates = `-- name: GetAggregates :many
 SELECT time_bucket($1, c.timestamp)::timestamptz AS bucket,
     asset_id,
@@ -212,3 +321,317 @@ func (q *Queries) GetCandleRangeByTicker(ctx context.Context, ticker string) (As
 	)
 	return i, err
 }
+
+const getMetricHistory = `-- name: GetMetricHistory :many
+SELECT r.id, r.created_at, m.value
+FROM runs r
+         JOIN run_metrics m ON m.run_id = r.id
+WHERE r.name = $1
+  AND m.name = $2
+ORDER BY r.created_at
+`
+
+type GetMetricHistoryParams struct {
+	RunName string
+	Metric  string
+}
+
+type GetMetricHistoryRow struct {
+	ID        string
+	CreatedAt *time.Time
+	Value     decimal.Decimal
+}
+
+// One metric over all runs of a name, oldest first
+func (q *Queries) GetMetricHistory(ctx context.Context, arg GetMetricHistoryParams) ([]GetMetricHistoryRow, error) {
+	rows, err := q.db.Query(ctx, getMetricHistory, arg.RunName, arg.Metric)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetMetricHistoryRow
+	for rows.Next() {
+		var i GetMetricHistoryRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.Value,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRun = `-- name: GetRun :one
+SELECT id, name, strategy, tags, start_time, end_time, created_at
+FROM runs
+WHERE id = $1
+`
+
+// Get run by id
+func (q *Queries) GetRun(ctx context.Context, id string) (Run, error) {
+	row := q.db.QueryRow(ctx, getRun, id)
+	var i Run
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Strategy,
+		&i.Tags,
+		&i.StartTime,
+		&i.EndTime,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getRunEquity = `-- name: GetRunEquity :many
+SELECT run_id, timestamp, equity, cash, net_contributions
+FROM run_equity
+WHERE run_id = $1
+ORDER BY timestamp
+`
+
+func (q *Queries) GetRunEquity(ctx context.Context, runID string) ([]RunEquity, error) {
+	rows, err := q.db.Query(ctx, getRunEquity, runID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []RunEquity
+	for rows.Next() {
+		var i RunEquity
+		if err := rows.Scan(
+			&i.RunID,
+			&i.Timestamp,
+			&i.Equity,
+			&i.Cash,
+			&i.NetContributions,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRunMetrics = `-- name: GetRunMetrics :many
+SELECT run_id, name, value
+FROM run_metrics
+WHERE run_id = $1
+ORDER BY name
+`
+
+func (q *Queries) GetRunMetrics(ctx context.Context, runID string) ([]RunMetric, error) {
+	rows, err := q.db.Query(ctx, getRunMetrics, runID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []RunMetric
+	for rows.Next() {
+		var i RunMetric
+		if err := rows.Scan(
+			&i.RunID,
+			&i.Name,
+			&i.Value,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRunParameters = `-- name: GetRunParameters :many
+SELECT run_id, name, value
+FROM run_parameters
+WHERE run_id = $1
+ORDER BY name
+`
+
+func (q *Queries) GetRunParameters(ctx context.Context, runID string) ([]RunParameter, error) {
+	rows, err := q.db.Query(ctx, getRunParameters, runID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []RunParameter
+	for rows.Next() {
+		var i RunParameter
+		if err := rows.Scan(
+			&i.RunID,
+			&i.Name,
+			&i.Value,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRunTrades = `-- name: GetRunTrades :many
+SELECT run_id, trade_id, ticker, side, open, entry_time, exit_time, quantity, entry_price, exit_price, fees, net_pnl, trade_return, reason, exit_reason
+FROM run_trades
+WHERE run_id = $1
+ORDER BY trade_id
+`
+
+func (q *Queries) GetRunTrades(ctx context.Context, runID string) ([]RunTrade, error) {
+	rows, err := q.db.Query(ctx, getRunTrades, runID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []RunTrade
+	for rows.Next() {
+		var i RunTrade
+		if err := rows.Scan(
+			&i.RunID,
+			&i.TradeID,
+			&i.Ticker,
+			&i.Side,
+			&i.Open,
+			&i.EntryTime,
+			&i.ExitTime,
+			&i.Quantity,
+			&i.EntryPrice,
+			&i.ExitPrice,
+			&i.Fees,
+			&i.NetPnl,
+			&i.TradeReturn,
+			&i.Reason,
+			&i.ExitReason,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listRuns = `-- name: ListRuns :many
+SELECT id, name, strategy, tags, start_time, end_time, created_at
+FROM runs
+ORDER BY created_at DESC
+`
+
+// List runs, newest first
+func (q *Queries) ListRuns(ctx context.Context) ([]Run, error) {
+	rows, err := q.db.Query(ctx, listRuns)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Run
+	for rows.Next() {
+		var i Run
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Strategy,
+			&i.Tags,
+			&i.StartTime,
+			&i.EndTime,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listRunsByTag = `-- name: ListRunsByTag :many
+SELECT id, name, strategy, tags, start_time, end_time, created_at
+FROM runs
+WHERE $1::text = ANY (tags)
+ORDER BY created_at DESC
+`
+
+// List runs with a tag, newest first
+func (q *Queries) ListRunsByTag(ctx context.Context, tag string) ([]Run, error) {
+	rows, err := q.db.Query(ctx, listRunsByTag, tag)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Run
+	for rows.Next() {
+		var i Run
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Strategy,
+			&i.Tags,
+			&i.StartTime,
+			&i.EndTime,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const upsertAsset = `-- name: UpsertAsset :one
+INSERT INTO assets (ticker, name, type, created_at, modified_at)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (ticker) DO UPDATE SET name        = EXCLUDED.name,
+                                   type        = EXCLUDED.type,
+                                   modified_at = EXCLUDED.modified_at
+RETURNING id, ticker, name, type, created_at, modified_at
+`
+
+type UpsertAssetParams struct {
+	Ticker     string
+	Name       string
+	Type       Assettype
+	CreatedAt  *time.Time
+	ModifiedAt *time.Time
+}
+
+// Create asset, or update name and type of an existing ticker
+func (q *Queries) UpsertAsset(ctx context.Context, arg UpsertAssetParams) (Asset, error) {
+	row := q.db.QueryRow(ctx, upsertAsset,
+		arg.Ticker,
+		arg.Name,
+		arg.Type,
+		arg.CreatedAt,
+		arg.ModifiedAt,
+	)
+	var i Asset
+	err := row.Scan(
+		&i.ID,
+		&i.Ticker,
+		&i.Name,
+		&i.Type,
+		&i.CreatedAt,
+		&i.ModifiedAt,
+	)
+	return i, err
+}
@@ -22,6 +22,15 @@ SELECT *
 FROM assets
 WHERE id = $1;
 
+-- Create asset, or update name and type of an existing ticker
+-- name: UpsertAsset :one
+INSERT INTO assets (ticker, name, type, created_at, modified_at)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (ticker) DO UPDATE SET name        = EXCLUDED.name,
+                                   type        = EXCLUDED.type,
+                                   modified_at = EXCLUDED.modified_at
+RETURNING *;
+
 -------------------- CANDLES ---------------------
 -- Get min/max candle by assetId
 -- name: GetCandleRangeByTicker :one
@@ -29,6 +38,17 @@ SELECT *
 FROM asset_candle_range
 WHERE ticker = $1;
 
+-- Delete candles of an asset in a time range
+-- name: DeleteCandles :exec
+DELETE
+FROM candles
+WHERE asset_id = @asset_id
+  AND timestamp BETWEEN @start_time AND @end_time;
+
+-- name: CreateCandles :copyfrom
+INSERT INTO candles (timestamp, asset_id, open, high, low, close, volume)
+VALUES ($1, $2, $3, $4, $5, $6, $7);
+
 -------------------- AGGREGATES ---------------------
 -- Get aggregate
 -- name: GetAggregates :many
@@ -44,4 +64,84 @@ where c.asset_id = $2
   and c.timestamp:: date BETWEEN @startTime
   AND @endTime
 GROUP BY bucket, asset_id
-ORDER BY bucket ASC;
\ No newline at end of file
+ORDER BY bucket ASC;
+-------------------- RUNS ---------------------
+-- Create run
+-- name: CreateRun :exec
+INSERT INTO runs (id, name, strategy, tags, start_time, end_time, created_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7);
+
+-- name: CreateRunParameters :copyfrom
+INSERT INTO run_parameters (run_id, name, value)
+VALUES ($1, $2, $3);
+
+-- name: CreateRunMetrics :copyfrom
+INSERT INTO run_metrics (run_id, name, value)
+VALUES ($1, $2, $3);
+
+-- name: CreateRunTrades :copyfrom
+INSERT INTO run_trades (run_id, trade_id, ticker, side, open, entry_time, exit_time, quantity, entry_price,
+                        exit_price, fees, net_pnl, trade_return, reason, exit_reason)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15);
+
+-- name: CreateRunEquity :copyfrom
+INSERT INTO run_equity (run_id, timestamp, equity, cash, net_contributions)
+VALUES ($1, $2, $3, $4, $5);
+
+-- Get run by id
+-- name: GetRun :one
+SELECT *
+FROM runs
+WHERE id = $1;
+
+-- List runs, newest first
+-- name: ListRuns :many
+SELECT *
+FROM runs
+ORDER BY created_at DESC;
+
+-- List runs with a tag, newest first
+-- name: ListRunsByTag :many
+SELECT *
+FROM runs
+WHERE @tag::text = ANY (tags)
+ORDER BY created_at DESC;
+
+-- name: GetRunParameters :many
+SELECT *
+FROM run_parameters
+WHERE run_id = $1
+ORDER BY name;
+
+-- name: GetRunMetrics :many
+SELECT *
+FROM run_metrics
+WHERE run_id = $1
+ORDER BY name;
+
+-- name: GetRunTrades :many
+SELECT *
+FROM run_trades
+WHERE run_id = $1
+ORDER BY trade_id;
+
+-- name: GetRunEquity :many
+SELECT *
+FROM run_equity
+WHERE run_id = $1
+ORDER BY timestamp;
+
+-- One metric over all runs of a name, oldest first
+-- name: GetMetricHistory :many
+SELECT r.id, r.created_at, m.value
+FROM runs r
+         JOIN run_metrics m ON m.run_id = r.id
+WHERE r.name = @run_name
+  AND m.name = @metric
+ORDER BY r.created_at;
+
+-- Delete run with its parameters, metrics, trades and equity
+-- name: DeleteRun :exec
+DELETE
+FROM runs
+WHERE id = $1;
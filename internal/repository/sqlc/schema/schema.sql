@@ -37,4 +37,66 @@ CREATE TABLE candles
     close     NUMERIC(18, 2) NOT NULL,
     volume    NUMERIC(18, 8) NOT NULL,
     PRIMARY KEY (timestamp, asset_id)
-);
\ No newline at end of file
+);
+-------------------- RUNS ---------------------
+-- id is the run ID of the engine, tags group runs for comparison
+CREATE TABLE runs
+(
+    id         TEXT PRIMARY KEY,
+    name       TEXT        NOT NULL,
+    strategy   TEXT        NOT NULL,
+    tags       TEXT[]      NOT NULL DEFAULT '{}',
+    start_time TIMESTAMPTZ NOT NULL,
+    end_time   TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX runs_name_created_at_idx ON runs (name, created_at);
+CREATE INDEX runs_tags_idx ON runs USING GIN (tags);
+
+CREATE TABLE run_parameters
+(
+    run_id TEXT NOT NULL REFERENCES runs (id) ON DELETE CASCADE,
+    name   TEXT NOT NULL,
+    value  TEXT NOT NULL,
+    PRIMARY KEY (run_id, name)
+);
+
+CREATE TABLE run_metrics
+(
+    run_id TEXT    NOT NULL REFERENCES runs (id) ON DELETE CASCADE,
+    name   TEXT    NOT NULL,
+    value  NUMERIC NOT NULL,
+    PRIMARY KEY (run_id, name)
+);
+
+-- Open trades have open set and exit_time at the end of the run
+CREATE TABLE run_trades
+(
+    run_id       TEXT        NOT NULL REFERENCES runs (id) ON DELETE CASCADE,
+    trade_id     INT         NOT NULL,
+    ticker       TEXT        NOT NULL,
+    side         TEXT        NOT NULL,
+    open         BOOLEAN     NOT NULL,
+    entry_time   TIMESTAMPTZ NOT NULL,
+    exit_time    TIMESTAMPTZ NOT NULL,
+    quantity     NUMERIC     NOT NULL,
+    entry_price  NUMERIC     NOT NULL,
+    exit_price   NUMERIC     NOT NULL,
+    fees         NUMERIC     NOT NULL,
+    net_pnl      NUMERIC     NOT NULL,
+    trade_return NUMERIC     NOT NULL,
+    reason       TEXT        NOT NULL,
+    exit_reason  TEXT        NOT NULL,
+    PRIMARY KEY (run_id, trade_id)
+);
+
+CREATE TABLE run_equity
+(
+    run_id            TEXT        NOT NULL REFERENCES runs (id) ON DELETE CASCADE,
+    timestamp         TIMESTAMPTZ NOT NULL,
+    equity            NUMERIC     NOT NULL,
+    cash              NUMERIC     NOT NULL,
+    net_contributions NUMERIC     NOT NULL,
+    PRIMARY KEY (run_id, timestamp)
+);
@@ -67,6 +67,7 @@ func ibkrForexTier1LowestUSDFee(tradeValueUSD decimal.Decimal) decimal.Decimal {
 // - Buys: check remaining cash, reject if insufficient (price * qty + fee)
 // - Sells: always allowed, proceeds added to remaining cash (minus fee)
 // - Does NOT mutate the portfolio directly; engine applies reports.
+// - Rejections keep the order's signal reason and put the cause in RejectReason.
 func (b *Broker) Execute(orders []types.Order, ctx types.ExecutionContext) []types.ExecutionReport {
 	var execReports []types.ExecutionReport
 	remainingCash := ctx.Portfolio.Cash
@@ -83,9 +84,9 @@ func (b *Broker) Execute(orders []types.Order, ctx types.ExecutionContext) []typ
 				decimal.Zero, // filledQty
 				decimal.Zero, // avgPrice
 				decimal.Zero, // fee
-				decimal.Zero, // slippage
-				"No market data for ticker",
+				decimal.Zero, // remaining qty
 				order.SignalReason,
+				"No market data for ticker",
 				ctx.CurTime,
 			)
 			execReports = append(execReports, report)
@@ -104,8 +105,8 @@ func (b *Broker) Execute(orders []types.Order, ctx types.ExecutionContext) []typ
 				decimal.Zero,
 				decimal.Zero,
 				decimal.Zero,
-				"No future candle available for execution",
 				order.SignalReason,
+				"No future candle available for execution",
 				ctx.CurTime,
 			)
 			execReports = append(execReports, report)
@@ -123,8 +124,8 @@ func (b *Broker) Execute(orders []types.Order, ctx types.ExecutionContext) []typ
 				decimal.Zero,
 				decimal.Zero,
 				decimal.Zero,
-				"Non-positive order quantity",
 				order.SignalReason,
+				"Non-positive order quantity",
 				ctx.CurTime,
 			)
 			execReports = append(execReports, report)
@@ -153,8 +154,8 @@ func (b *Broker) Execute(orders []types.Order, ctx types.ExecutionContext) []typ
 					decimal.Zero,
 					decimal.Zero,
 					decimal.Zero,
-					"Not enough cash available for buy",
 					order.SignalReason,
+					"Not enough cash available for buy",
 					ctx.CurTime,
 				)
 				execReports = append(execReports, report)
@@ -185,9 +186,9 @@ func (b *Broker) Execute(orders []types.Order, ctx types.ExecutionContext) []typ
 			order.Quantity,     // filledQty
 			fillPrice,          // avgPrice
 			fee,                // fee
-			decimal.Zero,       // slippage (none)
-			"",                 // message
+			decimal.Zero,       // remaining qty
 			order.SignalReason, // signal reason
+			"",                 // reject reason
 			fillTime,           // report time = fill time
 		)
 
@@ -0,0 +1,44 @@
+package donchian
+
+import (
+	"backtester/types"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestBroker_RejectReasons(t *testing.T) {
+	at := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	candle := func(ts time.Time) types.Candle {
+		price := decimal.NewFromInt(100)
+		return types.Candle{Ticker: "AAA", Timestamp: ts, Open: price, High: price, Low: price, Close: price}
+	}
+	order := func(quantity int64) types.Order {
+		return types.NewOrder("AAA", decimal.NewFromInt(100), decimal.NewFromInt(quantity), types.TypeMarket, types.SideTypeBuy, "breakout", at)
+	}
+
+	tests := []struct {
+		name    string
+		order   types.Order
+		candles map[string][]types.Candle
+		want    string
+	}{
+		{"no market data", order(1), map[string][]types.Candle{}, "No market data for ticker"},
+		{"no future candle", order(1), map[string][]types.Candle{"AAA": {candle(at)}}, "No future candle available for execution"},
+		{"non-positive quantity", order(0), map[string][]types.Candle{"AAA": {candle(at.Add(time.Hour))}}, "Non-positive order quantity"},
+		{"not enough cash", order(1000), map[string][]types.Candle{"AAA": {candle(at.Add(time.Hour))}}, "Not enough cash available for buy"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := types.ExecutionContext{Candles: tt.candles, Portfolio: types.PortfolioView{Cash: decimal.NewFromInt(1000)}, CurTime: at}
+			reports := (&Broker{}).Execute([]types.Order{tt.order}, ctx)
+			if len(reports) != 1 {
+				t.Fatalf("got %d reports, want 1", len(reports))
+			}
+			if reports[0].RejectReason != tt.want || reports[0].SignalReason != "breakout" {
+				t.Errorf("reject reason = %q, signal reason = %q, want %q and %q", reports[0].RejectReason, reports[0].SignalReason, tt.want, "breakout")
+			}
+		})
+	}
+}
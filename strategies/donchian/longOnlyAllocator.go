@@ -2,26 +2,56 @@ package donchian
 
 import (
 	"backtester/internal/engine"
+	"backtester/strategies/sizing"
 	"backtester/types"
 
 	"github.com/shopspring/decimal"
 )
 
+// sizingHistory is how many primary candles of every instrument the sizer gets as price history.
+const sizingHistory = 250
+
 type LongOnlyAllocator struct {
-	api             engine.PortfolioApi
-	positionPercent decimal.Decimal
+	api     engine.PortfolioApi
+	sizer   sizing.Sizer
+	lotSize decimal.Decimal
 }
 
+// NewLongOnlyAllocator sizes every entry as positionPercent of the available cash.
 func NewLongOnlyAllocator(positionPercent decimal.Decimal) *LongOnlyAllocator {
+	return NewLongOnlyAllocatorWithSizer(sizing.NewFixedFractional(positionPercent, sizing.BaseCash))
+}
+
+func NewLongOnlyAllocatorWithSizer(sizer sizing.Sizer) *LongOnlyAllocator {
 	return &LongOnlyAllocator{
-		positionPercent: positionPercent,
+		sizer: sizer,
 	}
 }
 
+// WithLotSize sizes entries in multiples of lot, whole units by default.
+func (a *LongOnlyAllocator) WithLotSize(lot decimal.Decimal) *LongOnlyAllocator {
+	a.lotSize = lot
+	return a
+}
+
 func (a *LongOnlyAllocator) Init(api engine.PortfolioApi) error {
 	a.api = api
 	return nil
 }
+
+// sizingRequest fills the request with the price history of the ticker and of every instrument, which
+// volatility based sizers need.
+func (a *LongOnlyAllocator) sizingRequest(signal types.Signal, view types.PortfolioView) sizing.Request {
+	req := sizing.NewRequest(signal.Ticker, signal.Side, signal.Price, view)
+	req.Stop = signal.Stop
+	req.LotSize = a.lotSize
+	req.Candles = a.api.GetCandles(signal.Ticker, sizingHistory)
+	req.Universe = make(map[string][]types.Candle)
+	for _, ticker := range a.api.GetTickers() {
+		req.Universe[ticker] = a.api.GetCandles(ticker, sizingHistory)
+	}
+	return req
+}
 func (a *LongOnlyAllocator) Allocate(signals map[string][]types.Signal, view types.PortfolioView) []types.Order {
 	if len(signals) == 0 {
 		return nil
@@ -32,7 +62,7 @@ func (a *LongOnlyAllocator) Allocate(signals map[string][]types.Signal, view typ
 	for ticker, signalPerTicker := range signals {
 		curPos := view.Positions[ticker]
 
-		// Skip tickers with 0 or more than 1 signal (double signal, etc.)
+		// The engine resolves conflicting signals before allocation, several only arrive with PASS_THROUGH
 		if len(signalPerTicker) != 1 {
 			continue
 		}
@@ -46,8 +76,7 @@ func (a *LongOnlyAllocator) Allocate(signals map[string][]types.Signal, view typ
 				continue
 			}
 
-			cashForSignal := view.Cash.Mul(a.positionPercent)
-			qty := getQuantityForPrice(curSignal.Price, cashForSignal)
+			qty := a.sizer.Size(a.sizingRequest(curSignal, view))
 			if qty.IsZero() {
 				continue
 			}
@@ -57,7 +86,7 @@ func (a *LongOnlyAllocator) Allocate(signals map[string][]types.Signal, view typ
 				types.TypeLimit, types.SideTypeBuy,
 				"No existing position (long-only): "+curSignal.Reason,
 				curSignal.CreatedAt,
-			))
+			).WithStop(curSignal.Stop))
 			continue
 		}
 
@@ -104,10 +133,3 @@ func (a *LongOnlyAllocator) Allocate(signals map[string][]types.Signal, view typ
 
 	return orders
 }
-
-func getQuantityForPrice(stockPrice, capitalToUse decimal.Decimal) decimal.Decimal {
-	if stockPrice.IsZero() {
-		return decimal.Zero
-	}
-	return capitalToUse.Div(stockPrice).Floor()
-}
@@ -0,0 +1,91 @@
+package donchian
+
+import (
+	"backtester/strategies/sizing"
+	"backtester/types"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// historyApi serves fixed price history to the allocator.
+type historyApi struct {
+	candles map[string][]types.Candle
+}
+
+func (h *historyApi) GetPortfolioSnapshot() types.PortfolioView { return types.PortfolioView{} }
+
+func (h *historyApi) GetExecutionReportsForTicker(string) []types.ExecutionReport { return nil }
+
+func (h *historyApi) GetLastPrice(string) decimal.Decimal { return decimal.Zero }
+
+func (h *historyApi) GetCandles(ticker string, n int) []types.Candle {
+	candles := h.candles[ticker]
+	if n > 0 && len(candles) > n {
+		candles = candles[len(candles)-n:]
+	}
+	return candles
+}
+
+func (h *historyApi) GetTickers() []string {
+	tickers := make([]string, 0, len(h.candles))
+	for ticker := range h.candles {
+		tickers = append(tickers, ticker)
+	}
+	return tickers
+}
+
+func TestLongOnlyAllocator_VolatilitySizers(t *testing.T) {
+	at := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	// Every candle ranges 99 to 101 around a close of 100, so the ATR is 2
+	history := func(ticker string) []types.Candle {
+		candles := make([]types.Candle, 20)
+		for i := range candles {
+			candles[i] = types.Candle{
+				Ticker:    ticker,
+				Timestamp: at.Add(time.Duration(i) * time.Hour),
+				Open:      decimal.NewFromInt(100),
+				High:      decimal.NewFromInt(101),
+				Low:       decimal.NewFromInt(99),
+				Close:     decimal.NewFromInt(100),
+			}
+		}
+		return candles
+	}
+	api := &historyApi{candles: map[string][]types.Candle{"AAA": history("AAA"), "BBB": history("BBB")}}
+	view := types.PortfolioView{Time: at, Cash: decimal.NewFromInt(10000), Positions: map[string]types.PositionSnapshot{}}
+	signals := map[string][]types.Signal{
+		"AAA": {types.NewSignal("AAA", types.SideTypeBuy, decimal.NewFromInt(100), "breakout", at)},
+	}
+
+	tests := []struct {
+		name  string
+		sizer sizing.Sizer
+		want  string
+	}{
+		{
+			name:  "volatility target",
+			sizer: sizing.NewVolatilityTarget(decimal.RequireFromString("0.01"), 14, decimal.NewFromInt(2)),
+			want:  "25", // 100 risk / (2 ATR * 2)
+		},
+		{
+			name:  "equal risk contribution",
+			sizer: sizing.NewEqualRiskContribution(decimal.RequireFromString("0.5"), 14),
+			want:  "25", // half the equity split across two equally volatile tickers, 2500 / 100
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			allocator := NewLongOnlyAllocatorWithSizer(tt.sizer)
+			if err := allocator.Init(api); err != nil {
+				t.Fatalf("Init() error = %v", err)
+			}
+			orders := allocator.Allocate(signals, view)
+			if len(orders) != 1 || !orders[0].Quantity.Equal(decimal.RequireFromString(tt.want)) {
+				t.Fatalf("orders = %+v, want one buy of %s", orders, tt.want)
+			}
+		})
+	}
+}
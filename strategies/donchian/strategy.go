@@ -2,28 +2,52 @@ package donchian
 
 import (
 	"backtester/internal/engine"
+	"backtester/strategies/indicators"
 	"backtester/types"
+	"fmt"
 
 	"github.com/shopspring/decimal"
 )
 
+const defaultLookbackWeeks = 4
+
 type Strategy struct {
-	portfolio engine.PortfolioApi
+	portfolio     engine.PortfolioApi
+	lookbackWeeks int
+
+	indicators *indicators.Registry
+	channels   map[string]*indicators.Donchian
+}
+
+// NewStrategy trades breaks of the weekly Donchian channel over the given number of weeks.
+// The zero value Strategy uses a 4 week channel.
+func NewStrategy(lookbackWeeks int) *Strategy {
+	return &Strategy{lookbackWeeks: lookbackWeeks}
 }
 
 func (s *Strategy) Init(api engine.PortfolioApi) error {
 	s.portfolio = api
+	if s.lookbackWeeks <= 0 {
+		s.lookbackWeeks = defaultLookbackWeeks
+	}
+	s.indicators = indicators.NewRegistry()
+	s.channels = make(map[string]*indicators.Donchian)
 	return nil
 }
 
 func (s *Strategy) OnCandle(candle types.Candle, contexts map[types.Interval][]types.Candle) []types.Signal {
+	channel, ok := s.channels[candle.Ticker]
+	if !ok {
+		channel = indicators.Bind(s.indicators, candle.Ticker, types.Week, indicators.NewDonchian(s.lookbackWeeks))
+		s.channels[candle.Ticker] = channel
+	}
+	s.indicators.OnContext(candle.Ticker, contexts)
 
-	if len(contexts[types.Week]) < 4 {
+	if !channel.Ready() {
 		return nil
 	}
 
-	last4Weeks := contexts[types.Week][len(contexts[types.Week])-4:]
-	highestHigh, lowestLow := donchianHighLow(last4Weeks)
+	highestHigh, lowestLow := channel.Upper(), channel.Lower()
 
 	var signals []types.Signal
 
@@ -32,9 +56,9 @@ func (s *Strategy) OnCandle(candle types.Candle, contexts map[types.Interval][]t
 			candle.Ticker,
 			types.SideTypeBuy,
 			highestHigh, // breakout level
-			"Break of highest weekly high of preceding 4 weeks (entry/stop-and-reverse BUY)",
+			fmt.Sprintf("Break of highest weekly high of preceding %d weeks (entry/stop-and-reverse BUY)", s.lookbackWeeks),
 			candle.Timestamp,
-		))
+		).WithStop(lowestLow).WithStrength(breakoutStrength(candle.High.Sub(highestHigh), highestHigh)))
 	}
 
 	if candle.Low.LessThan(lowestLow) {
@@ -42,63 +66,18 @@ func (s *Strategy) OnCandle(candle types.Candle, contexts map[types.Interval][]t
 			candle.Ticker,
 			types.SideTypeSell,
 			lowestLow, // breakout level
-			"Break of lowest weekly low of preceding 4 weeks (entry/stop-and-reverse SELL)",
+			fmt.Sprintf("Break of lowest weekly low of preceding %d weeks (entry/stop-and-reverse SELL)", s.lookbackWeeks),
 			candle.Timestamp,
-		))
+		).WithStop(highestHigh).WithStrength(breakoutStrength(lowestLow.Sub(candle.Low), lowestLow)))
 	}
 	return signals
 }
 
-// Utility: Donchian Channel High/Low
-func donchianHighLow(candles []types.Candle) (decimal.Decimal, decimal.Decimal) {
-	if len(candles) == 0 {
-		return decimal.Zero, decimal.Zero
-	}
-
-	highest := candles[0].High
-	lowest := candles[0].Low
-
-	for _, c := range candles {
-		if c.High.GreaterThan(highest) {
-			highest = c.High
-		}
-		if c.Low.LessThan(lowest) {
-			lowest = c.Low
-		}
-	}
-	return highest, lowest
-}
-
-func calcATR(candles []types.Candle, period int) decimal.Decimal {
-	if len(candles) < period+1 {
-		return decimal.Zero // need enough data (prev candle + period)
-	}
-
-	var trueRanges []decimal.Decimal
-
-	for i := 1; i < len(candles); i++ {
-		high := candles[i].High
-		low := candles[i].Low
-		prevClose := candles[i-1].Close
-
-		range1 := high.Sub(low)
-		range2 := high.Sub(prevClose).Abs()
-		range3 := low.Sub(prevClose).Abs()
-
-		maxTrueRange := decimal.Max(range1, range2, range3)
-		trueRanges = append(trueRanges, maxTrueRange)
+// breakoutStrength is how far price broke past the channel as a fraction of the broken level, so the
+// deeper break wins when a wide bar breaks both sides.
+func breakoutStrength(distance, level decimal.Decimal) decimal.Decimal {
+	if !level.IsPositive() {
+		return distance
 	}
-
-	atr := decimal.Zero
-	for _, tr := range trueRanges[:period] {
-		atr = atr.Add(tr)
-	}
-	atr = atr.Div(decimal.NewFromInt(int64(period)))
-
-	for i := period; i < len(trueRanges); i++ {
-		atr = (atr.Mul(decimal.NewFromInt(int64(period - 1))).Add(trueRanges[i])).
-			Div(decimal.NewFromInt(int64(period)))
-	}
-
-	return atr
+	return distance.Div(level)
 }
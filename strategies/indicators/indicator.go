@@ -0,0 +1,178 @@
+package indicators
+
+import (
+	"backtester/types"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Indicator is a streaming indicator. Update is O(1) (amortised for windowed extremes) per candle and
+// candles must be fed in chronological order. Values read as zero until Ready reports true.
+type Indicator interface {
+	Update(candle types.Candle)
+	Ready() bool
+}
+
+type bindingKey struct {
+	ticker   string
+	interval types.Interval
+}
+
+// Registry binds indicators to a ticker/interval feed so a strategy only has to forward the candles it
+// receives and can then read current values from the indicators it bound.
+type Registry struct {
+	bound map[bindingKey][]Indicator
+	last  map[bindingKey]time.Time
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		bound: make(map[bindingKey][]Indicator),
+		last:  make(map[bindingKey]time.Time),
+	}
+}
+
+// Bind registers the indicator on the ticker/interval feed and returns it, so the caller keeps a typed handle.
+func Bind[T Indicator](r *Registry, ticker string, interval types.Interval, indicator T) T {
+	key := bindingKey{ticker: ticker, interval: interval}
+	r.bound[key] = append(r.bound[key], indicator)
+	return indicator
+}
+
+// OnCandle updates every indicator bound to the candle's ticker and interval.
+// Candles that are not newer than the last one seen for that feed are ignored.
+func (r *Registry) OnCandle(candle types.Candle) {
+	key := bindingKey{ticker: candle.Ticker, interval: candle.Interval}
+	r.update(key, candle)
+}
+
+// OnContext feeds the context candles the engine hands to OnCandle. Only candles newer than the last
+// one seen per interval are applied, so passing the full context history every bar stays cheap.
+func (r *Registry) OnContext(ticker string, contexts map[types.Interval][]types.Candle) {
+	for interval, candles := range contexts {
+		key := bindingKey{ticker: ticker, interval: interval}
+		if len(r.bound[key]) == 0 {
+			continue
+		}
+		start := 0
+		if last, ok := r.last[key]; ok {
+			start = sort.Search(len(candles), func(i int) bool {
+				return candles[i].Timestamp.After(last)
+			})
+		}
+		for _, c := range candles[start:] {
+			r.update(key, c)
+		}
+	}
+}
+
+func (r *Registry) update(key bindingKey, candle types.Candle) {
+	if last, ok := r.last[key]; ok && !candle.Timestamp.After(last) {
+		return
+	}
+	r.last[key] = candle.Timestamp
+	for _, ind := range r.bound[key] {
+		ind.Update(candle)
+	}
+}
+
+// window is a fixed size ring buffer.
+type window struct {
+	values []decimal.Decimal
+	next   int
+	full   bool
+}
+
+func newWindow(size int) *window {
+	if size < 1 {
+		size = 1
+	}
+	return &window{values: make([]decimal.Decimal, size)}
+}
+
+// push adds a value and returns the value it evicted, if the window was already full.
+func (w *window) push(v decimal.Decimal) (decimal.Decimal, bool) {
+	evicted, didEvict := w.values[w.next], w.full
+	w.values[w.next] = v
+	w.next++
+	if w.next == len(w.values) {
+		w.next = 0
+		w.full = true
+	}
+	return evicted, didEvict
+}
+
+func (w *window) len() int {
+	if w.full {
+		return len(w.values)
+	}
+	return w.next
+}
+
+// extremeWindow tracks the max (or min) of the last period values with a monotonic deque.
+type extremeWindow struct {
+	period  int
+	count   int
+	indices []int
+	values  []decimal.Decimal
+	max     bool
+}
+
+func newExtremeWindow(period int, max bool) *extremeWindow {
+	return &extremeWindow{period: period, max: max}
+}
+
+func (e *extremeWindow) push(v decimal.Decimal) {
+	i := e.count
+	e.count++
+	for n := len(e.values); n > 0; n = len(e.values) {
+		last := e.values[n-1]
+		if (e.max && last.GreaterThan(v)) || (!e.max && last.LessThan(v)) {
+			break
+		}
+		e.values = e.values[:n-1]
+		e.indices = e.indices[:n-1]
+	}
+	e.values = append(e.values, v)
+	e.indices = append(e.indices, i)
+	for e.indices[0] <= i-e.period {
+		e.values = e.values[1:]
+		e.indices = e.indices[1:]
+	}
+}
+
+func (e *extremeWindow) value() decimal.Decimal {
+	if len(e.values) == 0 {
+		return decimal.Zero
+	}
+	return e.values[0]
+}
+
+func (e *extremeWindow) ready() bool {
+	return e.count >= e.period
+}
+
+func trueRange(high, low, prevClose decimal.Decimal) decimal.Decimal {
+	return decimal.Max(high.Sub(low), high.Sub(prevClose).Abs(), low.Sub(prevClose).Abs())
+}
+
+// sqrt refines the float64 square root with Newton's method to full decimal precision.
+func sqrt(d decimal.Decimal) decimal.Decimal {
+	if !d.IsPositive() {
+		return decimal.Zero
+	}
+	two := decimal.NewFromInt(2)
+	x := decimal.NewFromFloat(math.Sqrt(d.InexactFloat64()))
+	if !x.IsPositive() {
+		x = d
+	}
+	for i := 0; i < 4; i++ {
+		x = x.Add(d.Div(x)).Div(two)
+	}
+	return x
+}
+
+var hundred = decimal.NewFromInt(100)
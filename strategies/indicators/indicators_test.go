@@ -0,0 +1,234 @@
+package indicators
+
+import (
+	"backtester/strategies/sizing"
+	"backtester/types"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestMovingAverages(t *testing.T) {
+	closes := []string{"1", "2", "3", "4", "5", "6"}
+
+	tests := []struct {
+		name string
+		ind  interface {
+			Indicator
+			Value() decimal.Decimal
+		}
+		want []string // value after each candle, "" while not ready
+	}{
+		{
+			name: "sma",
+			ind:  NewSMA(3),
+			want: []string{"", "", "2", "3", "4", "5"},
+		},
+		{
+			name: "ema seeded with sma",
+			// alpha 0.5: 2 -> (4 + 2) / 2 = 3 -> 4 -> 5
+			ind:  NewEMA(3),
+			want: []string{"", "", "2", "3", "4", "5"},
+		},
+		{
+			name: "wma",
+			// (1*1 + 2*2 + 3*3) / 6 = 14/6, then (2 + 6 + 12) / 6 = 20/6 ...
+			ind:  NewWMA(3),
+			want: []string{"", "", "2.3333333333333333", "3.3333333333333333", "4.3333333333333333", "5.3333333333333333"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i, c := range closes {
+				tt.ind.Update(candle(i, c, c, c))
+				if tt.want[i] == "" {
+					if tt.ind.Ready() {
+						t.Fatalf("candle %d: ready too early", i)
+					}
+					continue
+				}
+				if !tt.ind.Ready() {
+					t.Fatalf("candle %d: not ready", i)
+				}
+				if want := decimal.RequireFromString(tt.want[i]); !tt.ind.Value().Equal(want) {
+					t.Errorf("candle %d: got=%s, want=%s", i, tt.ind.Value(), want)
+				}
+			}
+		})
+	}
+}
+
+func TestATRMatchesSizing(t *testing.T) {
+	candles := randomWalk(40)
+	atr := NewATR(14)
+	for i, c := range candles {
+		atr.Update(c)
+		if i < 14 {
+			continue
+		}
+		want := sizing.ATR(candles[:i+1], 14)
+		if !atr.Value().Equal(want) {
+			t.Fatalf("candle %d: got=%s, want=%s", i, atr.Value(), want)
+		}
+	}
+}
+
+func TestWindowedExtremesMatchBruteForce(t *testing.T) {
+	candles := randomWalk(60)
+	donchian := NewDonchian(5)
+	stoch := NewStochastic(5, 3)
+
+	for i, c := range candles {
+		donchian.Update(c)
+		stoch.Update(c)
+		if i < 4 {
+			if donchian.Ready() {
+				t.Fatalf("candle %d: donchian ready too early", i)
+			}
+			continue
+		}
+		highest, lowest := candles[i].High, candles[i].Low
+		for _, prev := range candles[i-4 : i+1] {
+			highest = decimal.Max(highest, prev.High)
+			lowest = decimal.Min(lowest, prev.Low)
+		}
+		if !donchian.Upper().Equal(highest) || !donchian.Lower().Equal(lowest) {
+			t.Fatalf("candle %d: got=%s/%s, want=%s/%s", i, donchian.Upper(), donchian.Lower(), highest, lowest)
+		}
+		wantK := c.Close.Sub(lowest).Div(highest.Sub(lowest)).Mul(hundred)
+		if !stoch.K().Equal(wantK) {
+			t.Fatalf("candle %d: %%K got=%s, want=%s", i, stoch.K(), wantK)
+		}
+	}
+	if !stoch.Ready() {
+		t.Fatalf("stochastic not ready")
+	}
+}
+
+func TestRollingStdDev(t *testing.T) {
+	std := NewRollingStdDev(4)
+	for i, c := range []string{"100", "2", "4", "4", "4", "5", "5", "7", "9"} {
+		std.Update(candle(i, c, c, c))
+	}
+	// Last four closes 5, 5, 7, 9: mean 6.5, variance 2.75
+	if want := decimal.RequireFromString("6.5"); !std.Mean().Equal(want) {
+		t.Errorf("mean got=%s, want=%s", std.Mean(), want)
+	}
+	if got := std.Value().Mul(std.Value()).Round(10); !got.Equal(decimal.RequireFromString("2.75")) {
+		t.Errorf("variance got=%s, want=2.75", got)
+	}
+	if z := std.ZScore(); !z.IsPositive() {
+		t.Errorf("z-score of the highest close should be positive, got %s", z)
+	}
+
+	bands := NewBollingerBands(3, decimal.NewFromInt(2))
+	for i := 0; i < 3; i++ {
+		bands.Update(candle(i, "10", "10", "10"))
+	}
+	if !bands.Upper().Equal(bands.Lower()) || !bands.Middle().Equal(decimal.NewFromInt(10)) {
+		t.Errorf("flat series should collapse the bands, got %s/%s/%s", bands.Upper(), bands.Middle(), bands.Lower())
+	}
+}
+
+func TestOscillators(t *testing.T) {
+	rsi := NewRSI(3)
+	macd := NewMACD(2, 4, 2)
+	adx := NewADX(3)
+	for i := 0; i < 10; i++ {
+		// Strictly rising market
+		c := decimal.NewFromInt(int64(100 + i))
+		bar := types.Candle{High: c.Add(decimal.NewFromInt(1)), Low: c.Sub(decimal.NewFromInt(1)), Close: c, Timestamp: ts(i)}
+		rsi.Update(bar)
+		macd.Update(bar)
+		adx.Update(bar)
+	}
+
+	if !rsi.Value().Equal(hundred) {
+		t.Errorf("rsi got=%s, want=100", rsi.Value())
+	}
+	if !macd.Ready() || !macd.MACD().IsPositive() {
+		t.Errorf("macd should be ready and positive in an uptrend, got ready=%v macd=%s", macd.Ready(), macd.MACD())
+	}
+	if !adx.Ready() || !adx.PlusDI().GreaterThan(adx.MinusDI()) || !adx.Value().Equal(hundred) {
+		t.Errorf("adx got=%s +DI=%s -DI=%s", adx.Value(), adx.PlusDI(), adx.MinusDI())
+	}
+}
+
+func TestVWAPResetsPerSession(t *testing.T) {
+	vwap := NewVWAP(time.UTC)
+	day := time.Date(2020, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	bar := func(at time.Time, price string, volume int64) types.Candle {
+		p := decimal.RequireFromString(price)
+		return types.Candle{High: p, Low: p, Close: p, Volume: decimal.NewFromInt(volume), Timestamp: at}
+	}
+
+	vwap.Update(bar(day, "10", 100))
+	vwap.Update(bar(day.Add(time.Hour), "20", 300))
+	if want := decimal.RequireFromString("17.5"); !vwap.Value().Equal(want) {
+		t.Errorf("got=%s, want=%s", vwap.Value(), want)
+	}
+
+	vwap.Update(bar(day.AddDate(0, 0, 1), "30", 10))
+	if want := decimal.RequireFromString("30"); !vwap.Value().Equal(want) {
+		t.Errorf("after reset got=%s, want=%s", vwap.Value(), want)
+	}
+}
+
+func TestRegistry_OnContextOnlyAppliesNewCandles(t *testing.T) {
+	reg := NewRegistry()
+	sma := Bind(reg, "AAA", types.Week, NewSMA(2))
+	other := Bind(reg, "BBB", types.Week, NewSMA(1))
+
+	weeks := []types.Candle{candle(0, "1", "1", "1"), candle(1, "3", "3", "3"), candle(2, "5", "5", "5")}
+
+	reg.OnContext("AAA", map[types.Interval][]types.Candle{types.Week: weeks[:2]})
+	// The same history again must not double count
+	reg.OnContext("AAA", map[types.Interval][]types.Candle{types.Week: weeks[:2]})
+	if want := decimal.NewFromInt(2); !sma.Value().Equal(want) {
+		t.Fatalf("got=%s, want=%s", sma.Value(), want)
+	}
+
+	reg.OnContext("AAA", map[types.Interval][]types.Candle{types.Week: weeks})
+	if want := decimal.NewFromInt(4); !sma.Value().Equal(want) {
+		t.Errorf("got=%s, want=%s", sma.Value(), want)
+	}
+	if other.Ready() {
+		t.Errorf("indicator bound to another ticker was updated")
+	}
+}
+
+// Helper functions
+func ts(i int) time.Time {
+	return time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC).Add(time.Duration(i) * time.Hour)
+}
+
+func candle(i int, high, low, close string) types.Candle {
+	return types.Candle{
+		High:      decimal.RequireFromString(high),
+		Low:       decimal.RequireFromString(low),
+		Close:     decimal.RequireFromString(close),
+		Timestamp: ts(i),
+	}
+}
+
+// randomWalk is a deterministic zigzag with varying ranges.
+func randomWalk(n int) []types.Candle {
+	candles := make([]types.Candle, n)
+	price := decimal.NewFromInt(100)
+	for i := range candles {
+		step := decimal.NewFromInt(int64((i*7)%11 - 5))
+		price = price.Add(step)
+		spread := decimal.NewFromInt(int64(1 + (i*3)%4))
+		candles[i] = types.Candle{
+			Open:      price,
+			High:      price.Add(spread),
+			Low:       price.Sub(spread),
+			Close:     price.Add(step.Div(decimal.NewFromInt(2))),
+			Timestamp: ts(i),
+		}
+	}
+	return candles
+}
@@ -0,0 +1,135 @@
+package indicators
+
+import (
+	"backtester/types"
+
+	"github.com/shopspring/decimal"
+)
+
+// SMA is the simple moving average of the close.
+type SMA struct {
+	period int
+	window *window
+	sum    decimal.Decimal
+}
+
+func NewSMA(period int) *SMA {
+	return &SMA{period: period, window: newWindow(period)}
+}
+
+func (s *SMA) Update(candle types.Candle) {
+	s.Add(candle.Close)
+}
+
+// Add feeds a raw value, which lets the average run over something other than the close.
+func (s *SMA) Add(v decimal.Decimal) {
+	evicted, didEvict := s.window.push(v)
+	s.sum = s.sum.Add(v)
+	if didEvict {
+		s.sum = s.sum.Sub(evicted)
+	}
+}
+
+func (s *SMA) Ready() bool {
+	return s.window.full
+}
+
+func (s *SMA) Value() decimal.Decimal {
+	if !s.Ready() {
+		return decimal.Zero
+	}
+	return s.sum.Div(decimal.NewFromInt(int64(s.period)))
+}
+
+// EMA is an exponential moving average of the close, seeded with the SMA of the first period values.
+type EMA struct {
+	// value = (weight * v + (divisor - weight) * value) / divisor
+	weight  decimal.Decimal
+	divisor decimal.Decimal
+	seed    *SMA
+	value   decimal.Decimal
+	ready   bool
+}
+
+// NewEMA uses the usual smoothing factor 2 / (period + 1).
+func NewEMA(period int) *EMA {
+	return &EMA{
+		weight:  decimal.NewFromInt(2),
+		divisor: decimal.NewFromInt(int64(period + 1)),
+		seed:    NewSMA(period),
+	}
+}
+
+// newWilder returns Wilder's smoothing (RMA) with smoothing factor 1 / period, as used by RSI, ATR and ADX.
+func newWilder(period int) *EMA {
+	return &EMA{
+		weight:  decimal.NewFromInt(1),
+		divisor: decimal.NewFromInt(int64(period)),
+		seed:    NewSMA(period),
+	}
+}
+
+func (e *EMA) Update(candle types.Candle) {
+	e.Add(candle.Close)
+}
+
+func (e *EMA) Add(v decimal.Decimal) {
+	if !e.ready {
+		e.seed.Add(v)
+		if e.seed.Ready() {
+			e.value = e.seed.Value()
+			e.ready = true
+		}
+		return
+	}
+	e.value = v.Mul(e.weight).Add(e.value.Mul(e.divisor.Sub(e.weight))).Div(e.divisor)
+}
+
+func (e *EMA) Ready() bool {
+	return e.ready
+}
+
+func (e *EMA) Value() decimal.Decimal {
+	return e.value
+}
+
+// WMA is the linearly weighted moving average of the close, the newest value weighing period times the oldest.
+type WMA struct {
+	period      int
+	window      *window
+	sum         decimal.Decimal
+	weightedSum decimal.Decimal
+}
+
+func NewWMA(period int) *WMA {
+	return &WMA{period: period, window: newWindow(period)}
+}
+
+func (w *WMA) Update(candle types.Candle) {
+	w.Add(candle.Close)
+}
+
+func (w *WMA) Add(v decimal.Decimal) {
+	if w.window.full {
+		// Every weight drops by one, which removes the plain sum once, then v enters with the top weight
+		w.weightedSum = w.weightedSum.Sub(w.sum).Add(v.Mul(decimal.NewFromInt(int64(w.period))))
+		evicted, _ := w.window.push(v)
+		w.sum = w.sum.Sub(evicted).Add(v)
+		return
+	}
+	w.window.push(v)
+	w.sum = w.sum.Add(v)
+	w.weightedSum = w.weightedSum.Add(v.Mul(decimal.NewFromInt(int64(w.window.len()))))
+}
+
+func (w *WMA) Ready() bool {
+	return w.window.full
+}
+
+func (w *WMA) Value() decimal.Decimal {
+	if !w.Ready() {
+		return decimal.Zero
+	}
+	weights := decimal.NewFromInt(int64(w.period * (w.period + 1) / 2))
+	return w.weightedSum.Div(weights)
+}
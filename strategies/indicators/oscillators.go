@@ -0,0 +1,140 @@
+package indicators
+
+import (
+	"backtester/types"
+
+	"github.com/shopspring/decimal"
+)
+
+// RSI is Wilder's relative strength index of the close, between 0 and 100.
+type RSI struct {
+	prevClose decimal.Decimal
+	hasPrev   bool
+	gains     *EMA
+	losses    *EMA
+}
+
+func NewRSI(period int) *RSI {
+	return &RSI{gains: newWilder(period), losses: newWilder(period)}
+}
+
+func (r *RSI) Update(candle types.Candle) {
+	if r.hasPrev {
+		change := candle.Close.Sub(r.prevClose)
+		r.gains.Add(decimal.Max(change, decimal.Zero))
+		r.losses.Add(decimal.Max(change.Neg(), decimal.Zero))
+	}
+	r.prevClose = candle.Close
+	r.hasPrev = true
+}
+
+func (r *RSI) Ready() bool {
+	return r.gains.Ready()
+}
+
+func (r *RSI) Value() decimal.Decimal {
+	if !r.Ready() {
+		return decimal.Zero
+	}
+	avgGain, avgLoss := r.gains.Value(), r.losses.Value()
+	if avgLoss.IsZero() {
+		if avgGain.IsZero() {
+			return decimal.NewFromInt(50)
+		}
+		return hundred
+	}
+	rs := avgGain.Div(avgLoss)
+	return hundred.Sub(hundred.Div(rs.Add(decimal.NewFromInt(1))))
+}
+
+// MACD is the difference between a fast and a slow EMA of the close, with an EMA of that difference as signal line.
+type MACD struct {
+	fast   *EMA
+	slow   *EMA
+	signal *EMA
+	macd   decimal.Decimal
+}
+
+func NewMACD(fastPeriod, slowPeriod, signalPeriod int) *MACD {
+	return &MACD{
+		fast:   NewEMA(fastPeriod),
+		slow:   NewEMA(slowPeriod),
+		signal: NewEMA(signalPeriod),
+	}
+}
+
+func (m *MACD) Update(candle types.Candle) {
+	m.fast.Add(candle.Close)
+	m.slow.Add(candle.Close)
+	if !m.fast.Ready() || !m.slow.Ready() {
+		return
+	}
+	m.macd = m.fast.Value().Sub(m.slow.Value())
+	m.signal.Add(m.macd)
+}
+
+// Ready reports whether the signal line is available, the MACD line itself is available earlier.
+func (m *MACD) Ready() bool {
+	return m.signal.Ready()
+}
+
+func (m *MACD) MACD() decimal.Decimal {
+	return m.macd
+}
+
+func (m *MACD) Signal() decimal.Decimal {
+	return m.signal.Value()
+}
+
+func (m *MACD) Histogram() decimal.Decimal {
+	if !m.Ready() {
+		return decimal.Zero
+	}
+	return m.macd.Sub(m.signal.Value())
+}
+
+// Stochastic is the stochastic oscillator: %K places the close within the high/low range of the last
+// kPeriod candles and %D is the SMA of %K over dPeriod.
+type Stochastic struct {
+	highs *extremeWindow
+	lows  *extremeWindow
+	d     *SMA
+	k     decimal.Decimal
+}
+
+func NewStochastic(kPeriod, dPeriod int) *Stochastic {
+	return &Stochastic{
+		highs: newExtremeWindow(kPeriod, true),
+		lows:  newExtremeWindow(kPeriod, false),
+		d:     NewSMA(dPeriod),
+	}
+}
+
+func (s *Stochastic) Update(candle types.Candle) {
+	s.highs.push(candle.High)
+	s.lows.push(candle.Low)
+	if !s.highs.ready() {
+		return
+	}
+	highest, lowest := s.highs.value(), s.lows.value()
+	rangeSize := highest.Sub(lowest)
+	if rangeSize.IsZero() {
+		// A flat range has no position, call it the middle
+		s.k = decimal.NewFromInt(50)
+	} else {
+		s.k = candle.Close.Sub(lowest).Div(rangeSize).Mul(hundred)
+	}
+	s.d.Add(s.k)
+}
+
+func (s *Stochastic) Ready() bool {
+	return s.d.Ready()
+}
+
+func (s *Stochastic) K() decimal.Decimal {
+	return s.k
+}
+
+func (s *Stochastic) D() decimal.Decimal {
+	return s.d.Value()
+}
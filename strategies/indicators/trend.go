@@ -0,0 +1,137 @@
+package indicators
+
+import (
+	"backtester/types"
+
+	"github.com/shopspring/decimal"
+)
+
+// Donchian is the highest high and lowest low of the last period candles.
+type Donchian struct {
+	highs *extremeWindow
+	lows  *extremeWindow
+}
+
+func NewDonchian(period int) *Donchian {
+	return &Donchian{
+		highs: newExtremeWindow(period, true),
+		lows:  newExtremeWindow(period, false),
+	}
+}
+
+func (d *Donchian) Update(candle types.Candle) {
+	d.highs.push(candle.High)
+	d.lows.push(candle.Low)
+}
+
+func (d *Donchian) Ready() bool {
+	return d.highs.ready()
+}
+
+func (d *Donchian) Upper() decimal.Decimal {
+	if !d.Ready() {
+		return decimal.Zero
+	}
+	return d.highs.value()
+}
+
+func (d *Donchian) Lower() decimal.Decimal {
+	if !d.Ready() {
+		return decimal.Zero
+	}
+	return d.lows.value()
+}
+
+func (d *Donchian) Middle() decimal.Decimal {
+	return d.Upper().Add(d.Lower()).Div(decimal.NewFromInt(2))
+}
+
+// ADX is Wilder's average directional index together with the +DI and -DI lines.
+type ADX struct {
+	period decimal.Decimal
+	n      int
+
+	prev    types.Candle
+	hasPrev bool
+
+	// Wilder running sums, seeded with the plain sum of the first period values
+	seeded   int
+	trSum    decimal.Decimal
+	plusSum  decimal.Decimal
+	minusSum decimal.Decimal
+
+	plusDI  decimal.Decimal
+	minusDI decimal.Decimal
+	adx     *EMA
+}
+
+func NewADX(period int) *ADX {
+	return &ADX{
+		period: decimal.NewFromInt(int64(period)),
+		n:      period,
+		adx:    newWilder(period),
+	}
+}
+
+func (a *ADX) Update(candle types.Candle) {
+	if !a.hasPrev {
+		a.prev = candle
+		a.hasPrev = true
+		return
+	}
+
+	upMove := candle.High.Sub(a.prev.High)
+	downMove := a.prev.Low.Sub(candle.Low)
+	plusDM, minusDM := decimal.Zero, decimal.Zero
+	if upMove.GreaterThan(downMove) && upMove.IsPositive() {
+		plusDM = upMove
+	}
+	if downMove.GreaterThan(upMove) && downMove.IsPositive() {
+		minusDM = downMove
+	}
+	tr := trueRange(candle.High, candle.Low, a.prev.Close)
+	a.prev = candle
+
+	if a.seeded < a.n {
+		a.trSum = a.trSum.Add(tr)
+		a.plusSum = a.plusSum.Add(plusDM)
+		a.minusSum = a.minusSum.Add(minusDM)
+		a.seeded++
+		if a.seeded < a.n {
+			return
+		}
+	} else {
+		a.trSum = a.trSum.Sub(a.trSum.Div(a.period)).Add(tr)
+		a.plusSum = a.plusSum.Sub(a.plusSum.Div(a.period)).Add(plusDM)
+		a.minusSum = a.minusSum.Sub(a.minusSum.Div(a.period)).Add(minusDM)
+	}
+
+	if a.trSum.IsZero() {
+		a.plusDI, a.minusDI = decimal.Zero, decimal.Zero
+	} else {
+		a.plusDI = a.plusSum.Div(a.trSum).Mul(hundred)
+		a.minusDI = a.minusSum.Div(a.trSum).Mul(hundred)
+	}
+
+	dx := decimal.Zero
+	if diSum := a.plusDI.Add(a.minusDI); !diSum.IsZero() {
+		dx = a.plusDI.Sub(a.minusDI).Abs().Div(diSum).Mul(hundred)
+	}
+	a.adx.Add(dx)
+}
+
+func (a *ADX) Ready() bool {
+	return a.adx.Ready()
+}
+
+func (a *ADX) Value() decimal.Decimal {
+	return a.adx.Value()
+}
+
+func (a *ADX) PlusDI() decimal.Decimal {
+	return a.plusDI
+}
+
+func (a *ADX) MinusDI() decimal.Decimal {
+	return a.minusDI
+}
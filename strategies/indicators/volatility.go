@@ -0,0 +1,172 @@
+package indicators
+
+import (
+	"backtester/types"
+
+	"github.com/shopspring/decimal"
+)
+
+// ATR is Wilder's average true range. It matches sizing.ATR for the same candles.
+type ATR struct {
+	prevClose decimal.Decimal
+	hasPrev   bool
+	avg       *EMA
+}
+
+func NewATR(period int) *ATR {
+	return &ATR{avg: newWilder(period)}
+}
+
+func (a *ATR) Update(candle types.Candle) {
+	if a.hasPrev {
+		a.avg.Add(trueRange(candle.High, candle.Low, a.prevClose))
+	}
+	a.prevClose = candle.Close
+	a.hasPrev = true
+}
+
+func (a *ATR) Ready() bool {
+	return a.avg.Ready()
+}
+
+func (a *ATR) Value() decimal.Decimal {
+	return a.avg.Value()
+}
+
+// RollingStdDev is the population standard deviation of the close over the last period candles.
+type RollingStdDev struct {
+	period int
+	window *window
+	sum    decimal.Decimal
+	sumSq  decimal.Decimal
+	last   decimal.Decimal
+}
+
+func NewRollingStdDev(period int) *RollingStdDev {
+	return &RollingStdDev{period: period, window: newWindow(period)}
+}
+
+func (r *RollingStdDev) Update(candle types.Candle) {
+	r.Add(candle.Close)
+}
+
+func (r *RollingStdDev) Add(v decimal.Decimal) {
+	evicted, didEvict := r.window.push(v)
+	r.sum = r.sum.Add(v)
+	r.sumSq = r.sumSq.Add(v.Mul(v))
+	if didEvict {
+		r.sum = r.sum.Sub(evicted)
+		r.sumSq = r.sumSq.Sub(evicted.Mul(evicted))
+	}
+	r.last = v
+}
+
+func (r *RollingStdDev) Ready() bool {
+	return r.window.full
+}
+
+func (r *RollingStdDev) Mean() decimal.Decimal {
+	if !r.Ready() {
+		return decimal.Zero
+	}
+	return r.sum.Div(decimal.NewFromInt(int64(r.period)))
+}
+
+func (r *RollingStdDev) Value() decimal.Decimal {
+	if !r.Ready() {
+		return decimal.Zero
+	}
+	n := decimal.NewFromInt(int64(r.period))
+	mean := r.sum.Div(n)
+	return sqrt(r.sumSq.Div(n).Sub(mean.Mul(mean)))
+}
+
+// ZScore is the number of standard deviations the latest value sits from the rolling mean.
+func (r *RollingStdDev) ZScore() decimal.Decimal {
+	std := r.Value()
+	if std.IsZero() {
+		return decimal.Zero
+	}
+	return r.last.Sub(r.Mean()).Div(std)
+}
+
+// BollingerBands are an SMA of the close with bands k standard deviations above and below.
+type BollingerBands struct {
+	k   decimal.Decimal
+	std *RollingStdDev
+}
+
+func NewBollingerBands(period int, k decimal.Decimal) *BollingerBands {
+	return &BollingerBands{k: k, std: NewRollingStdDev(period)}
+}
+
+func (b *BollingerBands) Update(candle types.Candle) {
+	b.std.Add(candle.Close)
+}
+
+func (b *BollingerBands) Ready() bool {
+	return b.std.Ready()
+}
+
+func (b *BollingerBands) Middle() decimal.Decimal {
+	return b.std.Mean()
+}
+
+func (b *BollingerBands) Upper() decimal.Decimal {
+	return b.std.Mean().Add(b.std.Value().Mul(b.k))
+}
+
+func (b *BollingerBands) Lower() decimal.Decimal {
+	return b.std.Mean().Sub(b.std.Value().Mul(b.k))
+}
+
+// Bandwidth is the band width relative to the middle band.
+func (b *BollingerBands) Bandwidth() decimal.Decimal {
+	mid := b.Middle()
+	if mid.IsZero() {
+		return decimal.Zero
+	}
+	return b.Upper().Sub(b.Lower()).Div(mid)
+}
+
+// KeltnerChannel is an EMA of the close with bands a multiple of the ATR above and below.
+type KeltnerChannel struct {
+	multiplier decimal.Decimal
+	ema        *EMA
+	atr        *ATR
+}
+
+func NewKeltnerChannel(emaPeriod, atrPeriod int, multiplier decimal.Decimal) *KeltnerChannel {
+	return &KeltnerChannel{
+		multiplier: multiplier,
+		ema:        NewEMA(emaPeriod),
+		atr:        NewATR(atrPeriod),
+	}
+}
+
+func (k *KeltnerChannel) Update(candle types.Candle) {
+	k.ema.Update(candle)
+	k.atr.Update(candle)
+}
+
+func (k *KeltnerChannel) Ready() bool {
+	return k.ema.Ready() && k.atr.Ready()
+}
+
+func (k *KeltnerChannel) Middle() decimal.Decimal {
+	return k.ema.Value()
+}
+
+func (k *KeltnerChannel) Upper() decimal.Decimal {
+	if !k.Ready() {
+		return decimal.Zero
+	}
+	return k.ema.Value().Add(k.atr.Value().Mul(k.multiplier))
+}
+
+func (k *KeltnerChannel) Lower() decimal.Decimal {
+	if !k.Ready() {
+		return decimal.Zero
+	}
+	return k.ema.Value().Sub(k.atr.Value().Mul(k.multiplier))
+}
@@ -0,0 +1,49 @@
+package indicators
+
+import (
+	"backtester/types"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// VWAP is the volume weighted average of the typical price (high + low + close) / 3.
+// With a location it resets at the start of every calendar day in that location (session VWAP),
+// with a nil location it accumulates from the first candle (anchored VWAP).
+type VWAP struct {
+	loc      *time.Location
+	session  time.Time
+	priceVol decimal.Decimal
+	volume   decimal.Decimal
+}
+
+func NewVWAP(loc *time.Location) *VWAP {
+	return &VWAP{loc: loc}
+}
+
+func (v *VWAP) Update(candle types.Candle) {
+	if v.loc != nil {
+		y, m, d := candle.Timestamp.In(v.loc).Date()
+		session := time.Date(y, m, d, 0, 0, 0, 0, v.loc)
+		if !session.Equal(v.session) {
+			v.session = session
+			v.priceVol = decimal.Zero
+			v.volume = decimal.Zero
+		}
+	}
+
+	typical := candle.High.Add(candle.Low).Add(candle.Close).Div(decimal.NewFromInt(3))
+	v.priceVol = v.priceVol.Add(typical.Mul(candle.Volume))
+	v.volume = v.volume.Add(candle.Volume)
+}
+
+func (v *VWAP) Ready() bool {
+	return v.volume.IsPositive()
+}
+
+func (v *VWAP) Value() decimal.Decimal {
+	if !v.Ready() {
+		return decimal.Zero
+	}
+	return v.priceVol.Div(v.volume)
+}
@@ -0,0 +1,89 @@
+package sizing
+
+import (
+	"github.com/shopspring/decimal"
+)
+
+type CapitalBase string
+
+const (
+	BaseCash   CapitalBase = "CASH"
+	BaseEquity CapitalBase = "EQUITY"
+)
+
+// FixedFractional commits a fixed fraction of cash or equity to every entry.
+type FixedFractional struct {
+	fraction decimal.Decimal
+	base     CapitalBase
+}
+
+func NewFixedFractional(fraction decimal.Decimal, base CapitalBase) *FixedFractional {
+	return &FixedFractional{
+		fraction: fraction,
+		base:     base,
+	}
+}
+
+func (f *FixedFractional) Size(req Request) decimal.Decimal {
+	capital := req.Portfolio.Cash
+	if f.base == BaseEquity {
+		capital = req.Equity()
+	}
+	return quantityForCapital(capital.Mul(f.fraction), req)
+}
+
+// FixedRisk sizes so that getting stopped out loses a fixed fraction of equity.
+// Without a stop in the request it returns zero.
+type FixedRisk struct {
+	riskFraction decimal.Decimal
+}
+
+func NewFixedRisk(riskFraction decimal.Decimal) *FixedRisk {
+	return &FixedRisk{riskFraction: riskFraction}
+}
+
+func (f *FixedRisk) Size(req Request) decimal.Decimal {
+	if req.Stop.IsZero() {
+		return decimal.Zero
+	}
+	riskPerUnit := req.Price.Sub(req.Stop).Abs()
+	if riskPerUnit.IsZero() {
+		return decimal.Zero
+	}
+	riskAmount := req.Equity().Mul(f.riskFraction)
+	return roundToLot(riskAmount.Div(riskPerUnit), req.LotSize)
+}
+
+// Kelly sizes with a fraction of the Kelly criterion f* = W - (1 - W) / R,
+// where W is the win rate and R the average win divided by the average loss.
+// A fraction of 0.5 gives the common half-Kelly.
+type Kelly struct {
+	winRate      decimal.Decimal
+	winLossRatio decimal.Decimal
+	fraction     decimal.Decimal
+}
+
+func NewKelly(winRate, winLossRatio, fraction decimal.Decimal) *Kelly {
+	return &Kelly{
+		winRate:      winRate,
+		winLossRatio: winLossRatio,
+		fraction:     fraction,
+	}
+}
+
+// Weight returns the fraction of equity to commit. A negative edge gives zero.
+func (k *Kelly) Weight() decimal.Decimal {
+	if !k.winLossRatio.IsPositive() {
+		return decimal.Zero
+	}
+	one := decimal.NewFromInt(1)
+	f := k.winRate.Sub(one.Sub(k.winRate).Div(k.winLossRatio))
+	if !f.IsPositive() {
+		return decimal.Zero
+	}
+	return f.Mul(k.fraction)
+}
+
+func (k *Kelly) Size(req Request) decimal.Decimal {
+	return quantityForCapital(req.Equity().Mul(k.Weight()), req)
+}
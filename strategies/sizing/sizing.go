@@ -0,0 +1,100 @@
+package sizing
+
+import (
+	"backtester/types"
+
+	"github.com/shopspring/decimal"
+)
+
+// Sizer turns an entry into a quantity. Sizers only size new risk; closing a position is left to the allocator.
+type Sizer interface {
+	Size(req Request) decimal.Decimal
+}
+
+// Request carries everything a sizer may need. Fields a sizer does not use can be left empty.
+type Request struct {
+	Ticker string
+	Side   types.Side
+	Price  decimal.Decimal
+	// Stop is the protective stop price, used by risk based sizers. Zero means no stop is known.
+	Stop      decimal.Decimal
+	Portfolio types.PortfolioView
+	// Candles is recent price history of Ticker, used by volatility based sizers.
+	Candles []types.Candle
+	// Universe holds recent price history per ticker for sizers that size across instruments.
+	Universe map[string][]types.Candle
+	// LotSize is the smallest tradable quantity. Zero means whole units.
+	LotSize decimal.Decimal
+}
+
+func NewRequest(ticker string, side types.Side, price decimal.Decimal, view types.PortfolioView) Request {
+	return Request{
+		Ticker:    ticker,
+		Side:      side,
+		Price:     price,
+		Portfolio: view,
+	}
+}
+
+// Equity returns cash plus the market value of all positions.
+func (r Request) Equity() decimal.Decimal {
+	value := r.Portfolio.Cash
+	for _, pos := range r.Portfolio.Positions {
+		value = value.Add(pos.Quantity.Mul(pos.LastMarketPrice))
+	}
+	return value
+}
+
+// quantityForCapital converts an amount of capital into a quantity rounded down to the lot size.
+func quantityForCapital(capital decimal.Decimal, req Request) decimal.Decimal {
+	if !req.Price.IsPositive() || !capital.IsPositive() {
+		return decimal.Zero
+	}
+	return roundToLot(capital.Div(req.Price), req.LotSize)
+}
+
+func roundToLot(qty, lot decimal.Decimal) decimal.Decimal {
+	if !qty.IsPositive() {
+		return decimal.Zero
+	}
+	if !lot.IsPositive() {
+		return qty.Floor()
+	}
+	return qty.Div(lot).Floor().Mul(lot)
+}
+
+type minSizer struct {
+	sizers []Sizer
+}
+
+// Min composes sizers by taking the smallest quantity any of them allows.
+func Min(sizers ...Sizer) Sizer {
+	return &minSizer{sizers: sizers}
+}
+
+func (m *minSizer) Size(req Request) decimal.Decimal {
+	if len(m.sizers) == 0 {
+		return decimal.Zero
+	}
+	qty := m.sizers[0].Size(req)
+	for _, s := range m.sizers[1:] {
+		qty = decimal.Min(qty, s.Size(req))
+	}
+	return qty
+}
+
+type cappedSizer struct {
+	sizer     Sizer
+	maxWeight decimal.Decimal
+}
+
+// Capped limits the position produced by a sizer to a fraction of equity.
+func Capped(sizer Sizer, maxWeight decimal.Decimal) Sizer {
+	return &cappedSizer{sizer: sizer, maxWeight: maxWeight}
+}
+
+func (c *cappedSizer) Size(req Request) decimal.Decimal {
+	qty := c.sizer.Size(req)
+	maxQty := quantityForCapital(req.Equity().Mul(c.maxWeight), req)
+	return decimal.Min(qty, maxQty)
+}
@@ -0,0 +1,154 @@
+package sizing
+
+import (
+	"backtester/types"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestSizers(t *testing.T) {
+	view := types.PortfolioView{
+		Cash: decimal.NewFromInt(5000),
+		Positions: map[string]types.PositionSnapshot{
+			"BBB": {Ticker: "BBB", Quantity: decimal.NewFromInt(50), LastMarketPrice: decimal.NewFromInt(100)},
+		},
+	}
+	// Equity = 5000 + 50 * 100 = 10000
+
+	withStop := NewRequest("AAA", types.SideTypeBuy, decimal.NewFromInt(50), view)
+	withStop.Stop = decimal.NewFromInt(48)
+
+	fractional := NewRequest("AAA", types.SideTypeBuy, decimal.NewFromInt(30), view)
+	fractional.LotSize = decimal.RequireFromString("0.01")
+
+	tests := []struct {
+		name  string
+		sizer Sizer
+		req   Request
+		want  string
+	}{
+		{
+			name:  "fixed fractional of cash floors to whole units",
+			sizer: NewFixedFractional(decimal.RequireFromString("0.1"), BaseCash),
+			req:   NewRequest("AAA", types.SideTypeBuy, decimal.NewFromInt(30), view),
+			want:  "16", // 500 / 30 = 16.67
+		},
+		{
+			name:  "fixed fractional of equity",
+			sizer: NewFixedFractional(decimal.RequireFromString("0.1"), BaseEquity),
+			req:   NewRequest("AAA", types.SideTypeBuy, decimal.NewFromInt(50), view),
+			want:  "20",
+		},
+		{
+			name:  "fixed fractional respects lot size",
+			sizer: NewFixedFractional(decimal.RequireFromString("0.1"), BaseCash),
+			req:   fractional,
+			want:  "16.66",
+		},
+		{
+			name:  "fixed risk uses stop distance",
+			sizer: NewFixedRisk(decimal.RequireFromString("0.01")),
+			req:   withStop,
+			want:  "50", // 100 risk / 2 per share
+		},
+		{
+			name:  "fixed risk without stop is zero",
+			sizer: NewFixedRisk(decimal.RequireFromString("0.01")),
+			req:   NewRequest("AAA", types.SideTypeBuy, decimal.NewFromInt(50), view),
+			want:  "0",
+		},
+		{
+			name:  "half kelly",
+			sizer: NewKelly(decimal.RequireFromString("0.5"), decimal.NewFromInt(2), decimal.RequireFromString("0.5")),
+			req:   NewRequest("AAA", types.SideTypeBuy, decimal.NewFromInt(50), view),
+			want:  "25", // f* = 0.5 - 0.5/2 = 0.25, half = 0.125 -> 1250 / 50
+		},
+		{
+			name:  "kelly with negative edge is zero",
+			sizer: NewKelly(decimal.RequireFromString("0.3"), decimal.NewFromInt(1), decimal.NewFromInt(1)),
+			req:   NewRequest("AAA", types.SideTypeBuy, decimal.NewFromInt(50), view),
+			want:  "0",
+		},
+		{
+			name: "min takes the smallest quantity",
+			sizer: Min(
+				NewFixedFractional(decimal.RequireFromString("0.5"), BaseCash),
+				NewFixedFractional(decimal.RequireFromString("0.1"), BaseCash),
+			),
+			req:  NewRequest("AAA", types.SideTypeBuy, decimal.NewFromInt(50), view),
+			want: "10",
+		},
+		{
+			name:  "capped limits to a weight of equity",
+			sizer: Capped(NewFixedFractional(decimal.NewFromInt(1), BaseCash), decimal.RequireFromString("0.05")),
+			req:   NewRequest("AAA", types.SideTypeBuy, decimal.NewFromInt(50), view),
+			want:  "10",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.sizer.Size(tt.req)
+			if !got.Equal(decimal.RequireFromString(tt.want)) {
+				t.Errorf("Size() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVolatilitySizers(t *testing.T) {
+	view := types.PortfolioView{Cash: decimal.NewFromInt(10000)}
+
+	// Constant true range of 2 on a price of 100
+	calm := mockCandles(100, 2, 15)
+	// Constant true range of 4 on a price of 100
+	wild := mockCandles(100, 4, 15)
+
+	req := NewRequest("CALM", types.SideTypeBuy, decimal.NewFromInt(100), view)
+	req.Candles = calm
+	req.Universe = map[string][]types.Candle{"CALM": calm, "WILD": wild}
+
+	vol := NewVolatilityTarget(decimal.RequireFromString("0.01"), 14, decimal.NewFromInt(2))
+	if got := vol.Size(req); !got.Equal(decimal.NewFromInt(25)) {
+		t.Errorf("VolatilityTarget.Size() = %s, want 25", got) // 100 / (2 * 2)
+	}
+
+	erc := NewEqualRiskContribution(decimal.NewFromInt(1), 14)
+	weights := erc.Weights(req.Universe)
+	wantCalm := decimal.NewFromInt(2).Div(decimal.NewFromInt(3))
+	if !weights["CALM"].Round(8).Equal(wantCalm.Round(8)) {
+		t.Errorf("CALM weight = %s, want %s", weights["CALM"], wantCalm)
+	}
+	if got := erc.Size(req); !got.Equal(decimal.NewFromInt(66)) {
+		t.Errorf("EqualRiskContribution.Size() = %s, want 66", got)
+	}
+}
+
+func TestATR(t *testing.T) {
+	candles := mockCandles(100, 3, 10)
+	if got := ATR(candles, 5); !got.Equal(decimal.NewFromInt(3)) {
+		t.Errorf("ATR() = %s, want 3", got)
+	}
+	if got := ATR(candles[:5], 5); !got.IsZero() {
+		t.Errorf("ATR() with too little data = %s, want 0", got)
+	}
+}
+
+// Helper functions
+func mockCandles(price, trueRange int64, n int) []types.Candle {
+	out := make([]types.Candle, 0, n)
+	half := decimal.NewFromInt(trueRange).Div(decimal.NewFromInt(2))
+	for i := 0; i < n; i++ {
+		p := decimal.NewFromInt(price)
+		out = append(out, types.Candle{
+			Open:      p,
+			Close:     p,
+			High:      p.Add(half),
+			Low:       p.Sub(half),
+			Timestamp: time.UnixMilli(int64(i)),
+		})
+	}
+	return out
+}
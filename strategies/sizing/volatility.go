@@ -0,0 +1,115 @@
+package sizing
+
+import (
+	"backtester/types"
+
+	"github.com/shopspring/decimal"
+)
+
+// VolatilityTarget sizes so that a move of atrMultiple ATRs against the position loses riskFraction of equity.
+type VolatilityTarget struct {
+	riskFraction decimal.Decimal
+	atrPeriod    int
+	atrMultiple  decimal.Decimal
+}
+
+func NewVolatilityTarget(riskFraction decimal.Decimal, atrPeriod int, atrMultiple decimal.Decimal) *VolatilityTarget {
+	return &VolatilityTarget{
+		riskFraction: riskFraction,
+		atrPeriod:    atrPeriod,
+		atrMultiple:  atrMultiple,
+	}
+}
+
+func (v *VolatilityTarget) Size(req Request) decimal.Decimal {
+	atr := ATR(req.Candles, v.atrPeriod)
+	riskPerUnit := atr.Mul(v.atrMultiple)
+	if !riskPerUnit.IsPositive() {
+		return decimal.Zero
+	}
+	riskAmount := req.Equity().Mul(v.riskFraction)
+	return roundToLot(riskAmount.Div(riskPerUnit), req.LotSize)
+}
+
+// EqualRiskContribution splits grossWeight of equity across the universe so every instrument
+// contributes the same volatility, measured as ATR relative to price. This is inverse volatility
+// weighting, which equals true risk parity when the instruments are uncorrelated.
+type EqualRiskContribution struct {
+	grossWeight decimal.Decimal
+	atrPeriod   int
+}
+
+func NewEqualRiskContribution(grossWeight decimal.Decimal, atrPeriod int) *EqualRiskContribution {
+	return &EqualRiskContribution{
+		grossWeight: grossWeight,
+		atrPeriod:   atrPeriod,
+	}
+}
+
+// Weights returns the target equity weight per ticker. Tickers without enough history are left out.
+func (e *EqualRiskContribution) Weights(universe map[string][]types.Candle) map[string]decimal.Decimal {
+	inverseVol := make(map[string]decimal.Decimal, len(universe))
+	total := decimal.Zero
+	for ticker, candles := range universe {
+		if len(candles) == 0 {
+			continue
+		}
+		lastClose := candles[len(candles)-1].Close
+		atr := ATR(candles, e.atrPeriod)
+		if !atr.IsPositive() || !lastClose.IsPositive() {
+			continue
+		}
+		iv := lastClose.Div(atr)
+		inverseVol[ticker] = iv
+		total = total.Add(iv)
+	}
+
+	weights := make(map[string]decimal.Decimal, len(inverseVol))
+	if total.IsZero() {
+		return weights
+	}
+	for ticker, iv := range inverseVol {
+		weights[ticker] = iv.Div(total).Mul(e.grossWeight)
+	}
+	return weights
+}
+
+func (e *EqualRiskContribution) Size(req Request) decimal.Decimal {
+	weight := e.Weights(req.Universe)[req.Ticker]
+	return quantityForCapital(req.Equity().Mul(weight), req)
+}
+
+// ATR returns Wilder's average true range over the last candles. It returns zero without period+1 candles.
+func ATR(candles []types.Candle, period int) decimal.Decimal {
+	if period <= 0 || len(candles) < period+1 {
+		return decimal.Zero // need enough data (prev candle + period)
+	}
+
+	var trueRanges []decimal.Decimal
+
+	for i := 1; i < len(candles); i++ {
+		high := candles[i].High
+		low := candles[i].Low
+		prevClose := candles[i-1].Close
+
+		range1 := high.Sub(low)
+		range2 := high.Sub(prevClose).Abs()
+		range3 := low.Sub(prevClose).Abs()
+
+		maxTrueRange := decimal.Max(range1, range2, range3)
+		trueRanges = append(trueRanges, maxTrueRange)
+	}
+
+	atr := decimal.Zero
+	for _, tr := range trueRanges[:period] {
+		atr = atr.Add(tr)
+	}
+	atr = atr.Div(decimal.NewFromInt(int64(period)))
+
+	for i := period; i < len(trueRanges); i++ {
+		atr = (atr.Mul(decimal.NewFromInt(int64(period - 1))).Add(trueRanges[i])).
+			Div(decimal.NewFromInt(int64(period)))
+	}
+
+	return atr
+}
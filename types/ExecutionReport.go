@@ -17,9 +17,15 @@ type ExecutionReport struct {
 	RemainingQty   decimal.Decimal
 	SignalReason   string
 	RejectReason   string
+	RejectCode     RejectCode
 	ReportTime     time.Time
+	// StopPrice is copied from the order. Zero means no stop is known.
+	StopPrice decimal.Decimal
 }
 
+// RejectCode identifies the rule that rejected an order so rejections can be grouped and reported on.
+type RejectCode string
+
 type Fill struct {
 	Time     time.Time
 	Price    decimal.Decimal
@@ -14,6 +14,8 @@ type Order struct {
 	Side         Side
 	SignalReason string
 	CreatedAt    time.Time
+	// StopPrice is the protective stop of an entry, carried to its execution report. Zero means no stop is known.
+	StopPrice decimal.Decimal
 }
 
 func NewOrder(
@@ -35,3 +37,8 @@ func NewOrder(
 		CreatedAt:    createdAt,
 	}
 }
+
+func (o Order) WithStop(stop decimal.Decimal) Order {
+	o.StopPrice = stop
+	return o
+}
@@ -10,6 +10,8 @@ type PortfolioView struct {
 	Cash      decimal.Decimal
 	Positions map[string]PositionSnapshot
 	Time      time.Time
+	// NetContributions is the cumulative sum of external deposits minus withdrawals since the start of the run.
+	NetContributions decimal.Decimal
 }
 
 type PositionSnapshot struct {
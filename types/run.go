@@ -0,0 +1,55 @@
+package types
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Run is a finished backtest as it is stored in the database. Name groups runs of the same setup over
+// time and Tags group runs for comparison.
+type Run struct {
+	RunID      string
+	Name       string
+	Strategy   string
+	Tags       []string
+	Start      time.Time
+	End        time.Time
+	CreatedAt  time.Time
+	Parameters map[string]string
+	Metrics    map[string]decimal.Decimal
+	Trades     []RunTrade
+	Equity     []EquityPoint
+}
+
+// RunTrade is one round trip of a run. Open trades exit at the end of the run.
+type RunTrade struct {
+	Ticker     string
+	Side       Side
+	Open       bool
+	EntryTime  time.Time
+	ExitTime   time.Time
+	Quantity   decimal.Decimal
+	EntryPrice decimal.Decimal
+	ExitPrice  decimal.Decimal
+	Fees       decimal.Decimal
+	NetPnL     decimal.Decimal
+	Return     decimal.Decimal
+	Reason     string
+	ExitReason string
+}
+
+// EquityPoint is one portfolio snapshot of the equity curve of a run.
+type EquityPoint struct {
+	Time             time.Time
+	Equity           decimal.Decimal
+	Cash             decimal.Decimal
+	NetContributions decimal.Decimal
+}
+
+// MetricPoint is the value of one metric in one run, see MetricHistory of the repository.
+type MetricPoint struct {
+	RunID     string
+	CreatedAt time.Time
+	Value     decimal.Decimal
+}
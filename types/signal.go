@@ -9,10 +9,18 @@ import (
 type Signal struct {
 	Ticker string
 	Side   Side
-	//Strength decimal.Decimal //TODO: We can use this for Signal normalization later
+	// Strength is the confidence of the signal, used when resolving conflicting signals. Zero counts as 1.
+	Strength  decimal.Decimal
 	Price     decimal.Decimal
 	Reason    string
 	CreatedAt time.Time
+	// ExpiresAt keeps the signal in force on later bars until this time, unless the strategy emits a newer
+	// signal for the ticker. Zero means the signal only applies to the bar it was emitted on.
+	ExpiresAt time.Time
+	// TargetWeight is the desired fraction of portfolio equity for Ticker, for allocators that rebalance to weights.
+	TargetWeight decimal.NullDecimal
+	// Stop is the protective stop price of the entry. Zero means no stop is known.
+	Stop decimal.Decimal
 }
 
 func NewSignal(
@@ -30,3 +38,39 @@ func NewSignal(
 		CreatedAt: createdAt,
 	}
 }
+
+func (s Signal) WithStrength(strength decimal.Decimal) Signal {
+	s.Strength = strength
+	return s
+}
+
+func (s Signal) WithExpiry(expiresAt time.Time) Signal {
+	s.ExpiresAt = expiresAt
+	return s
+}
+
+func (s Signal) WithStop(stop decimal.Decimal) Signal {
+	s.Stop = stop
+	return s
+}
+
+func NewTargetWeightSignal(
+	ticker string,
+	weight decimal.Decimal,
+	price decimal.Decimal,
+	reason string,
+	createdAt time.Time,
+) Signal {
+	side := SideTypeBuy
+	if weight.IsNegative() {
+		side = SideTypeSell
+	}
+	return Signal{
+		Ticker:       ticker,
+		Side:         side,
+		Price:        price,
+		Reason:       reason,
+		CreatedAt:    createdAt,
+		TargetWeight: decimal.NewNullDecimal(weight),
+	}
+}